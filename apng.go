@@ -0,0 +1,251 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// APNGEncoder encodes frames to an animated PNG.
+// Alpha is ignored, like when the pixels are displayed.
+// Frames that are identical to the previous frame are merged into it, by increasing its delay.
+// Since the number of frames must be written first, the compressed frames are kept in memory
+// and the APNG is written to w when Close is called. Recorders that write APNG files write each
+// frame as soon as its delay is known instead, and fill in the number of frames when they are stopped.
+type APNGEncoder struct {
+	w         io.Writer
+	file      *os.File // If set, frames are written to the file as they are done, instead of being kept in memory
+	start     int64    // The offset in the file where the APNG starts
+	width     int
+	height    int
+	frameRate int
+	frames    [][]byte // zlib compressed and filtered RGB image data, for frames that are not written yet
+	delays    []uint16 // Delay of each frame that is not written yet, in 1/frameRate seconds
+	count     int      // The number of frames that have been written
+	previous  []uint32
+	err       error
+}
+
+// NewAPNGEncoder creates a new APNG encoder for frames of the given size.
+// frameRate is used for the frame delays.
+func NewAPNGEncoder(w io.Writer, width, height, frameRate int) *APNGEncoder {
+	if frameRate <= 0 {
+		frameRate = 60
+	}
+	return &APNGEncoder{w: w, width: width, height: height, frameRate: frameRate}
+}
+
+// newAPNGFileEncoder creates a new APNG encoder that writes to the given file, from its current offset.
+// Each frame is written when the next different frame is encoded, and the number of frames is filled in by Close.
+func newAPNGFileEncoder(f *os.File, width, height, frameRate int) (*APNGEncoder, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	e := NewAPNGEncoder(f, width, height, frameRate)
+	e.file, e.start = f, start
+	return e, nil
+}
+
+// Encode filters, compresses and adds a frame
+func (e *APNGEncoder) Encode(pixels []uint32, pitch int32) error {
+	if len(e.frames) > 0 && e.delays[len(e.delays)-1] < 0xffff && e.sameAsPrevious(pixels, pitch) {
+		e.delays[len(e.delays)-1]++
+		return nil
+	}
+	if e.file != nil && len(e.frames) > 0 {
+		// The delay of the previous frame is known, so it can be written
+		e.writeFrame(e.frames[0], e.delays[0], 0)
+		e.frames, e.delays = e.frames[:0], e.delays[:0]
+		if e.err != nil {
+			return e.err
+		}
+	}
+	if e.previous == nil {
+		e.previous = make([]uint32, e.width*e.height)
+	}
+	for y := 0; y < e.height; y++ {
+		copy(e.previous[y*e.width:(y+1)*e.width], pixels[int32(y)*pitch:])
+	}
+	data, err := e.compress(e.previous)
+	if err != nil {
+		return err
+	}
+	e.frames = append(e.frames, data)
+	e.delays = append(e.delays, 1)
+	return nil
+}
+
+// sameAsPrevious checks if the given pixels are the same as the previous frame, disregarding alpha
+func (e *APNGEncoder) sameAsPrevious(pixels []uint32, pitch int32) bool {
+	for y := 0; y < e.height; y++ {
+		row := pixels[int32(y)*pitch:]
+		prev := e.previous[y*e.width:]
+		for x := 0; x < e.width; x++ {
+			if row[x]&0xffffff != prev[x]&0xffffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compress converts the pixels to filtered RGB scanlines and compresses them with zlib.
+// For each scanline, the filter (none, sub or up) that gives the smallest sum of absolute values is used.
+func (e *APNGEncoder) compress(pixels []uint32) ([]byte, error) {
+	rowLength := e.width * 3
+	current := make([]byte, rowLength)
+	above := make([]byte, rowLength)
+	filtered := make([][]byte, 3)
+	for i := range filtered {
+		filtered[i] = make([]byte, rowLength+1)
+		filtered[i][0] = byte(i)
+	}
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	for y := 0; y < e.height; y++ {
+		for x, cv := range pixels[y*e.width : (y+1)*e.width] {
+			current[x*3] = Red(cv)
+			current[x*3+1] = Green(cv)
+			current[x*3+2] = Blue(cv)
+		}
+		best, bestSum := 0, -1
+		for filter := range filtered {
+			sum := 0
+			for i := 0; i < rowLength; i++ {
+				v := current[i]
+				switch filter {
+				case 1: // sub
+					if i >= 3 {
+						v -= current[i-3]
+					}
+				case 2: // up
+					v -= above[i]
+				}
+				filtered[filter][i+1] = v
+				if v < 128 {
+					sum += int(v)
+				} else {
+					sum += 256 - int(v)
+				}
+			}
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = filter, sum
+			}
+		}
+		if _, err := zw.Write(filtered[best]); err != nil {
+			return nil, err
+		}
+		current, above = above, current
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeChunk writes a PNG chunk with the given type and data
+func (e *APNGEncoder) writeChunk(chunkType string, data ...[]byte) {
+	if e.err != nil {
+		return
+	}
+	length := 0
+	for _, d := range data {
+		length += len(d)
+	}
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(length))
+	copy(header[4:], chunkType)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	if _, e.err = e.w.Write(header); e.err != nil {
+		return
+	}
+	for _, d := range data {
+		crc.Write(d)
+		if _, e.err = e.w.Write(d); e.err != nil {
+			return
+		}
+	}
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	_, e.err = e.w.Write(footer)
+}
+
+// apngFrameCountOffset is the offset of the number of frames, from the start of the APNG:
+// after the signature, the IHDR chunk, and the length and type of the acTL chunk
+const apngFrameCountOffset = 8 + 12 + 13 + 8
+
+// writeFrame writes a frame with the given delay. The signature, the image header and the animation control
+// chunk with the given number of frames are written before the first frame.
+func (e *APNGEncoder) writeFrame(data []byte, delay uint16, frameCount int) {
+	if e.count == 0 {
+		if _, e.err = e.w.Write([]byte("\x89PNG\r\n\x1a\n")); e.err != nil {
+			return
+		}
+
+		// Image header: width, height, 8 bits per channel, RGB, deflate, no interlacing
+		ihdr := make([]byte, 13)
+		binary.BigEndian.PutUint32(ihdr[0:], uint32(e.width))
+		binary.BigEndian.PutUint32(ihdr[4:], uint32(e.height))
+		ihdr[8] = 8
+		ihdr[9] = 2
+		e.writeChunk("IHDR", ihdr)
+
+		// Animation control: number of frames, and loop forever
+		actl := make([]byte, 8)
+		binary.BigEndian.PutUint32(actl[0:], uint32(frameCount))
+		e.writeChunk("acTL", actl)
+	}
+
+	// Frame control: sequence number, size, offset, delay, no disposal and no blending.
+	// The first frame is the regular PNG image, the following frames are frame data chunks,
+	// which also have sequence numbers.
+	sequence := uint32(max(0, 2*e.count-1))
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:], sequence)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(e.width))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(e.height))
+	binary.BigEndian.PutUint16(fctl[20:], delay)
+	binary.BigEndian.PutUint16(fctl[22:], uint16(e.frameRate))
+	e.writeChunk("fcTL", fctl)
+	if e.count == 0 {
+		e.writeChunk("IDAT", data)
+	} else {
+		seq := make([]byte, 4)
+		binary.BigEndian.PutUint32(seq, sequence+1)
+		e.writeChunk("fdAT", seq, data)
+	}
+	e.count++
+}
+
+// Close writes the remaining frames and the end of the animated PNG.
+// When writing to a file, the number of frames is filled in.
+func (e *APNGEncoder) Close() error {
+	if e.err != nil || e.count+len(e.frames) == 0 {
+		return e.err
+	}
+	frameCount := e.count + len(e.frames)
+	for i, data := range e.frames {
+		e.writeFrame(data, e.delays[i], frameCount)
+	}
+	e.frames, e.delays = nil, nil
+	e.writeChunk("IEND")
+	if e.file != nil && e.err == nil {
+		// Fill in the number of frames, and the checksum of the animation control chunk
+		actl := make([]byte, 12)
+		binary.BigEndian.PutUint32(actl, uint32(frameCount))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte("acTL"))
+		crc.Write(actl[:8])
+		binary.BigEndian.PutUint32(actl[8:], crc.Sum32())
+		_, e.err = e.file.WriteAt(actl, e.start+apngFrameCountOffset)
+	}
+	return e.err
+}
@@ -0,0 +1,33 @@
+package pixelpusher
+
+import (
+	"time"
+)
+
+// Backend is something that can display the pixels of a Canvas and deliver input events.
+// Canvas.Run uses the SDL2 backend by default, but any Backend can be set in Canvas.Backend.
+type Backend interface {
+	// Open prepares the backend for displaying the given canvas
+	Open(c *Canvas) error
+	// Present displays the current contents of c.Pixels.
+	// If the window does not have the same aspect ratio as the canvas, the canvas should be letterboxed.
+	Present(c *Canvas) error
+	// ScreenSize returns the size of the window, in the same coordinates as mouse events
+	ScreenSize() (int32, int32)
+	// CursorVisible checks if the mouse cursor is shown
+	CursorVisible() bool
+	// PollEvent returns the next pending event, or nil if there are no more events
+	PollEvent() Event
+	// Delay waits for the given duration. It is called once per iteration of the main loop, also with 0.
+	Delay(d time.Duration)
+	// Now returns the time since Open was called
+	Now() time.Duration
+	// ToggleFullscreen switches to fullscreen mode, or back.
+	// Returns true if the mode has been switched to fullscreen.
+	ToggleFullscreen() bool
+	// Screenshot saves the displayed image to a PNG file.
+	// Set overwrite to true for overwriting any existing files.
+	Screenshot(filename string, overwrite bool) error
+	// Close releases all resources held by the backend
+	Close() error
+}
@@ -0,0 +1,539 @@
+package pixelpusher
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Action is a named action that keys and joystick buttons can be bound to
+type Action string
+
+// Actions that are used by the default bindings.
+// ActionLeft to ActionEsc are passed on to the ActionFunctions given to Canvas.Run.
+// ActionQuit, ActionPause, ActionRecord, ActionScreenshot, ActionFullscreen and ActionStats are handled by Canvas.Run itself.
+// ActionQuit and ActionStats are not bound by default.
+const (
+	ActionLeft       Action = "left"
+	ActionRight      Action = "right"
+	ActionUp         Action = "up"
+	ActionDown       Action = "down"
+	ActionSpace      Action = "space"
+	ActionEnter      Action = "enter"
+	ActionEsc        Action = "esc"
+	ActionQuit       Action = "quit"
+	ActionPause      Action = "pause"
+	ActionRecord     Action = "record"
+	ActionScreenshot Action = "screenshot"
+	ActionFullscreen Action = "fullscreen"
+	ActionStats      Action = "stats"
+)
+
+// ActionEvent is sent by Canvas.RunEvents, after the event that triggered it, when a bound input is pressed or released
+type ActionEvent struct {
+	Action Action
+	Down   bool
+	Repeat bool
+}
+
+// InputKind is the kind of physical input in an Input
+type InputKind uint8
+
+// Kinds of physical input
+const (
+	InputKey InputKind = iota
+	InputJoyButton
+	InputJoyAxis
+	InputControllerButton
+)
+
+// Input is a physical input that can be bound to an action.
+// Code is the key code, or the button or axis number.
+// Mod is the modifiers that must be held down, for keys. Only ModCtrl, ModAlt, ModShift and ModGUI are used.
+// Dir is the direction of an axis, -1 or 1.
+type Input struct {
+	Kind InputKind
+	Code int32
+	Mod  Mod
+	Dir  int8
+}
+
+// Bindings maps physical inputs to actions
+type Bindings map[Input]Action
+
+// KeyInput returns the Input for the given key, while the given modifiers are held down
+func KeyInput(key Key, mod Mod) Input {
+	return Input{Kind: InputKey, Code: int32(key), Mod: normalizeMod(mod)}
+}
+
+// JoyButtonInput returns the Input for the given joystick button
+func JoyButtonInput(button uint8) Input {
+	return Input{Kind: InputJoyButton, Code: int32(button)}
+}
+
+// JoyAxisInput returns the Input for moving the given joystick axis in the given direction (-1 or 1)
+func JoyAxisInput(axis uint8, dir int8) Input {
+	if dir < 0 {
+		dir = -1
+	} else {
+		dir = 1
+	}
+	return Input{Kind: InputJoyAxis, Code: int32(axis), Dir: dir}
+}
+
+// ControllerButtonInput returns the Input for the given game controller button
+func ControllerButtonInput(button ControllerButton) Input {
+	return Input{Kind: InputControllerButton, Code: int32(button)}
+}
+
+// normalizeMod returns ModCtrl, ModAlt, ModShift and ModGUI for the modifiers that are held down, regardless of side
+func normalizeMod(mod Mod) Mod {
+	var m Mod
+	for _, both := range []Mod{ModCtrl, ModAlt, ModShift, ModGUI} {
+		if mod&both != 0 {
+			m |= both
+		}
+	}
+	return m
+}
+
+// DefaultBindings returns the default bindings:
+// arrow keys, WASD and joystick axis 0 and 1 for moving, space and joystick button 0 for space,
+// return and joystick button 1 for enter, esc, q and joystick button 2 for esc,
+// p for pausing, r for recording, F12 and ctrl-s for taking a screenshot
+// and f, F11 and alt-enter for toggling fullscreen.
+func DefaultBindings() Bindings {
+	b := make(Bindings)
+	b.Bind(ActionLeft, KeyInput(KeyLeft, ModNone), KeyInput(KeyA, ModNone), JoyAxisInput(0, -1))
+	b.Bind(ActionRight, KeyInput(KeyRight, ModNone), KeyInput(KeyD, ModNone), JoyAxisInput(0, 1))
+	b.Bind(ActionUp, KeyInput(KeyUp, ModNone), KeyInput(KeyW, ModNone), JoyAxisInput(1, -1))
+	b.Bind(ActionDown, KeyInput(KeyDown, ModNone), KeyInput(KeyS, ModNone), JoyAxisInput(1, 1))
+	b.Bind(ActionSpace, KeyInput(KeySpace, ModNone), JoyButtonInput(0))
+	b.Bind(ActionEnter, KeyInput(KeyReturn, ModNone), JoyButtonInput(1))
+	b.Bind(ActionEsc, KeyInput(KeyEscape, ModNone), KeyInput(KeyQ, ModNone), JoyButtonInput(2))
+	b.Bind(ActionPause, KeyInput(KeyP, ModNone))
+	b.Bind(ActionRecord, KeyInput(KeyR, ModNone))
+	b.Bind(ActionScreenshot, KeyInput(KeyF12, ModNone), KeyInput(KeyS, ModCtrl))
+	b.Bind(ActionFullscreen, KeyInput(KeyF, ModNone), KeyInput(KeyF11, ModNone), KeyInput(KeyReturn, ModAlt))
+	return b
+}
+
+// Bind binds the given inputs to the given action.
+// Any previous action for the inputs is replaced.
+func (b Bindings) Bind(action Action, inputs ...Input) {
+	for _, input := range inputs {
+		b[input] = action
+	}
+}
+
+// Unbind removes the bindings for the given inputs
+func (b Bindings) Unbind(inputs ...Input) {
+	for _, input := range inputs {
+		delete(b, input)
+	}
+}
+
+// UnbindAction removes all bindings to the given action
+func (b Bindings) UnbindAction(action Action) {
+	for input, a := range b {
+		if a == action {
+			delete(b, input)
+		}
+	}
+}
+
+// Inputs returns the inputs that are bound to the given action, sorted by name
+func (b Bindings) Inputs(action Action) []Input {
+	var inputs []Input
+	for input, a := range b {
+		if a == action {
+			inputs = append(inputs, input)
+		}
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		return inputs[i].String() < inputs[j].String()
+	})
+	return inputs
+}
+
+// Actions returns all actions that have bindings, sorted by name
+func (b Bindings) Actions() []Action {
+	seen := make(map[Action]bool)
+	var actions []Action
+	for _, a := range b {
+		if !seen[a] {
+			seen[a] = true
+			actions = append(actions, a)
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i] < actions[j]
+	})
+	return actions
+}
+
+// KeyAction returns the action for the given key and modifiers.
+// If there is no binding for the key with the held down modifiers,
+// the binding for the key without modifiers is used.
+func (b Bindings) KeyAction(key Key, mod Mod) (Action, bool) {
+	if action, ok := b[KeyInput(key, mod)]; ok {
+		return action, true
+	}
+	action, ok := b[KeyInput(key, ModNone)]
+	return action, ok
+}
+
+// Translate returns the action events for the given input event
+func (b Bindings) Translate(event Event) []*ActionEvent {
+	switch e := event.(type) {
+	case *KeyEvent:
+		if action, ok := b.KeyAction(e.Key, e.Mod); ok {
+			return []*ActionEvent{{Action: action, Down: e.Down, Repeat: e.Repeat}}
+		}
+	case *JoyButtonEvent:
+		if action, ok := b[JoyButtonInput(e.Button)]; ok {
+			return []*ActionEvent{{Action: action, Down: e.Down}}
+		}
+	case *ControllerButtonEvent:
+		if action, ok := b[ControllerButtonInput(e.Button)]; ok {
+			return []*ActionEvent{{Action: action, Down: e.Down}}
+		}
+	case *JoyAxisEvent:
+		// Moving the axis in a direction presses that direction,
+		// and centering the axis releases both directions.
+		var actionEvents []*ActionEvent
+		for _, dir := range []int8{-1, 1} {
+			action, ok := b[JoyAxisInput(e.Axis, dir)]
+			if !ok {
+				continue
+			}
+			if e.Value == 0 {
+				actionEvents = append(actionEvents, &ActionEvent{Action: action, Down: false})
+			} else if (e.Value < 0) == (dir < 0) {
+				actionEvents = append(actionEvents, &ActionEvent{Action: action, Down: true})
+			}
+		}
+		return actionEvents
+	}
+	return nil
+}
+
+// Names of keys that do not have a printable name
+var keyNames = map[Key]string{
+	KeyReturn:    "return",
+	KeyEscape:    "escape",
+	KeyBackspace: "backspace",
+	KeyTab:       "tab",
+	KeySpace:     "space",
+	KeyComma:     "comma",
+	KeyDelete:    "delete",
+	KeyF1:        "f1",
+	KeyF2:        "f2",
+	KeyF3:        "f3",
+	KeyF4:        "f4",
+	KeyF5:        "f5",
+	KeyF6:        "f6",
+	KeyF7:        "f7",
+	KeyF8:        "f8",
+	KeyF9:        "f9",
+	KeyF10:       "f10",
+	KeyF11:       "f11",
+	KeyF12:       "f12",
+	KeyInsert:    "insert",
+	KeyHome:      "home",
+	KeyPageUp:    "pageup",
+	KeyEnd:       "end",
+	KeyPageDown:  "pagedown",
+	KeyRight:     "right",
+	KeyLeft:      "left",
+	KeyDown:      "down",
+	KeyUp:        "up",
+	KeyLCtrl:     "lctrl",
+	KeyLShift:    "lshift",
+	KeyLAlt:      "lalt",
+	KeyRCtrl:     "rctrl",
+	KeyRShift:    "rshift",
+	KeyRAlt:      "ralt",
+}
+
+// Names of game controller buttons
+var controllerButtonNames = map[ControllerButton]string{
+	ButtonA:             "a",
+	ButtonB:             "b",
+	ButtonX:             "x",
+	ButtonY:             "y",
+	ButtonBack:          "back",
+	ButtonGuide:         "guide",
+	ButtonStart:         "start",
+	ButtonLeftStick:     "leftstick",
+	ButtonRightStick:    "rightstick",
+	ButtonLeftShoulder:  "leftshoulder",
+	ButtonRightShoulder: "rightshoulder",
+	ButtonDPadUp:        "up",
+	ButtonDPadDown:      "down",
+	ButtonDPadLeft:      "left",
+	ButtonDPadRight:     "right",
+}
+
+// Names of modifiers, in the order they are written
+var modNames = []struct {
+	mod  Mod
+	name string
+}{
+	{ModCtrl, "ctrl"},
+	{ModAlt, "alt"},
+	{ModShift, "shift"},
+	{ModGUI, "gui"},
+}
+
+// String returns the name of the input, like "ctrl+s", "f12", "button0", "axis1-" or "pad:start"
+func (i Input) String() string {
+	switch i.Kind {
+	case InputKey:
+		var sb strings.Builder
+		for _, m := range modNames {
+			if i.Mod&m.mod != 0 {
+				sb.WriteString(m.name + "+")
+			}
+		}
+		key := Key(i.Code)
+		if name, ok := keyNames[key]; ok {
+			sb.WriteString(name)
+		} else if key > ' ' && key < 127 && key != '+' && key != '#' && key != '=' {
+			sb.WriteRune(rune(key))
+		} else {
+			sb.WriteString("key" + strconv.Itoa(int(key)))
+		}
+		return sb.String()
+	case InputJoyButton:
+		return "button" + strconv.Itoa(int(i.Code))
+	case InputJoyAxis:
+		if i.Dir < 0 {
+			return "axis" + strconv.Itoa(int(i.Code)) + "-"
+		}
+		return "axis" + strconv.Itoa(int(i.Code)) + "+"
+	case InputControllerButton:
+		if name, ok := controllerButtonNames[ControllerButton(i.Code)]; ok {
+			return "pad:" + name
+		}
+		return "pad:" + strconv.Itoa(int(i.Code))
+	}
+	return fmt.Sprintf("input(%d, %d)", i.Kind, i.Code)
+}
+
+// ParseInput parses the name of an input, as returned by Input.String
+func ParseInput(s string) (Input, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(name, "pad:"):
+		name = strings.TrimPrefix(name, "pad:")
+		for button, buttonName := range controllerButtonNames {
+			if name == buttonName {
+				return ControllerButtonInput(button), nil
+			}
+		}
+		if n, err := strconv.ParseUint(name, 10, 8); err == nil {
+			return ControllerButtonInput(ControllerButton(n)), nil
+		}
+		return Input{}, fmt.Errorf("unknown controller button: %q", s)
+	case strings.HasPrefix(name, "button") && len(name) > len("button"):
+		n, err := strconv.ParseUint(strings.TrimPrefix(name, "button"), 10, 8)
+		if err != nil {
+			return Input{}, fmt.Errorf("invalid joystick button: %q", s)
+		}
+		return JoyButtonInput(uint8(n)), nil
+	case strings.HasPrefix(name, "axis") && len(name) > len("axis"):
+		var dir int8
+		switch {
+		case strings.HasSuffix(name, "-"):
+			dir = -1
+		case strings.HasSuffix(name, "+"):
+			dir = 1
+		default:
+			return Input{}, fmt.Errorf("joystick axis without a direction (+ or -): %q", s)
+		}
+		n, err := strconv.ParseUint(name[len("axis"):len(name)-1], 10, 8)
+		if err != nil {
+			return Input{}, fmt.Errorf("invalid joystick axis: %q", s)
+		}
+		return JoyAxisInput(uint8(n), dir), nil
+	}
+
+	// A key, possibly with modifiers
+	var mod Mod
+	parts := strings.Split(name, "+")
+	for _, part := range parts[:len(parts)-1] {
+		found := false
+		for _, m := range modNames {
+			if part == m.name {
+				mod |= m.mod
+				found = true
+				break
+			}
+		}
+		if !found {
+			return Input{}, fmt.Errorf("unknown modifier %q in %q", part, s)
+		}
+	}
+	keyName := parts[len(parts)-1]
+	for key, n := range keyNames {
+		if keyName == n {
+			return KeyInput(key, mod), nil
+		}
+	}
+	if len(keyName) == 1 && keyName[0] > ' ' && keyName[0] < 127 {
+		return KeyInput(Key(keyName[0]), mod), nil
+	}
+	if strings.HasPrefix(keyName, "key") {
+		if n, err := strconv.ParseInt(strings.TrimPrefix(keyName, "key"), 10, 32); err == nil {
+			return KeyInput(Key(n), mod), nil
+		}
+	}
+	return Input{}, fmt.Errorf("unknown key: %q", s)
+}
+
+// MarshalText makes it possible to use an Input as a JSON key or value
+func (i Input) MarshalText() ([]byte, error) {
+	return []byte(i.String()), nil
+}
+
+// UnmarshalText makes it possible to use an Input as a JSON key or value
+func (i *Input) UnmarshalText(text []byte) error {
+	input, err := ParseInput(string(text))
+	if err != nil {
+		return err
+	}
+	*i = input
+	return nil
+}
+
+// MarshalJSON encodes the bindings as a JSON object, where each action has a list of inputs
+func (b Bindings) MarshalJSON() ([]byte, error) {
+	m := make(map[Action][]Input)
+	for _, action := range b.Actions() {
+		m[action] = b.Inputs(action)
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes bindings from a JSON object, where each action has a list of inputs
+func (b *Bindings) UnmarshalJSON(data []byte) error {
+	var m map[Action][]Input
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*b = make(Bindings)
+	for action, inputs := range m {
+		b.Bind(action, inputs...)
+	}
+	return nil
+}
+
+// WriteText writes the bindings as text, with one action per line,
+// like "screenshot = ctrl+s, f12". Lines starting with # are comments.
+func (b Bindings) WriteText(w io.Writer) error {
+	for _, action := range b.Actions() {
+		var names []string
+		for _, input := range b.Inputs(action) {
+			names = append(names, input.String())
+		}
+		if _, err := fmt.Fprintf(w, "%s = %s\n", action, strings.Join(names, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBindings reads bindings in the text format written by WriteText
+func ReadBindings(r io.Reader) (Bindings, error) {
+	b := make(Bindings)
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"action = input, input\", got %q", lineNumber, line)
+		}
+		action := Action(strings.TrimSpace(fields[0]))
+		if action == "" {
+			return nil, fmt.Errorf("line %d: missing action name", lineNumber)
+		}
+		for _, name := range strings.Split(fields[1], ",") {
+			if strings.TrimSpace(name) == "" {
+				continue
+			}
+			input, err := ParseInput(name)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+			}
+			b.Bind(action, input)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// LoadBindings loads bindings from a file.
+// Files ending with .json are read as JSON, other files are read in the text format.
+func LoadBindings(filename string) (Bindings, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		var b Bindings
+		if err := json.NewDecoder(f).Decode(&b); err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err)
+		}
+		return b, nil
+	}
+	b, err := ReadBindings(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return b, nil
+}
+
+// Save saves the bindings to a file.
+// Files ending with .json are written as JSON, other files are written in the text format.
+// Set overwrite to true to allow overwriting files.
+func (b Bindings) Save(filename string, overwrite bool) error {
+	if !overwrite && exists(filename) {
+		return errors.New(filename + " already exists")
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		data, err := json.MarshalIndent(b, "", "  ")
+		if err == nil {
+			_, err = f.Write(append(data, '\n'))
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	if err := b.WriteText(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
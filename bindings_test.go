@@ -0,0 +1,99 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseInput(t *testing.T) {
+	for _, name := range []string{"a", "ctrl+s", "alt+return", "ctrl+shift+f12", "button2", "axis1-", "axis0+", "pad:start", "key1073741925"} {
+		input, err := ParseInput(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if input.String() != name {
+			t.Errorf("expected %s, got %s", name, input.String())
+		}
+	}
+	if input, _ := ParseInput("Ctrl+S"); input != KeyInput(KeyS, ModCtrl) {
+		t.Error("expected ctrl+s, got", input)
+	}
+	for _, name := range []string{"hyper+a", "axis1", "button", "pad:turbo", "nosuchkey"} {
+		if _, err := ParseInput(name); err == nil {
+			t.Errorf("expected an error for %q", name)
+		}
+	}
+}
+
+func TestBindingsText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := DefaultBindings().WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "screenshot = ctrl+s, f12\n") {
+		t.Errorf("unexpected text:\n%s", buf.String())
+	}
+	b, err := ReadBindings(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != len(DefaultBindings()) {
+		t.Errorf("expected %d bindings, got %d", len(DefaultBindings()), len(b))
+	}
+	_, err = ReadBindings(strings.NewReader("# jump\njump = space, button0\nfire ctrl+x\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Error("expected an error for line 3, got:", err)
+	}
+}
+
+func TestBindingsJSON(t *testing.T) {
+	b := DefaultBindings()
+	b.Bind("jump", ControllerButtonInput(ButtonA))
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b2 Bindings
+	if err := json.Unmarshal(data, &b2); err != nil {
+		t.Fatal(err)
+	}
+	if len(b2) != len(b) {
+		t.Fatalf("expected %d bindings, got %d", len(b), len(b2))
+	}
+	for input, action := range b {
+		if b2[input] != action {
+			t.Errorf("expected %s for %s, got %s", action, input, b2[input])
+		}
+	}
+}
+
+func TestBindingsTranslate(t *testing.T) {
+	b := DefaultBindings()
+	if a := b.Translate(&KeyEvent{Key: KeyS, Mod: ModLCtrl, Down: true}); len(a) != 1 || a[0].Action != ActionScreenshot {
+		t.Error("expected ctrl+s to be the screenshot action")
+	}
+	if a := b.Translate(&KeyEvent{Key: KeyS, Mod: ModLShift, Down: true}); len(a) != 1 || a[0].Action != ActionDown {
+		t.Error("expected shift+s to be the down action")
+	}
+	if a := b.Translate(&JoyAxisEvent{Axis: 1, Value: 0}); len(a) != 2 || a[0].Down || a[1].Down {
+		t.Error("expected centering an axis to release both directions")
+	}
+
+	// Unbind q, so that it no longer quits
+	b.Unbind(KeyInput(KeyQ, ModNone))
+	b.Bind(ActionQuit, KeyInput(KeyX, ModCtrl))
+	canvas := New("Bindings")
+	canvas.Bindings = b
+	headless := NewHeadlessBackend(0)
+	headless.Send(1, &KeyEvent{Key: KeyQ, Down: true}, &KeyEvent{Key: KeyQ})
+	headless.Send(2, &KeyEvent{Key: KeyX, Mod: ModRCtrl, Down: true})
+	canvas.Backend = headless
+	if err := canvas.Run(nil, nil, nil, nil); err != ErrQuit {
+		t.Fatal("expected ErrQuit, got:", err)
+	}
+	if headless.Frame() != 2 {
+		t.Errorf("expected to quit at frame 2, quit at frame %d", headless.Frame())
+	}
+}
@@ -0,0 +1,221 @@
+package pixelpusher
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"github.com/xyproto/pf"
+)
+
+// Buffer is a rectangle of ARGB pixels that can be drawn to.
+// The pixel at (x, y) is Pixels[y*Stride+x]. A Buffer can be a view into a larger pixel buffer,
+// created with SubBuffer, that shares the pixels with the larger buffer.
+// Everything that is drawn to a Buffer is clipped to its Width and Height.
+type Buffer struct {
+	Pixels  []uint32
+	Width   int32
+	Height  int32
+	Stride  int32 // The distance from one row to the next, in pixels. This is the pitch of the underlying pixel buffer.
+	OriginX int32 // The position of the upper left pixel, within the underlying pixel buffer
+	OriginY int32
+	Linear  bool         // Blend, blur and scale in linear light, instead of directly on the sRGB values
+	Depth   *DepthBuffer // Used by DepthTriangle for hiding what is behind. Should have the same size as the buffer.
+}
+
+// NewBuffer creates a new buffer with the given size, where all pixels are 0
+func NewBuffer(width, height int32) *Buffer {
+	if width < 0 || height < 0 {
+		width, height = 0, 0
+	}
+	return &Buffer{
+		Pixels: make([]uint32, width*height),
+		Width:  width,
+		Height: height,
+		Stride: width,
+	}
+}
+
+// NewBufferFromPixels wraps an existing pixel buffer, without copying the pixels.
+// pitch is the width of the pixel buffer, and the height is len(pixels) / pitch.
+func NewBufferFromPixels(pixels []uint32, pitch int32) *Buffer {
+	if pitch <= 0 {
+		return &Buffer{}
+	}
+	return &Buffer{
+		Pixels: pixels,
+		Width:  pitch,
+		Height: int32(len(pixels)) / pitch,
+		Stride: pitch,
+	}
+}
+
+// Buffer returns a Buffer for the pixels of the canvas, sharing the pixels with the canvas
+func (c *Canvas) Buffer() *Buffer {
+	return &Buffer{
+		Pixels: c.Pixels,
+		Width:  int32(c.Width),
+		Height: int32(c.Height),
+		Stride: c.Pitch,
+		Linear: c.Linear,
+		Depth:  c.Depth,
+	}
+}
+
+// Rect returns the rectangle that is covered by the buffer, starting at (0, 0)
+func (b *Buffer) Rect() image.Rectangle {
+	return image.Rect(0, 0, int(b.Width), int(b.Height))
+}
+
+// SubBuffer returns a view into the given rectangle of the buffer, sharing the pixels with the buffer.
+// The rectangle is clipped to the buffer, and (0, 0) in the returned buffer is the upper left corner of the rectangle.
+func (b *Buffer) SubBuffer(r image.Rectangle) *Buffer {
+	r = r.Intersect(b.Rect())
+	sub := &Buffer{
+		Width:   int32(r.Dx()),
+		Height:  int32(r.Dy()),
+		Stride:  b.Stride,
+		OriginX: b.OriginX + int32(r.Min.X),
+		OriginY: b.OriginY + int32(r.Min.Y),
+		Linear:  b.Linear,
+	}
+	if b.Depth != nil {
+		sub.Depth = b.Depth.sub(r)
+	}
+	if r.Empty() {
+		sub.Width, sub.Height = 0, 0
+		return sub
+	}
+	start := int32(r.Min.Y)*b.Stride + int32(r.Min.X)
+	end := start + (sub.Height-1)*b.Stride + sub.Width
+	sub.Pixels = b.Pixels[start:end:end]
+	return sub
+}
+
+// Clone returns a copy of the buffer, with its own pixels, and its own depth buffer if there is one.
+// The returned buffer has no padding between the rows, and the origin is set to (0, 0).
+func (b *Buffer) Clone() *Buffer {
+	c := NewBuffer(b.Width, b.Height)
+	c.Linear = b.Linear
+	if b.Depth != nil {
+		c.Depth = b.Depth.Clone()
+	}
+	for y := int32(0); y < b.Height; y++ {
+		copy(c.Pixels[y*c.Stride:(y+1)*c.Stride], b.Pixels[y*b.Stride:])
+	}
+	return c
+}
+
+// Row returns the pixels of the given row, which must be within the buffer
+func (b *Buffer) Row(y int32) []uint32 {
+	offset := y * b.Stride
+	return b.Pixels[offset : offset+b.Width]
+}
+
+// Contains checks if the given position is within the buffer
+func (b *Buffer) Contains(x, y int32) bool {
+	return x >= 0 && y >= 0 && x < b.Width && y < b.Height
+}
+
+// GetPixel returns the color value at the given position, or 0 if it is outside of the buffer
+func (b *Buffer) GetPixel(x, y int32) uint32 {
+	if !b.Contains(x, y) {
+		return 0
+	}
+	return b.Pixels[y*b.Stride+x]
+}
+
+// SetPixel sets the color value at the given position, if it is within the buffer
+func (b *Buffer) SetPixel(x, y int32, colorValue uint32) {
+	if b.Contains(x, y) {
+		b.Pixels[y*b.Stride+x] = colorValue
+	}
+}
+
+// Pixel draws a pixel, if it is within the buffer
+func (b *Buffer) Pixel(x, y int32, c color.RGBA) {
+	b.SetPixel(x, y, ColorToColorValue(c))
+}
+
+// PixelRGB draws an opaque pixel, given red, green and blue, if it is within the buffer
+func (b *Buffer) PixelRGB(x, y int32, r, g, blue uint8) {
+	b.SetPixel(x, y, RGBAToColorValue(r, g, blue, 0xff))
+}
+
+// Clear changes all pixels to the given color
+func (b *Buffer) Clear(c color.RGBA) {
+	b.FastClear(ColorToColorValue(c))
+}
+
+// FastClear changes all pixels to the given uint32 color value
+func (b *Buffer) FastClear(colorValue uint32) {
+	if b.Stride == b.Width {
+		FastClear(b.Pixels[:b.Width*b.Height], colorValue)
+		return
+	}
+	for y := int32(0); y < b.Height; y++ {
+		FastClear(b.Row(y), colorValue)
+	}
+}
+
+// splitRows divides the rows from minY to maxY (not included) into at most "cores" parts,
+// and calls f for each part concurrently, then waits for all of them to complete.
+func splitRows(cores int, minY, maxY int32, f func(minY, maxY int32)) {
+	rows := maxY - minY
+	if rows <= 0 {
+		return
+	}
+	if cores < 2 || rows < 2 {
+		f(minY, maxY)
+		return
+	}
+	if int32(cores) > rows {
+		cores = int(rows)
+	}
+	var wg sync.WaitGroup
+	wg.Add(cores)
+	for i := int32(0); i < int32(cores); i++ {
+		go func(y1, y2 int32) {
+			defer wg.Done()
+			f(y1, y2)
+		}(minY+rows*i/int32(cores), minY+rows*(i+1)/int32(cores))
+	}
+	wg.Wait()
+}
+
+// Map applies a pixel function to all pixels in the buffer, concurrently
+func (b *Buffer) Map(cores int, f pf.PixelFunction) {
+	if b.Stride == b.Width {
+		pf.Map(cores, f, b.Pixels[:b.Width*b.Height])
+		return
+	}
+	splitRows(cores, 0, b.Height, func(minY, maxY int32) {
+		for y := minY; y < maxY; y++ {
+			row := b.Row(y)
+			for i := range row {
+				row[i] = f(row[i])
+			}
+		}
+	})
+}
+
+// ColorModel returns ARGBModel, for implementing image.Image
+func (b *Buffer) ColorModel() color.Model {
+	return ARGBModel
+}
+
+// Bounds returns the rectangle that is covered by the buffer, for implementing image.Image
+func (b *Buffer) Bounds() image.Rectangle {
+	return b.Rect()
+}
+
+// At returns the color of the pixel at the given position as an ARGB color, for implementing image.Image
+func (b *Buffer) At(x, y int) color.Color {
+	return ARGB(b.GetPixel(int32(x), int32(y)))
+}
+
+// Set changes the color of the pixel at the given position, if it is within the buffer.
+// This implements draw.Image, so that the buffer can be drawn to with the image/draw package.
+func (b *Buffer) Set(x, y int, c color.Color) {
+	b.SetPixel(int32(x), int32(y), ToColorValue(c))
+}
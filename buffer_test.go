@@ -0,0 +1,109 @@
+package pixelpusher
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSubBuffer(t *testing.T) {
+	b := NewBuffer(8, 6)
+	sub := b.SubBuffer(image.Rect(2, 1, 6, 4))
+	if sub.Width != 4 || sub.Height != 3 || sub.Stride != 8 {
+		t.Fatalf("unexpected sub buffer size: %dx%d with stride %d", sub.Width, sub.Height, sub.Stride)
+	}
+	// Nested views keep track of where they are in the underlying pixel buffer
+	subsub := sub.SubBuffer(image.Rect(1, 1, 10, 10))
+	if subsub.OriginX != 3 || subsub.OriginY != 2 || subsub.Width != 3 || subsub.Height != 2 {
+		t.Errorf("unexpected nested sub buffer: origin (%d, %d), size %dx%d", subsub.OriginX, subsub.OriginY, subsub.Width, subsub.Height)
+	}
+	// The pixels are shared
+	sub.SetPixel(0, 0, 0xffffffff)
+	if b.GetPixel(2, 1) != 0xffffffff {
+		t.Error("expected the sub buffer to share pixels with the buffer")
+	}
+	// Clearing a sub buffer only changes the pixels within it
+	sub.FastClear(0xff00ff00)
+	count := 0
+	for _, cv := range b.Pixels {
+		if cv == 0xff00ff00 {
+			count++
+		}
+	}
+	if count != 12 {
+		t.Errorf("expected 12 cleared pixels, got %d", count)
+	}
+	// Pixels outside of the view are ignored
+	sub.SetPixel(-1, 0, 0xffff0000)
+	sub.SetPixel(4, 0, 0xffff0000)
+	if b.GetPixel(1, 1) != 0 || b.GetPixel(6, 1) != 0 || sub.GetPixel(4, 0) != 0 {
+		t.Error("expected pixels outside of the sub buffer to be left alone")
+	}
+	if empty := b.SubBuffer(image.Rect(10, 10, 20, 20)); empty.Width != 0 || empty.Height != 0 {
+		t.Error("expected an empty sub buffer")
+	}
+}
+
+func TestBufferClipping(t *testing.T) {
+	b := NewBuffer(10, 10)
+	sub := b.SubBuffer(image.Rect(2, 2, 8, 8))
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	// Draw shapes that are larger than the sub buffer
+	sub.Line(-20, 3, 30, 3, white)
+	sub.Line(-5, -5, 20, 20, white)
+	sub.VerticalLine(0, -10, 10, white)
+	sub.Triangle(4, -10, -10, 30, 0, 0, 30, white)
+	sub.WireTriangle(1, -10, -10, 30, 0, 0, 30, white)
+
+	for y := int32(0); y < 10; y++ {
+		for x := int32(0); x < 10; x++ {
+			inside := x >= 2 && y >= 2 && x < 8 && y < 8
+			if !inside && b.GetPixel(x, y) != 0 {
+				t.Fatalf("pixel (%d, %d) outside of the sub buffer was drawn to", x, y)
+			}
+		}
+	}
+	if b.GetPixel(5, 5) != 0xffffffff || b.GetPixel(2, 7) != 0xffffffff {
+		t.Error("expected the sub buffer to be drawn to")
+	}
+}
+
+func TestBufferWrappers(t *testing.T) {
+	// The free functions should give the same result as the Buffer methods
+	pixels := make([]uint32, 16*8)
+	b := NewBuffer(16, 8)
+	c := color.RGBA{0x10, 0x20, 0x30, 0xff}
+
+	Triangle(3, pixels, 1, 1, 14, 2, 5, 7, c, 16)
+	b.Triangle(1, 1, 1, 14, 2, 5, 7, c)
+	Line(pixels, 0, 7, 15, 0, c, 16)
+	b.Line(0, 7, 15, 0, c)
+	VerticalLine(pixels, 3, 6, 1, c, 16)
+	b.VerticalLine(3, 6, 1, c)
+
+	for i := range pixels {
+		if pixels[i] != b.Pixels[i] {
+			t.Fatalf("the pixel buffer and the Buffer differ at %d", i)
+		}
+	}
+	// A vertical line from y 1 to 6 covers six rows
+	for y := int32(1); y <= 6; y++ {
+		if b.GetPixel(3, y) != ColorToColorValue(c) {
+			t.Errorf("expected the vertical line to cover (3, %d)", y)
+		}
+	}
+}
+
+func TestBufferMap(t *testing.T) {
+	b := NewBuffer(4, 4)
+	sub := b.SubBuffer(image.Rect(1, 1, 3, 3))
+	sub.Map(4, func(cv uint32) uint32 { return cv + 1 })
+	if b.GetPixel(1, 1) != 1 || b.GetPixel(2, 2) != 1 || b.GetPixel(0, 0) != 0 || b.GetPixel(3, 2) != 0 {
+		t.Error("expected Map to only change the pixels in the sub buffer")
+	}
+	clone := sub.Clone()
+	if clone.Stride != 2 || len(clone.Pixels) != 4 || clone.Pixels[3] != 1 {
+		t.Error("expected a compact copy of the sub buffer")
+	}
+}
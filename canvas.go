@@ -4,20 +4,34 @@ import (
 	"errors"
 	"fmt"
 	"image/color"
-
-	"github.com/veandco/go-sdl2/sdl"
+	"io"
+	"time"
 )
 
 // Canvas is a window title + pixels + additional info
 type Canvas struct {
-	Title      string
-	PixelScale int
-	Width      int
-	Height     int
-	Pitch      int32
-	FrameRate  int
-	Opaque     uint8
-	Pixels     []uint32
+	Title        string
+	PixelScale   int
+	Width        int
+	Height       int
+	Pitch        int32
+	FrameRate    int          // Target framerate. 0 means no limit.
+	UpdateRate   int          // Fixed timestep updates per second, for calling the tick function. 0 means once per frame.
+	MaxUpdates   int          // The maximum number of fixed timestep updates per frame, when catching up. 0 means no limit.
+	VSync        bool         // Wait for the display when presenting a frame, instead of waiting for the framerate
+	ShowStats    bool         // Draw the framerate and frame time on top of the pixels when presenting a frame
+	RecordFormat RecordFormat // File format for recordings, which are started and stopped with the record action
+	RecordWriter io.Writer    // If set, every presented frame is encoded with RecordFormat and written here, while Run is running
+	Linear       bool         // Blend and filter in linear light when drawing to the Buffer returned by Canvas.Buffer
+	Depth        *DepthBuffer // Attached to the Buffer returned by Canvas.Buffer, for depth-tested triangles. Can be nil.
+	Opaque       uint8
+	Pixels       []uint32
+	Backend      Backend  // Used by Run for displaying pixels and receiving events. SDL2 is used if nil.
+	Bindings     Bindings // Maps keys and joystick buttons to actions. DefaultBindings are used if nil.
+
+	screenWidth, screenHeight int32 // Window size, as reported by the backend
+	mouse                     MouseState
+	stats                     FrameStats
 }
 
 // DrawFunction can be used to draw pixels to canvas.Pixels
@@ -26,10 +40,54 @@ type DrawFunction func(*Canvas) error
 // ActionFunction is called when keys are pressed or released. Order: left, right, up, down, space, return, esc
 type ActionFunction func(bool, bool, bool, bool, bool, bool, bool) error
 
-// TickFunction is called at every loop
+// ActionEvents returns an EventFunction that calls the given ActionFunctions
+// when ActionLeft, ActionRight, ActionUp, ActionDown, ActionSpace, ActionEnter or ActionEsc
+// is pressed or released.
+// If pressFunc (or releaseFunc) is nil, pressing (or releasing) ActionEsc returns ErrQuit.
+func ActionEvents(pressFunc, releaseFunc ActionFunction) EventFunction {
+	return func(event Event) error {
+		e, ok := event.(*ActionEvent)
+		if !ok {
+			return nil
+		}
+		f := pressFunc
+		if !e.Down {
+			f = releaseFunc
+		}
+		var left, right, up, down, space, enter, esc bool
+		switch e.Action {
+		case ActionLeft:
+			left = true
+		case ActionRight:
+			right = true
+		case ActionUp:
+			up = true
+		case ActionDown:
+			down = true
+		case ActionSpace:
+			space = true
+		case ActionEnter:
+			enter = true
+		case ActionEsc:
+			if f == nil {
+				return ErrQuit
+			}
+			esc = true
+		default:
+			return nil
+		}
+		if f == nil {
+			return nil
+		}
+		return f(left, right, up, down, space, enter, esc)
+	}
+}
+
+// TickFunction is called at every loop, or UpdateRate times per second if UpdateRate is set
 type TickFunction func() error
 
-var errQuit = errors.New("quit")
+// ErrQuit is returned by Run when the window is closed or the quit key is pressed
+var ErrQuit = errors.New("quit")
 
 // New creates a new Canvas
 func New(title string) *Canvas {
@@ -40,8 +98,10 @@ func New(title string) *Canvas {
 		Height:     200,   // height, worldspace
 		Pitch:      320,   // Same as width, used when calculating where to place pixels (y*pitch+x)
 		FrameRate:  60,    // Target framerate
+		MaxUpdates: 5,     // Maximum number of fixed timestep updates per frame, if UpdateRate is set
 		Opaque:     255,   // Alpha value for opaque colors
 		Pixels:     make([]uint32, 320*200),
+		Bindings:   DefaultBindings(),
 	}
 }
 
@@ -59,312 +119,221 @@ func Plot(c *Canvas, x, y, r, g, b int) error {
 
 // Run takes an optional function draw drawing pixels, an optional function for when an action is pressed, an optional function for when an action is released and a function for each loop
 func (c *Canvas) Run(drawFunc DrawFunction, pressFunc ActionFunction, releaseFunc ActionFunction, tickFunc TickFunction) error {
+	return c.RunEvents(drawFunc, ActionEvents(pressFunc, releaseFunc), tickFunc)
+}
+
+// RunEvents takes an optional function for drawing pixels, an optional function that is called for every input event and an optional function for each loop.
+// Input events that are bound to an action in c.Bindings are followed by an *ActionEvent.
+// Errors from starting, writing or finishing a recording are returned.
+//
+// If c.UpdateRate is 0, the tick function is called once per iteration of the main loop.
+// If c.UpdateRate is set, the tick function is called c.UpdateRate times per second, with a fixed timestep,
+// at most c.MaxUpdates times per frame. The draw function can then use c.Stats().Alpha to interpolate
+// between the previous and the next update.
+func (c *Canvas) RunEvents(drawFunc DrawFunction, eventFunc EventFunction, tickFunc TickFunction) (runErr error) {
 	var (
-		window                    *sdl.Window
-		renderer                  *sdl.Renderer
-		event                     sdl.Event
-		joystick                  *sdl.Joystick
-		pause, recording          bool
-		loopCounter, frameCounter uint64
-		err                       error
+		event       Event
+		pause       bool
+		recorder    *Recorder     // Started and stopped with the record action
+		stream      *Recorder     // Writes to c.RecordWriter
+		accumulator time.Duration // Time that has not yet been covered by fixed timestep updates
+		timer       frameTimer
+		overlay     []uint32
 	)
 
-	// Initialize SDL (video + joystick)
-	sdl.Init(uint32(sdl.INIT_VIDEO) | uint32(sdl.INIT_JOYSTICK))
-	defer sdl.Quit()
-
-	// Create a window
-	window, err = sdl.CreateWindow(c.Title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, int32(c.Width*c.PixelScale), int32(c.Height*c.PixelScale), sdl.WINDOW_SHOWN)
-	if err != nil {
-		return fmt.Errorf("failed to create window: %s", err)
+	backend := c.Backend
+	if backend == nil {
+		backend = NewSDLBackend()
 	}
-	defer window.Destroy()
-
-	// Create a renderer
-	renderer, err = sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
-	if err != nil {
-		return fmt.Errorf("failed to create renderer: %s", err)
+	bindings := c.Bindings
+	if bindings == nil {
+		bindings = DefaultBindings()
 	}
-	defer renderer.Destroy()
-
-	// Fill the render buffer with black
-	renderer.SetDrawColor(0, 0, 0, c.Opaque)
-	renderer.Clear()
-
-	// Create a texture to draw to
-	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STREAMING, int32(c.Width), int32(c.Height))
-	if err != nil {
-		return fmt.Errorf("failed to create texture: %s", err)
+	if err := backend.Open(c); err != nil {
+		return err
 	}
-
-	// Initialize joystick
-	if sdl.NumJoysticks() > 0 {
-		joystick = sdl.JoystickOpen(0)
-		defer joystick.Close()
+	defer backend.Close()
+	defer func() {
+		// Report errors from finishing the recording, for instance if the disk is full
+		if recorder != nil {
+			if err := recorder.Stop(); err != nil && (runErr == nil || runErr == ErrQuit) {
+				runErr = err
+			}
+		}
+	}()
+	if c.RecordWriter != nil {
+		var err error
+		stream, err = NewRecorderWriter(c.RecordWriter, c.RecordFormat, c.Width, c.Height, c.FrameRate)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			// Report write errors, for instance if the program that is piped to has exited
+			if err := stream.Stop(); err != nil && (runErr == nil || runErr == ErrQuit) {
+				runErr = err
+			}
+		}()
 	}
+	c.screenWidth, c.screenHeight = backend.ScreenSize()
+	c.stats = FrameStats{}
+
+	loopStart := backend.Now()
+	lastTick := loopStart  // When the fixed timestep updates were last considered
+	nextFrame := loopStart // When the next frame should start
 
 	// Innerloop
 	for {
+		frameStart := backend.Now()
 		if !pause {
 			if drawFunc != nil {
 				if err := drawFunc(c); err != nil {
 					return err
 				}
 			}
-			texture.UpdateRGBA(nil, c.Pixels, int(c.Pitch))
-			renderer.Copy(texture, nil, nil)
-			renderer.Present()
-			if recording {
-				filename := fmt.Sprintf("frame%05d.png", frameCounter)
-				SavePixelsToPNG(c.Pixels, c.Pitch, filename, true)
-				frameCounter++
+			if c.ShowStats {
+				// Draw the stats on top of a copy of the pixels, then present that copy
+				if len(overlay) != len(c.Pixels) {
+					overlay = make([]uint32, len(c.Pixels))
+				}
+				copy(overlay, c.Pixels)
+				pixels := c.Pixels
+				c.Pixels = overlay
+				DrawStats(c.Pixels, c.Pitch, c.stats.String())
+				err := backend.Present(c)
+				c.Pixels = pixels
+				if err != nil {
+					return err
+				}
+			} else if err := backend.Present(c); err != nil {
+				return err
+			}
+			c.stats.DrawTime = backend.Now() - frameStart
+			c.stats.Frames++
+			if recorder != nil {
+				recorder.Add(c.Pixels, c.Pitch)
+			}
+			if stream != nil {
+				stream.Add(c.Pixels, c.Pitch)
 			}
 		}
 
 		// Check for events
-		for event = sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
-			case *sdl.QuitEvent:
-				return errQuit
-			case *sdl.JoyAxisEvent:
-				ae := event.(*sdl.JoyAxisEvent)
-				if ae.Axis == 0 {
-					if ae.Value > 0 {
-						// right
-						if pressFunc != nil {
-							if err := pressFunc(false, true, false, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					} else if ae.Value < 0 {
-						// left
-						if pressFunc != nil {
-							if err := pressFunc(true, false, false, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					}
+		c.mouse.WheelX, c.mouse.WheelY = 0, 0
+		c.mouse.Visible = backend.CursorVisible()
+		for event = backend.PollEvent(); event != nil; event = backend.PollEvent() {
+			if _, ok := event.(*QuitEvent); ok {
+				return ErrQuit
+			}
+			c.updateMouse(event)
+			if eventFunc != nil {
+				if err := eventFunc(event); err != nil {
+					return err
 				}
-				if ae.Axis == 1 {
-					if ae.Value > 0 {
-						// down
-						if pressFunc != nil {
-							if err := pressFunc(false, false, false, true, false, false, false); err != nil {
+			}
+			for _, actionEvent := range bindings.Translate(event) {
+				if actionEvent.Down && !actionEvent.Repeat {
+					switch actionEvent.Action {
+					case ActionQuit:
+						return ErrQuit
+					case ActionFullscreen:
+						backend.ToggleFullscreen()
+					case ActionPause:
+						pause = !pause
+					case ActionScreenshot:
+						backend.Screenshot("screenshot.png", true)
+					case ActionRecord:
+						// Start recording to a new file, or stop recording and finish writing the file
+						if recorder == nil {
+							var err error
+							recorder, err = NewRecorder(TimestampedFilename("recording", c.RecordFormat), c.RecordFormat, c.Width, c.Height, c.FrameRate)
+							if err != nil {
 								return err
 							}
-						}
-					} else if ae.Value < 0 {
-						// up
-						if pressFunc != nil {
-							if err := pressFunc(false, false, true, false, false, false, false); err != nil {
+						} else {
+							err := recorder.Stop()
+							recorder = nil
+							if err != nil {
 								return err
 							}
 						}
+					case ActionStats:
+						c.ShowStats = !c.ShowStats
 					}
 				}
-			case *sdl.JoyButtonEvent:
-				be := event.(*sdl.JoyButtonEvent)
-				if be.Button == 0 {
-					if be.State == 1 { // pressed A
-						// fire pressed
-						if pressFunc != nil {
-							if err := pressFunc(false, false, false, false, true, false, false); err != nil {
-								return err
-							}
-						}
-					} else if be.State == 0 { // released A
-						// fire released
-						if releaseFunc != nil {
-							if err := releaseFunc(false, false, false, false, true, false, false); err != nil {
-								return err
-							}
-						}
+				if eventFunc != nil {
+					if err := eventFunc(actionEvent); err != nil {
+						return err
 					}
 				}
-				if be.Button == 1 {
-					if be.State == 1 { // pressed B
-						// secondary pressed
-						if pressFunc != nil {
-							if err := pressFunc(false, false, false, false, false, true, false); err != nil {
-								return err
-							}
-						}
-					} else if be.State == 0 { // released B
-						// secondary released
-						if releaseFunc != nil {
-							if err := releaseFunc(false, false, false, false, false, true, false); err != nil {
-								return err
-							}
-						}
-					}
+			}
+		}
+
+		// Update
+		tickStart := backend.Now()
+		if c.UpdateRate > 0 {
+			update := time.Second / time.Duration(c.UpdateRate)
+			accumulator += tickStart - lastTick
+			lastTick = tickStart
+			for updates := 0; accumulator >= update; updates++ {
+				if c.MaxUpdates > 0 && updates >= c.MaxUpdates {
+					// Too far behind, skip the remaining updates
+					c.stats.DroppedUpdates += uint64(accumulator / update)
+					accumulator %= update
+					break
 				}
-				if be.Button == 2 {
-					if be.State == 1 { // pressed C
-						// tertiary pressed
-						if pressFunc != nil {
-							if err := pressFunc(false, false, false, false, false, false, true); err != nil {
-								return err
-							}
-						}
-					} else if be.State == 0 { // released C
-						// tertiary released
-						if releaseFunc != nil {
-							if err := releaseFunc(false, false, false, false, false, false, true); err != nil {
-								return err
-							}
-						}
+				if tickFunc != nil {
+					if err := tickFunc(); err != nil {
+						return err
 					}
 				}
-			case *sdl.KeyboardEvent:
-				ke := event.(*sdl.KeyboardEvent)
-				if ke.Type == sdl.KEYDOWN {
-					ks := ke.Keysym
-					switch ks.Sym {
-					case sdl.K_ESCAPE, sdl.K_q:
-						// quit
-						if pressFunc == nil {
-							return errQuit
-						} else if err := pressFunc(false, false, false, false, false, false, true); err != nil {
-							return err
-						}
-					case sdl.K_SPACE:
-						// fire
-						if pressFunc != nil {
-							if err := pressFunc(false, false, false, false, true, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_LEFT, sdl.K_a:
-						// left
-						if pressFunc != nil {
-							if err := pressFunc(true, false, false, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_RIGHT, sdl.K_d:
-						// right
-						if pressFunc != nil {
-							if err := pressFunc(false, true, false, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_UP, sdl.K_w:
-						// up
-						if pressFunc != nil {
-							if err := pressFunc(false, false, true, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_DOWN:
-						// down
-						if pressFunc != nil {
-							if err := pressFunc(false, false, false, true, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_RETURN:
-						altHeldDown := ks.Mod == sdl.KMOD_LALT || ks.Mod == sdl.KMOD_RALT
-						if !altHeldDown {
-							// alt+enter is not pressed
-							// enter is pressed
-							if pressFunc != nil {
-								if err := pressFunc(false, false, false, false, false, true, false); err != nil {
-									return err
-								}
-							}
-							break
-						}
-						// alt+enter is pressed
-						fallthrough
-					case sdl.K_f, sdl.K_F11:
-						ToggleFullscreen(window)
-					case sdl.K_p:
-						// pause toggle
-						pause = !pause
-					case sdl.K_s:
-						ctrlHeldDown := ks.Mod == sdl.KMOD_LCTRL || ks.Mod == sdl.KMOD_RCTRL
-						if !ctrlHeldDown {
-							// ctrl+s is not pressed
-							// s is pressed
-							if pressFunc != nil {
-								if err := pressFunc(false, false, false, true, false, false, false); err != nil {
-									return err
-								}
-							}
-							break
-						}
-						// ctrl+s is pressed
-						fallthrough
-					case sdl.K_F12:
-						// screenshot
-						Screenshot(renderer, "screenshot.png", true)
-					case sdl.K_r:
-						// recording
-						recording = !recording
-						frameCounter = 0
-					}
-				} else if ke.Type == sdl.KEYUP {
-					ks := ke.Keysym
-					switch ks.Sym {
-					case sdl.K_ESCAPE, sdl.K_q:
-						// quit
-						if releaseFunc == nil {
-							return errQuit
-						} else if err := releaseFunc(false, false, false, false, false, false, true); err != nil {
-							return err
-						}
-					case sdl.K_SPACE:
-						// fire
-						if releaseFunc != nil {
-							if err := releaseFunc(false, false, false, false, true, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_LEFT, sdl.K_a:
-						// left
-						if releaseFunc != nil {
-							if err := releaseFunc(true, false, false, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_RIGHT, sdl.K_d:
-						// right
-						if releaseFunc != nil {
-							if err := releaseFunc(false, true, false, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_UP, sdl.K_w:
-						// up
-						if releaseFunc != nil {
-							if err := releaseFunc(false, false, true, false, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_DOWN, sdl.K_s:
-						// down
-						if releaseFunc != nil {
-							if err := releaseFunc(false, false, false, true, false, false, false); err != nil {
-								return err
-							}
-						}
-					case sdl.K_RETURN:
-						if releaseFunc != nil {
-							if err := releaseFunc(false, false, false, false, false, true, false); err != nil {
-								return err
-							}
-						}
-					}
+				c.stats.Updates++
+				accumulator -= update
+			}
+			c.stats.Alpha = float64(accumulator) / float64(update)
+		} else {
+			if tickFunc != nil {
+				if err := tickFunc(); err != nil {
+					return err
 				}
 			}
+			c.stats.Updates++
 		}
-		if tickFunc != nil {
-			if err := tickFunc(); err != nil {
-				return err
+		c.stats.UpdateTime = backend.Now() - tickStart
+
+		// Wait until it is time for the next frame
+		var wait time.Duration
+		if c.FrameRate > 0 {
+			frameDuration := time.Second / time.Duration(c.FrameRate)
+			nextFrame += frameDuration
+			if now := backend.Now(); now > nextFrame {
+				// Behind schedule, skip the frames that there was no time for
+				c.stats.DroppedFrames += uint64((now - nextFrame) / frameDuration)
+				nextFrame = now
+			} else if !c.VSync {
+				wait = nextFrame - now
 			}
 		}
-		sdl.Delay(uint32(1000 / c.FrameRate))
-		loopCounter++
+		backend.Delay(wait)
+
+		c.stats.Loops++
+		c.stats.FrameTime = backend.Now() - frameStart
+		c.stats.Elapsed = backend.Now() - loopStart
+		c.stats.FPS = timer.add(c.stats.FrameTime)
 	}
-	return nil
+}
+
+// RunHeadless is like Run, but draws the given number of frames without opening a window.
+// A copy of the pixels of every drawn frame is returned.
+// Quitting, either by running out of frames or from the quit key, is not reported as an error.
+func (c *Canvas) RunHeadless(frames uint64, drawFunc DrawFunction, pressFunc ActionFunction, releaseFunc ActionFunction, tickFunc TickFunction) ([][]uint32, error) {
+	headless := NewHeadlessBackend(frames)
+	previous := c.Backend
+	c.Backend = headless
+	defer func() {
+		c.Backend = previous
+	}()
+	if err := c.Run(drawFunc, pressFunc, releaseFunc, tickFunc); err != nil && err != ErrQuit {
+		return headless.Output, err
+	}
+	return headless.Output, nil
 }
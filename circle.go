@@ -0,0 +1,377 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math"
+)
+
+// Angles are in radians, starting at the positive x axis. Since y points down, the angles increase clockwise,
+// as seen on the screen. Arcs and pie slices go from the start angle and clockwise to the end angle.
+
+// circleQuadrant returns the positions of the pixels of a circle outline with the given radius,
+// in the lower right quadrant, relative to the center. This is the midpoint circle algorithm.
+func circleQuadrant(r int32) []Pos {
+	// One octant, from the bottom and to the diagonal
+	var octant []Pos
+	x, y := int32(0), r
+	d := 1 - r
+	for x <= y {
+		octant = append(octant, Pos{x, y})
+		x++
+		if d < 0 {
+			d += 2*x + 1
+		} else {
+			y--
+			d += 2*(x-y) + 1
+		}
+	}
+	// Mirror the octant along the diagonal
+	quadrant := make([]Pos, 0, 2*len(octant))
+	quadrant = append(quadrant, octant...)
+	for i := len(octant) - 1; i >= 0; i-- {
+		if p := octant[i]; p.x != p.y {
+			quadrant = append(quadrant, Pos{p.y, p.x})
+		}
+	}
+	return quadrant
+}
+
+// ellipseQuadrant returns the positions of the pixels of an ellipse outline with the given radii,
+// in the lower right quadrant, relative to the center. This is the midpoint ellipse algorithm.
+// Both radii must be larger than 0.
+func ellipseQuadrant(rx, ry int32) []Pos {
+	var quadrant []Pos
+	rx2, ry2 := int64(rx)*int64(rx), int64(ry)*int64(ry)
+	x, y := int64(0), int64(ry)
+	px, py := int64(0), 2*rx2*y
+
+	// The first region, where the slope is less than 1 and x is stepped.
+	// The decision variables are multiplied by 4, to avoid fractions.
+	p := 4*ry2 - 4*rx2*y + rx2
+	for px < py {
+		quadrant = append(quadrant, Pos{int32(x), int32(y)})
+		x++
+		px += 2 * ry2
+		if p < 0 {
+			p += 4 * (ry2 + px)
+		} else {
+			y--
+			py -= 2 * rx2
+			p += 4 * (ry2 + px - py)
+		}
+	}
+
+	// The second region, where the slope is larger than 1 and y is stepped
+	p = ry2*(2*x+1)*(2*x+1) + 4*rx2*(y-1)*(y-1) - 4*rx2*ry2
+	for y >= 0 {
+		quadrant = append(quadrant, Pos{int32(x), int32(y)})
+		y--
+		py -= 2 * rx2
+		if p > 0 {
+			p += 4 * (rx2 - py)
+		} else {
+			x++
+			px += 2 * ry2
+			p += 4 * (rx2 - py + px)
+		}
+	}
+	return quadrant
+}
+
+// outlineQuadrant returns the lower right quadrant of an ellipse outline, or nil if nothing should be drawn.
+// Circles use the midpoint circle algorithm.
+func (b *Buffer) outlineQuadrant(cx, cy, rx, ry int32) []Pos {
+	if rx < 0 || ry < 0 || cx+rx < 0 || cy+ry < 0 || cx-rx >= b.Width || cy-ry >= b.Height {
+		return nil
+	}
+	switch {
+	case rx == 0 || ry == 0:
+		// A line, from the center to the edge
+		quadrant := make([]Pos, 0, rx+ry+1)
+		for i := int32(0); i <= rx+ry; i++ {
+			if rx == 0 {
+				quadrant = append(quadrant, Pos{0, ry - i})
+			} else {
+				quadrant = append(quadrant, Pos{i, 0})
+			}
+		}
+		return quadrant
+	case rx == ry:
+		return circleQuadrant(rx)
+	}
+	return ellipseQuadrant(rx, ry)
+}
+
+// reflect calls f for each of the up to four positions that are reflections of (x, y) in the four quadrants
+func reflect(x, y int32, f func(x, y int32)) {
+	f(x, y)
+	if x != 0 {
+		f(-x, y)
+	}
+	if y != 0 {
+		f(x, -y)
+		if x != 0 {
+			f(-x, -y)
+		}
+	}
+}
+
+// normalizeAngles returns the start angle in the range from 0 to 2π, and how many radians
+// there are clockwise from the start angle to the end angle, from 0 to 2π
+func normalizeAngles(start, end float32) (float64, float64) {
+	s, e := float64(start), float64(end)
+	span := e - s
+	if span >= 2*math.Pi {
+		span = 2 * math.Pi
+	} else {
+		span = math.Mod(span, 2*math.Pi)
+		if span < 0 {
+			span += 2 * math.Pi
+		}
+	}
+	s = math.Mod(s, 2*math.Pi)
+	if s < 0 {
+		s += 2 * math.Pi
+	}
+	return s, span
+}
+
+// inAngle checks if the position (x, y), relative to the center, is between the start angle and the span.
+// The center itself is always included.
+func inAngle(x, y float64, start, span float64) bool {
+	if span >= 2*math.Pi || (x == 0 && y == 0) {
+		return true
+	}
+	a := math.Atan2(y, x) - start
+	if a < 0 {
+		a += 2 * math.Pi
+	}
+	return a <= span
+}
+
+// Circle draws a circle outline with the given center and radius, clipped to the buffer
+func (b *Buffer) Circle(cx, cy, r int32, c color.RGBA) {
+	b.EllipseBlend(cx, cy, r, r, c, BlendSrc)
+}
+
+// CircleBlend draws a circle outline with the given center and radius and blend mode, clipped to the buffer
+func (b *Buffer) CircleBlend(cx, cy, r int32, c color.RGBA, mode BlendMode) {
+	b.EllipseBlend(cx, cy, r, r, c, mode)
+}
+
+// FilledCircle draws a filled circle with the given center and radius, clipped to the buffer
+func (b *Buffer) FilledCircle(cx, cy, r int32, c color.RGBA) {
+	b.FilledEllipseBlend(cx, cy, r, r, c, BlendSrc)
+}
+
+// FilledCircleBlend draws a filled circle with the given center and radius and blend mode, clipped to the buffer
+func (b *Buffer) FilledCircleBlend(cx, cy, r int32, c color.RGBA, mode BlendMode) {
+	b.FilledEllipseBlend(cx, cy, r, r, c, mode)
+}
+
+// Ellipse draws an ellipse outline with the given center and horizontal and vertical radii, clipped to the buffer
+func (b *Buffer) Ellipse(cx, cy, rx, ry int32, c color.RGBA) {
+	b.EllipseBlend(cx, cy, rx, ry, c, BlendSrc)
+}
+
+// EllipseBlend draws an ellipse outline with the given center, horizontal and vertical radii and blend mode,
+// clipped to the buffer. Each pixel is only drawn once.
+func (b *Buffer) EllipseBlend(cx, cy, rx, ry int32, c color.RGBA, mode BlendMode) {
+	colorValue := ColorToColorValue(c)
+	for _, p := range b.outlineQuadrant(cx, cy, rx, ry) {
+		reflect(p.x, p.y, func(x, y int32) {
+			b.BlendPixel(cx+x, cy+y, colorValue, mode)
+		})
+	}
+}
+
+// FilledEllipse draws a filled ellipse with the given center and horizontal and vertical radii, clipped to the buffer
+func (b *Buffer) FilledEllipse(cx, cy, rx, ry int32, c color.RGBA) {
+	b.FilledEllipseBlend(cx, cy, rx, ry, c, BlendSrc)
+}
+
+// FilledEllipseBlend draws a filled ellipse with the given center, horizontal and vertical radii and blend mode,
+// clipped to the buffer. The ellipse is drawn as horizontal spans, that cover the same pixels as the outline.
+func (b *Buffer) FilledEllipseBlend(cx, cy, rx, ry int32, c color.RGBA, mode BlendMode) {
+	quadrant := b.outlineQuadrant(cx, cy, rx, ry)
+	if quadrant == nil {
+		return
+	}
+	// The widest part of the outline, for each row
+	halfWidths := make([]int32, ry+1)
+	for _, p := range quadrant {
+		halfWidths[p.y] = Max2(halfWidths[p.y], p.x)
+	}
+	for y, hw := range halfWidths {
+		b.HorizontalLineBlend(cy+int32(y), cx-hw, cx+hw, c, mode)
+		if y != 0 {
+			b.HorizontalLineBlend(cy-int32(y), cx-hw, cx+hw, c, mode)
+		}
+	}
+}
+
+// Arc draws a part of an ellipse outline, from the start angle and clockwise to the end angle, clipped to the buffer
+func (b *Buffer) Arc(cx, cy, rx, ry int32, start, end float32, c color.RGBA) {
+	b.ArcBlend(cx, cy, rx, ry, start, end, c, BlendSrc)
+}
+
+// ArcBlend draws a part of an ellipse outline with the given blend mode, from the start angle
+// and clockwise to the end angle, clipped to the buffer
+func (b *Buffer) ArcBlend(cx, cy, rx, ry int32, start, end float32, c color.RGBA, mode BlendMode) {
+	s, span := normalizeAngles(start, end)
+	colorValue := ColorToColorValue(c)
+	for _, p := range b.outlineQuadrant(cx, cy, rx, ry) {
+		reflect(p.x, p.y, func(x, y int32) {
+			if inAngle(float64(x), float64(y), s, span) {
+				b.BlendPixel(cx+x, cy+y, colorValue, mode)
+			}
+		})
+	}
+}
+
+// Pie draws a filled pie slice of an ellipse, from the start angle and clockwise to the end angle,
+// clipped to the buffer
+func (b *Buffer) Pie(cx, cy, rx, ry int32, start, end float32, c color.RGBA) {
+	b.PieBlend(cx, cy, rx, ry, start, end, c, BlendSrc)
+}
+
+// PieBlend draws a filled pie slice of an ellipse with the given blend mode, from the start angle
+// and clockwise to the end angle, clipped to the buffer
+func (b *Buffer) PieBlend(cx, cy, rx, ry int32, start, end float32, c color.RGBA, mode BlendMode) {
+	quadrant := b.outlineQuadrant(cx, cy, rx, ry)
+	if quadrant == nil {
+		return
+	}
+	s, span := normalizeAngles(start, end)
+	halfWidths := make([]int32, ry+1)
+	for _, p := range quadrant {
+		halfWidths[p.y] = Max2(halfWidths[p.y], p.x)
+	}
+	colorValue := ColorToColorValue(c)
+	for y := -ry; y <= ry; y++ {
+		hw := halfWidths[Abs(y)]
+		if cy+y < 0 || cy+y >= b.Height {
+			continue
+		}
+		row := b.Row(cy + y)
+		for x := Max2(cx-hw, 0); x <= Min2(cx+hw, b.Width-1); x++ {
+			if inAngle(float64(x-cx), float64(y), s, span) {
+				row[x] = b.combine(mode, row[x], colorValue)
+			}
+		}
+	}
+}
+
+// ellipseDistance returns the approximate signed distance from (x, y), relative to the center,
+// to the edge of an ellipse with the given radii. It is negative inside of the ellipse.
+func ellipseDistance(x, y, rx, ry float64) float64 {
+	nx, ny := x/rx, y/ry
+	l := math.Hypot(nx, ny)
+	if l == 0 {
+		return -math.Min(rx, ry)
+	}
+	// The distance in the scaled space, divided by the length of the gradient
+	g := math.Hypot(nx/rx, ny/ry) / l
+	return (l - 1) / g
+}
+
+// wedgeDistance returns the signed distance from (x, y), relative to the center, to the edges of the wedge
+// from the start angle and the span. It is negative inside of the wedge.
+func wedgeDistance(x, y, start, span float64) float64 {
+	if span >= 2*math.Pi {
+		return math.Inf(-1)
+	}
+	sx, sy := math.Cos(start), math.Sin(start)
+	ex, ey := math.Cos(start+span), math.Sin(start+span)
+	d1 := -(sx*y - sy*x) // Outside if counterclockwise of the start
+	d2 := -(x*ey - y*ex) // Outside if clockwise of the end
+	if span <= math.Pi {
+		return math.Max(d1, d2)
+	}
+	return math.Min(d1, d2)
+}
+
+// drawEllipseCoverage draws an anti-aliased ellipse, pie slice, ellipse outline or arc, depending on
+// if it is filled and what the start angle and span are
+func (b *Buffer) drawEllipseCoverage(cx, cy, rx, ry float32, start, span float64, filled bool, c color.RGBA, mode BlendMode) {
+	if rx <= 0 || ry <= 0 {
+		return
+	}
+	m := b.newCoverageMask(cx-rx-1, cy-ry-1, cx+rx+1, cy+ry+1)
+	for y := m.minY; y < m.minY+m.height; y++ {
+		for x := m.minX; x < m.minX+m.width; x++ {
+			dx, dy := float64(x)-float64(cx), float64(y)-float64(cy)
+			d := ellipseDistance(dx, dy, float64(rx), float64(ry))
+			coverage := 0.5 - d
+			if !filled {
+				// An outline that is one pixel wide, centered on the edge
+				coverage = 1 - math.Abs(d)
+			}
+			if wedge := 0.5 - wedgeDistance(dx, dy, start, span); wedge < coverage {
+				coverage = wedge
+			}
+			if coverage > 0 {
+				m.set(x, y, clampUnit(float32(coverage)))
+			}
+		}
+	}
+	b.drawCoverage(m, ColorToColorValue(c), mode)
+}
+
+// ACircle draws an anti-aliased circle outline, blended on top with BlendOver, clipped to the buffer.
+// The center has subpixel precision.
+func (b *Buffer) ACircle(cx, cy, r float32, c color.RGBA) {
+	b.AEllipseBlend(cx, cy, r, r, c, BlendOver)
+}
+
+// AFilledCircle draws an anti-aliased filled circle, blended on top with BlendOver, clipped to the buffer.
+// The center has subpixel precision.
+func (b *Buffer) AFilledCircle(cx, cy, r float32, c color.RGBA) {
+	b.AFilledEllipseBlend(cx, cy, r, r, c, BlendOver)
+}
+
+// AEllipse draws an anti-aliased ellipse outline, blended on top with BlendOver, clipped to the buffer
+func (b *Buffer) AEllipse(cx, cy, rx, ry float32, c color.RGBA) {
+	b.AEllipseBlend(cx, cy, rx, ry, c, BlendOver)
+}
+
+// AEllipseBlend draws an anti-aliased ellipse outline with the given blend mode, clipped to the buffer
+func (b *Buffer) AEllipseBlend(cx, cy, rx, ry float32, c color.RGBA, mode BlendMode) {
+	b.drawEllipseCoverage(cx, cy, rx, ry, 0, 2*math.Pi, false, c, mode)
+}
+
+// AFilledEllipse draws an anti-aliased filled ellipse, blended on top with BlendOver, clipped to the buffer
+func (b *Buffer) AFilledEllipse(cx, cy, rx, ry float32, c color.RGBA) {
+	b.AFilledEllipseBlend(cx, cy, rx, ry, c, BlendOver)
+}
+
+// AFilledEllipseBlend draws an anti-aliased filled ellipse with the given blend mode, clipped to the buffer
+func (b *Buffer) AFilledEllipseBlend(cx, cy, rx, ry float32, c color.RGBA, mode BlendMode) {
+	b.drawEllipseCoverage(cx, cy, rx, ry, 0, 2*math.Pi, true, c, mode)
+}
+
+// AArc draws an anti-aliased part of an ellipse outline, from the start angle and clockwise to the end angle,
+// blended on top with BlendOver, clipped to the buffer
+func (b *Buffer) AArc(cx, cy, rx, ry, start, end float32, c color.RGBA) {
+	b.AArcBlend(cx, cy, rx, ry, start, end, c, BlendOver)
+}
+
+// AArcBlend draws an anti-aliased part of an ellipse outline with the given blend mode,
+// from the start angle and clockwise to the end angle, clipped to the buffer
+func (b *Buffer) AArcBlend(cx, cy, rx, ry, start, end float32, c color.RGBA, mode BlendMode) {
+	s, span := normalizeAngles(start, end)
+	b.drawEllipseCoverage(cx, cy, rx, ry, s, span, false, c, mode)
+}
+
+// APie draws an anti-aliased filled pie slice of an ellipse, from the start angle and clockwise to the end angle,
+// blended on top with BlendOver, clipped to the buffer
+func (b *Buffer) APie(cx, cy, rx, ry, start, end float32, c color.RGBA) {
+	b.APieBlend(cx, cy, rx, ry, start, end, c, BlendOver)
+}
+
+// APieBlend draws an anti-aliased filled pie slice of an ellipse with the given blend mode,
+// from the start angle and clockwise to the end angle, clipped to the buffer
+func (b *Buffer) APieBlend(cx, cy, rx, ry, start, end float32, c color.RGBA, mode BlendMode) {
+	s, span := normalizeAngles(start, end)
+	b.drawEllipseCoverage(cx, cy, rx, ry, s, span, true, c, mode)
+}
@@ -0,0 +1,164 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// drawn returns the positions of the pixels that are not black
+func drawn(b *Buffer) map[Pos]uint32 {
+	m := make(map[Pos]uint32)
+	for y := int32(0); y < b.Height; y++ {
+		for x := int32(0); x < b.Width; x++ {
+			if cv := b.GetPixel(x, y); cv&0xffffff != 0 {
+				m[Pos{x, y}] = cv
+			}
+		}
+	}
+	return m
+}
+
+func TestCircle(t *testing.T) {
+	c := color.RGBA{0x40, 0x40, 0x40, 0xff}
+	for _, r := range []int32{0, 1, 2, 5, 12} {
+		b := NewBuffer(32, 32)
+		b.FastClear(0xff000000)
+		// Each pixel is only drawn once, so additive blending gives the same color everywhere
+		b.CircleBlend(16, 16, r, c, BlendAdditive)
+		outline := drawn(b)
+		for p, cv := range outline {
+			if cv != 0xff404040 {
+				t.Errorf("radius %d: the pixel at (%d, %d) was drawn more than once", r, p.x, p.y)
+			}
+			dx, dy := float64(p.x-16), float64(p.y-16)
+			if d := math.Hypot(dx, dy) - float64(r); d < -0.8 || d > 0.8 {
+				t.Errorf("radius %d: the pixel at (%d, %d) is too far from the circle", r, p.x, p.y)
+			}
+		}
+		for _, p := range []Pos{{16 - r, 16}, {16 + r, 16}, {16, 16 - r}, {16, 16 + r}} {
+			if _, ok := outline[p]; !ok {
+				t.Errorf("radius %d: expected (%d, %d) to be drawn", r, p.x, p.y)
+			}
+		}
+
+		// The filled circle covers the outline, and the inside
+		b.FastClear(0xff000000)
+		b.FilledCircleBlend(16, 16, r, c, BlendAdditive)
+		filled := drawn(b)
+		for p := range outline {
+			if _, ok := filled[p]; !ok {
+				t.Errorf("radius %d: expected the filled circle to cover (%d, %d)", r, p.x, p.y)
+			}
+		}
+		for p, cv := range filled {
+			if cv != 0xff404040 {
+				t.Errorf("radius %d: the pixel at (%d, %d) was filled more than once", r, p.x, p.y)
+			}
+		}
+		if area := math.Pi * float64(r) * float64(r); r > 2 && math.Abs(float64(len(filled))-area) > 2*math.Pi*float64(r) {
+			t.Errorf("radius %d: expected an area close to %.0f, got %d", r, area, len(filled))
+		}
+	}
+}
+
+func TestEllipse(t *testing.T) {
+	b := NewBuffer(32, 32)
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	b.Ellipse(16, 16, 12, 5, white)
+	for _, p := range []Pos{{4, 16}, {28, 16}, {16, 11}, {16, 21}} {
+		if b.GetPixel(p.x, p.y) != 0xffffffff {
+			t.Errorf("expected (%d, %d) to be drawn", p.x, p.y)
+		}
+	}
+	if b.GetPixel(16, 16) != 0 || b.GetPixel(3, 16) != 0 || b.GetPixel(16, 10) != 0 {
+		t.Error("expected the inside and outside to be left alone")
+	}
+	b.FilledEllipse(16, 16, 12, 5, white)
+	if len(drawn(b)) < 150 || b.GetPixel(16, 16) == 0 || b.GetPixel(16, 22) != 0 {
+		t.Errorf("unexpected filled ellipse, with %d pixels", len(drawn(b)))
+	}
+
+	// Ellipses with a radius of 0 are lines
+	b.FastClear(0)
+	b.Ellipse(16, 16, 0, 3, white)
+	if len(drawn(b)) != 7 {
+		t.Errorf("expected a line with 7 pixels, got %d", len(drawn(b)))
+	}
+
+	// Ellipses that are partly or completely outside of the buffer
+	b.FilledEllipse(0, 0, 40, 20, white)
+	b.Ellipse(-100, -100, 10, 10, white)
+	b.FilledCircle(30, 30, 1000, white)
+	b.Circle(16, 16, -1, white)
+	if b.GetPixel(31, 31) != 0xffffffff {
+		t.Error("expected the large circle to cover the buffer")
+	}
+}
+
+func TestArcAndPie(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	b := NewBuffer(32, 32)
+
+	// A quarter circle, clockwise from the right to the bottom
+	b.Arc(16, 16, 10, 10, 0, math.Pi/2, white)
+	for p := range drawn(b) {
+		if p.x < 16 || p.y < 16 {
+			t.Errorf("expected the arc to be in the lower right quadrant, got (%d, %d)", p.x, p.y)
+		}
+	}
+	if b.GetPixel(26, 16) == 0 || b.GetPixel(16, 26) == 0 {
+		t.Error("expected the ends of the arc to be drawn")
+	}
+
+	// The upper half of the circle, going from the left to the right, through the top
+	b.FastClear(0)
+	b.Pie(16, 16, 10, 10, math.Pi, 2*math.Pi, white)
+	for p := range drawn(b) {
+		if p.y > 16 {
+			t.Errorf("expected the pie slice to be in the upper half, got (%d, %d)", p.x, p.y)
+		}
+	}
+	if b.GetPixel(16, 10) == 0 || b.GetPixel(16, 20) != 0 {
+		t.Error("unexpected pie slice")
+	}
+
+	// An end angle that is smaller than the start angle goes around
+	b.FastClear(0)
+	b.Pie(16, 16, 10, 10, 3*math.Pi/2, math.Pi/2, white)
+	if b.GetPixel(20, 16) == 0 || b.GetPixel(12, 16) != 0 {
+		t.Error("expected the pie slice to be on the right side")
+	}
+}
+
+func TestAntiAliasedCircle(t *testing.T) {
+	// The sum of the coverage is close to the area of the shapes
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	coverage := func(b *Buffer) float64 {
+		sum := 0.0
+		for _, cv := range b.Pixels {
+			sum += float64(cv&0xff) / 255
+		}
+		return sum
+	}
+	const r = 10.0
+	shapes := []struct {
+		name     string
+		draw     func(b *Buffer)
+		expected float64
+	}{
+		{"filled circle", func(b *Buffer) { b.AFilledCircle(16.3, 15.8, r, white) }, math.Pi * r * r},
+		{"circle", func(b *Buffer) { b.ACircle(16, 16, r, white) }, 2 * math.Pi * r},
+		{"filled ellipse", func(b *Buffer) { b.AFilledEllipse(16, 16, r, r/2, white) }, math.Pi * r * r / 2},
+		{"pie", func(b *Buffer) { b.APie(16, 16, r, r, 0.3, 0.3+math.Pi/2, white) }, math.Pi * r * r / 4},
+		{"arc", func(b *Buffer) { b.AArc(16, 16, r, r, 1, 1+math.Pi, white) }, math.Pi * r},
+	}
+	for _, s := range shapes {
+		b := NewBuffer(32, 32)
+		b.FastClear(0xff000000)
+		s.draw(b)
+		if sum := coverage(b); math.Abs(sum-s.expected) > s.expected*0.05 {
+			t.Errorf("%s: expected a coverage of about %.1f, got %.1f", s.name, s.expected, sum)
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"os"
 	"runtime"
 
-	"github.com/fogleman/fauxgl"
 	"github.com/veandco/go-sdl2/sdl"
 	pp "github.com/xyproto/pixelpusher"
 )
@@ -43,12 +42,12 @@ var (
 // DrawAll fills the pixel buffer with pixels.
 // "cores" is how many CPU cores should be targeted when drawing triangles,
 // by launching the same number of goroutines.
-func DrawAll(pixels []uint32, cores int, mesh *fauxgl.Mesh, cameraAngle float32, meshHexColor string) {
+func DrawAll(pixels []uint32, cores int, depth *pp.DepthBuffer, mesh *pp.Mesh, cameraAngle float32, meshColor color.RGBA) {
 	// Draw a triangle, concurrently
 	pp.WireTriangle(cores, pixels, rw(), rh(), rw(), rh(), rw(), rh(), color.RGBA{rb(), rb(), rb(), opaque}, pitch)
 
 	// Draw a 3D object on top
-	DrawMesh(pixels, pitch, mesh, cameraAngle, meshHexColor)
+	DrawMesh(cores, pixels, pitch, depth, mesh, cameraAngle, meshColor)
 }
 
 func run() int {
@@ -87,10 +86,11 @@ func run() int {
 	if err != nil {
 		panic(err)
 	}
-	meshHexColor := "#ff0000"
+	meshColor := color.RGBA{0xff, 0, 0, opaque}
 
 	var (
 		pixels      = make([]uint32, width*height)
+		depth       = pp.NewDepthBuffer(width, height)
 		cores       = runtime.NumCPU()
 		event       sdl.Event
 		quit        bool
@@ -103,7 +103,7 @@ func run() int {
 
 		if !pause {
 
-			DrawAll(pixels, cores, mesh, cameraAngle, meshHexColor)
+			DrawAll(pixels, cores, depth, mesh, cameraAngle, meshColor)
 
 			cameraAngle += 0.1
 			if cameraAngle >= 3.14*2.0 {
@@ -1,28 +1,23 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"github.com/fogleman/fauxgl"
-	"github.com/nfnt/resize"
-	"github.com/xyproto/pixelpusher"
-	"image"
+	"image/color"
 	"math"
+
+	"github.com/xyproto/pixelpusher"
 )
 
-func LoadMeshOBJ(filename string) (*fauxgl.Mesh, error) {
+func LoadMeshOBJ(filename string) (*pixelpusher.Mesh, error) {
 	fmt.Printf("Loading %s... ", filename)
-	// load the mesh
-	mesh, err := fauxgl.LoadOBJ(filename)
+	// load the mesh, and the materials
+	mesh, err := pixelpusher.LoadOBJ(filename)
 	if err != nil {
 		return nil, err
 	}
 
 	// fit mesh in a bi-unit cube centered at the origin
-	mesh.BiUnitCube()
-
-	// smooth the normals
-	mesh.SmoothNormalsThreshold(fauxgl.Radians(30))
+	mesh.FitCube()
 
 	fmt.Println("ok")
 
@@ -30,50 +25,38 @@ func LoadMeshOBJ(filename string) (*fauxgl.Mesh, error) {
 	return mesh, nil
 }
 
-func DrawMesh(pixels []uint32, pitch int32, mesh *fauxgl.Mesh, cameraAngle float32, hexColor string) error {
+// DrawMesh draws the mesh directly to the pixels, with pixelpusher's own triangle rasterizer.
+// The depth buffer is cleared before drawing.
+func DrawMesh(cores int, pixels []uint32, pitch int32, depth *pixelpusher.DepthBuffer, mesh *pixelpusher.Mesh, cameraAngle float32, c color.RGBA) {
 	const (
-		scale = 4  // optional supersampling
-		fovy  = 45 // vertical field of view in degrees
-		near  = 1  // near clipping plane
-		far   = 20 // far clipping plane
+		fovy = 45 // vertical field of view in degrees
+		near = 1  // near clipping plane
+		far  = 20 // far clipping plane
 	)
 	var (
-		center = fauxgl.V(0, -0.07, 0)                // view center position
-		up     = fauxgl.V(0, 1, 0)                    // up vector
-		light  = fauxgl.V(-0.75, 1, 0.25).Normalize() // light direction
-		color  = fauxgl.HexColor(hexColor)            // object color
+		center = pixelpusher.Vec3{X: 0, Y: -0.07, Z: 0}    // view center position
+		up     = pixelpusher.Vec3{X: 0, Y: 1, Z: 0}        // up vector
+		light  = pixelpusher.Vec3{X: -0.75, Y: 1, Z: 0.25} // light direction
 	)
 
 	// Camera position, calculated from cameraAngle
-	cameraX := math.Cos(float64(cameraAngle)) * 4.0
-	cameraY := math.Sin(float64(cameraAngle)) * 4.0
-	camera := fauxgl.V(cameraX, cameraY, 10.0)
-
-	// create a rendering context
-	context := fauxgl.NewContext(width*scale, height*scale)
-
-	// white transparent background
-	context.ClearColorBufferWith(fauxgl.HexColor("#fffffffff")) // #FFF8E3
-
-	// create transformation matrix and light direction
-	aspect := float64(width) / float64(height)
-	matrix := fauxgl.LookAt(camera, center, up).Perspective(fovy, aspect, near, far)
-
-	// use builtin phong shader
-	shader := fauxgl.NewPhongShader(matrix, light, camera)
-	shader.ObjectColor = color
-	context.Shader = shader
+	cameraX := float32(math.Cos(float64(cameraAngle)) * 4.0)
+	cameraY := float32(math.Sin(float64(cameraAngle)) * 4.0)
+	camera := pixelpusher.Vec3{X: cameraX, Y: cameraY, Z: 10.0}
+
+	// draw to the pixels, and hide what is behind with the depth buffer
+	b := pixelpusher.NewBufferFromPixels(pixels, pitch)
+	depth.Clear(float32(math.Inf(1)))
+	b.Depth = depth
+
+	// create transformation matrices and light direction
+	aspect := float32(width) / float32(height)
+	opts := pixelpusher.NewMeshOptions()
+	opts.View = pixelpusher.LookAt(camera, center, up)
+	opts.Projection = pixelpusher.Perspective(fovy*math.Pi/180, aspect, near, far)
+	opts.Light = light
+	opts.Color = c
 
 	// render
-	context.DrawMesh(mesh)
-
-	// downsample image for antialiasing
-	resized := resize.Resize(width, height, context.Image(), resize.Bilinear)
-
-	img, ok := resized.(*image.RGBA)
-	if !ok {
-		return errors.New("Could not convert image to *image.RGBA")
-	}
-
-	return pixelpusher.BlitImageOnTop(pixels, pitch, img)
+	b.DrawMesh(cores, mesh, opts)
 }
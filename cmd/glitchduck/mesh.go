@@ -1,12 +1,10 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"github.com/fogleman/fauxgl"
 	"github.com/nfnt/resize"
 	"github.com/xyproto/pixelpusher"
-	"image"
 	"math"
 )
 
@@ -75,10 +73,5 @@ func DrawMesh(pixels []uint32, pitch int32, mesh *fauxgl.Mesh, cameraAngle float
 	// downsample image for antialiasing
 	resized := resize.Resize(width, height, context.Image(), resize.Bilinear)
 
-	img, ok := resized.(*image.RGBA)
-	if !ok {
-		return errors.New("Could not convert image to *image.RGBA")
-	}
-
-	return pixelpusher.BlitImage(pixels, pitch, img)
+	return pixelpusher.BlitImage(pixels, pitch, resized)
 }
@@ -0,0 +1,61 @@
+package pixelpusher
+
+import (
+	"image/color"
+)
+
+// ARGB is a color in the same format as the pixels in a pixel buffer: an ARGB uint32 color value,
+// where the alpha is not premultiplied. It implements color.Color.
+type ARGB uint32
+
+// RGBA returns the alpha-premultiplied red, green, blue and alpha values, in the range 0 to 0xffff
+func (c ARGB) RGBA() (r, g, b, a uint32) {
+	a = uint32(c >> 24)
+	r = uint32(c>>16) & 0xff
+	r |= r << 8
+	r *= a
+	r /= 0xff
+	g = uint32(c>>8) & 0xff
+	g |= g << 8
+	g *= a
+	g /= 0xff
+	b = uint32(c) & 0xff
+	b |= b << 8
+	b *= a
+	b /= 0xff
+	a |= a << 8
+	return
+}
+
+// ARGBModel is the color model for ARGB colors, and for the pixels of a Buffer
+var ARGBModel color.Model = color.ModelFunc(argbModel)
+
+// argbModel converts any color to an ARGB color
+func argbModel(c color.Color) color.Color {
+	if c, ok := c.(ARGB); ok {
+		return c
+	}
+	return ARGB(ToColorValue(c))
+}
+
+// ToColorValue converts any color to an ARGB uint32 color value, where the alpha is not premultiplied
+func ToColorValue(c color.Color) uint32 {
+	switch c := c.(type) {
+	case ARGB:
+		return uint32(c)
+	case color.NRGBA:
+		return RGBAToColorValue(c.R, c.G, c.B, c.A)
+	}
+	r, g, b, a := c.RGBA()
+	switch a {
+	case 0xffff:
+		return RGBAToColorValue(uint8(r>>8), uint8(g>>8), uint8(b>>8), 0xff)
+	case 0:
+		return 0
+	}
+	// Undo the alpha premultiplication
+	r = (r * 0xffff) / a
+	g = (g * 0xffff) / a
+	b = (b * 0xffff) / a
+	return RGBAToColorValue(uint8(r>>8), uint8(g>>8), uint8(b>>8), uint8(a>>8))
+}
@@ -0,0 +1,92 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+func TestARGBModel(t *testing.T) {
+	tests := []struct {
+		c        color.Color
+		expected uint32
+	}{
+		{color.RGBA{0xff, 0x80, 0x00, 0xff}, 0xffff8000},
+		{color.NRGBA{0x10, 0x20, 0x30, 0x40}, 0x40102030},
+		{color.RGBA{0x40, 0x20, 0x00, 0x80}, 0x807f3f00},
+		{color.Gray{0x7f}, 0xff7f7f7f},
+		{color.Transparent, 0},
+		{ARGB(0x12345678), 0x12345678},
+	}
+	for _, test := range tests {
+		if cv := uint32(ARGBModel.Convert(test.c).(ARGB)); cv != test.expected {
+			t.Errorf("converting %v: expected %08x, got %08x", test.c, test.expected, cv)
+		}
+	}
+	// Converting back and forth should give the same color, for opaque colors
+	r, g, b, a := ARGB(0xff804020).RGBA()
+	if r != 0x8080 || g != 0x4040 || b != 0x2020 || a != 0xffff {
+		t.Errorf("unexpected RGBA values: %x %x %x %x", r, g, b, a)
+	}
+}
+
+func TestBufferImage(t *testing.T) {
+	var _ draw.Image = &Buffer{}
+
+	b := NewBuffer(4, 4)
+	b.FastClear(0xff000000)
+
+	// Draw a semi-transparent red square onto the buffer with the image/draw package
+	sub := b.SubBuffer(image.Rect(1, 1, 3, 3))
+	red := image.NewUniform(color.NRGBA{0xff, 0, 0, 0x80})
+	draw.Draw(sub, sub.Bounds(), red, image.Point{}, draw.Over)
+	if cv := b.GetPixel(1, 1); cv != 0xff800000 && cv != 0xff7f0000 {
+		t.Errorf("expected a dark red pixel, got %08x", cv)
+	}
+	if b.GetPixel(0, 0) != 0xff000000 || b.GetPixel(3, 3) != 0xff000000 {
+		t.Error("expected the pixels outside of the sub buffer to be left alone")
+	}
+
+	// Encode the buffer as a PNG directly, then decode and compare
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if ToColorValue(img.At(x, y)) != b.GetPixel(int32(x), int32(y)) {
+				t.Fatalf("the decoded PNG differs from the buffer at (%d, %d)", x, y)
+			}
+		}
+	}
+}
+
+func TestBlitAnyImage(t *testing.T) {
+	// Images that are not *image.RGBA can be blitted, also with a bounds that does not start at (0, 0)
+	gray := image.NewGray(image.Rect(10, 10, 14, 14))
+	for i := range gray.Pix {
+		gray.Pix[i] = 0x40
+	}
+	b := NewBuffer(4, 4)
+	if err := b.BlitImageOnTop(gray); err != nil {
+		t.Fatal(err)
+	}
+	if cv := b.GetPixel(2, 2); cv != 0xff404040 {
+		t.Errorf("expected ff404040, got %08x", cv)
+	}
+	// Blitting one Buffer onto another
+	pixels := make([]uint32, 16)
+	if err := BlitImage(pixels, 4, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := BlitImage(pixels, 4, NewBuffer(2, 2)); err == nil {
+		t.Error("expected an error when blitting an image that is too small")
+	}
+}
@@ -0,0 +1,212 @@
+package pixelpusher
+
+// BlendMode decides how a color that is drawn is combined with the color that is already there.
+// The Porter-Duff operators (BlendOver to BlendXor) combine the colors by using the alpha values only.
+// The artist blend modes (BlendMultiply to BlendDifference) first mix the colors,
+// then draw the result on top with BlendOver.
+type BlendMode int
+
+// Porter-Duff operators, where "source" is the color that is drawn and "destination" is the color that is already there
+const (
+	BlendOver    BlendMode = iota // The source on top of the destination. This is normal alpha blending.
+	BlendClear                    // Transparent black
+	BlendSrc                      // The source replaces the destination
+	BlendDst                      // The destination is kept as it is
+	BlendDstOver                  // The destination on top of the source
+	BlendSrcIn                    // The source, where the destination is
+	BlendDstIn                    // The destination, where the source is
+	BlendSrcOut                   // The source, where the destination is not
+	BlendDstOut                   // The destination, where the source is not
+	BlendSrcAtop                  // The source on top of the destination, only where the destination is
+	BlendDstAtop                  // The destination on top of the source, only where the source is
+	BlendXor                      // The source where the destination is not, and the destination where the source is not
+)
+
+// Artist blend modes
+const (
+	BlendMultiply   BlendMode = iota + 100 // Multiplies the colors, which gives a darker result
+	BlendScreen                            // Inverse multiply, which gives a brighter result
+	BlendOverlay                           // Multiply for dark destination colors, screen for bright ones
+	BlendDarken                            // The darkest of the two colors, for each channel
+	BlendLighten                           // The brightest of the two colors, for each channel
+	BlendAdditive                          // Adds the colors, saturating at the maximum value
+	BlendDifference                        // The absolute difference between the colors
+)
+
+// div255 divides by 255, with rounding, for values up to 255 * 255
+func div255(x uint32) uint32 {
+	x += 128
+	return (x + (x >> 8)) >> 8
+}
+
+// sourceOver draws the src color value on top of the dst color value, using the alpha of both, and returns the result.
+// Both colors have straight alpha. If premultiplied is true, the colors of src are premultiplied by its alpha.
+func sourceOver(dst, src uint32, premultiplied bool) uint32 {
+	sa := src >> 24
+	if sa == 0xff {
+		return src
+	}
+	sr, sg, sb := (src>>16)&0xff, (src>>8)&0xff, src&0xff
+	if sa == 0 && !premultiplied {
+		return dst
+	}
+	if !premultiplied {
+		sr, sg, sb = div255(sr*sa), div255(sg*sa), div255(sb*sa)
+	}
+	da := dst >> 24
+	dr, dg, db := div255(((dst>>16)&0xff)*da), div255(((dst>>8)&0xff)*da), div255((dst&0xff)*da)
+
+	// Porter-Duff source over, with premultiplied colors
+	inv := 0xff - sa
+	a := sa + div255(da*inv)
+	r := sr + div255(dr*inv)
+	g := sg + div255(dg*inv)
+	b := sb + div255(db*inv)
+	if a == 0 {
+		return 0
+	}
+	// Back to straight alpha
+	r, g, b = minUint32(r*0xff/a, 0xff), minUint32(g*0xff/a, 0xff), minUint32(b*0xff/a, 0xff)
+	return a<<24 | r<<16 | g<<8 | b
+}
+
+// minUint32 returns the smallest of two uint32 numbers
+func minUint32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// porterDuffFactors returns the factors that the premultiplied source and destination should be multiplied with,
+// for the given Porter-Duff operator and the source and destination alpha values (0 to 255)
+func (m BlendMode) porterDuffFactors(sa, da uint32) (uint32, uint32) {
+	switch m {
+	case BlendClear:
+		return 0, 0
+	case BlendSrc:
+		return 0xff, 0
+	case BlendDst:
+		return 0, 0xff
+	case BlendDstOver:
+		return 0xff - da, 0xff
+	case BlendSrcIn:
+		return da, 0
+	case BlendDstIn:
+		return 0, sa
+	case BlendSrcOut:
+		return 0xff - da, 0
+	case BlendDstOut:
+		return 0, 0xff - sa
+	case BlendSrcAtop:
+		return da, 0xff - sa
+	case BlendDstAtop:
+		return 0xff - da, sa
+	case BlendXor:
+		return 0xff - da, 0xff - sa
+	}
+	// BlendOver
+	return 0xff, 0xff - sa
+}
+
+// mix mixes a destination and source channel (0 to 255) with the given artist blend mode
+func (m BlendMode) mix(d, s uint32) uint32 {
+	switch m {
+	case BlendMultiply:
+		return div255(d * s)
+	case BlendScreen:
+		return d + s - div255(d*s)
+	case BlendOverlay:
+		if d < 128 {
+			return div255(2 * d * s)
+		}
+		return 0xff - div255(2*(0xff-d)*(0xff-s))
+	case BlendDarken:
+		return minUint32(d, s)
+	case BlendLighten:
+		if d > s {
+			return d
+		}
+		return s
+	case BlendAdditive:
+		return minUint32(d+s, 0xff)
+	case BlendDifference:
+		if d > s {
+			return d - s
+		}
+		return s - d
+	}
+	return s
+}
+
+// Combine draws the src color value on top of the dst color value with the blend mode, and returns the result.
+// Both are ARGB uint32 color values, where the alpha is not premultiplied.
+func (m BlendMode) Combine(dst, src uint32) uint32 {
+	switch m {
+	case BlendSrc:
+		return src
+	case BlendDst:
+		return dst
+	case BlendOver:
+		return sourceOver(dst, src, false)
+	}
+	sa, da := src>>24, dst>>24
+	sr, sg, sb := (src>>16)&0xff, (src>>8)&0xff, src&0xff
+	dr, dg, db := (dst>>16)&0xff, (dst>>8)&0xff, dst&0xff
+
+	fa, fb := m.porterDuffFactors(sa, da)
+	if m >= BlendMultiply {
+		// Mix the colors where the destination is opaque, then draw the mixed color on top with BlendOver.
+		// This is how blend modes are defined in the W3C Compositing and Blending specification.
+		sr = div255((0xff-da)*sr + da*m.mix(dr, sr))
+		sg = div255((0xff-da)*sg + da*m.mix(dg, sg))
+		sb = div255((0xff-da)*sb + da*m.mix(db, sb))
+		fa, fb = BlendOver.porterDuffFactors(sa, da)
+	}
+
+	// Porter-Duff compositing with premultiplied colors
+	a := div255(fa*sa + fb*da)
+	if a == 0 {
+		return 0
+	}
+	r := div255(fa*div255(sr*sa) + fb*div255(dr*da))
+	g := div255(fa*div255(sg*sa) + fb*div255(dg*da))
+	b := div255(fa*div255(sb*sa) + fb*div255(db*da))
+
+	// Back to straight alpha
+	r, g, b = minUint32(r*0xff/a, 0xff), minUint32(g*0xff/a, 0xff), minUint32(b*0xff/a, 0xff)
+	return a<<24 | r<<16 | g<<8 | b
+}
+
+// unpremultiply converts an ARGB uint32 color value with premultiplied alpha to straight alpha
+func unpremultiply(cv uint32) uint32 {
+	a := cv >> 24
+	if a == 0xff {
+		return cv
+	}
+	if a == 0 {
+		return 0
+	}
+	r, g, b := (cv>>16)&0xff, (cv>>8)&0xff, cv&0xff
+	r, g, b = minUint32(r*0xff/a, 0xff), minUint32(g*0xff/a, 0xff), minUint32(b*0xff/a, 0xff)
+	return a<<24 | r<<16 | g<<8 | b
+}
+
+// BlendPixel combines a color value with the pixel at the given position, if it is within the buffer
+func (b *Buffer) BlendPixel(x, y int32, colorValue uint32, mode BlendMode) {
+	if b.Contains(x, y) {
+		i := y*b.Stride + x
+		b.Pixels[i] = b.combine(mode, b.Pixels[i], colorValue)
+	}
+}
+
+// blendSpan combines a color value with the pixels in the given slice
+func (b *Buffer) blendSpan(pixels []uint32, colorValue uint32, mode BlendMode) {
+	if mode == BlendSrc {
+		FastClear(pixels, colorValue)
+		return
+	}
+	for i := range pixels {
+		pixels[i] = b.combine(mode, pixels[i], colorValue)
+	}
+}
@@ -0,0 +1,97 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPorterDuff(t *testing.T) {
+	red := uint32(0xffff0000)
+	halfBlue := uint32(0x800000ff)
+	transparent := uint32(0)
+
+	tests := []struct {
+		mode          BlendMode
+		dst, src, out uint32
+	}{
+		{BlendOver, red, halfBlue, 0xff7f0080},
+		{BlendOver, transparent, halfBlue, halfBlue},
+		{BlendClear, red, halfBlue, 0},
+		{BlendSrc, red, halfBlue, halfBlue},
+		{BlendDst, red, halfBlue, red},
+		{BlendDstOver, halfBlue, red, 0xff7f0080},
+		{BlendSrcIn, red, halfBlue, halfBlue},
+		{BlendSrcIn, transparent, halfBlue, 0},
+		{BlendDstIn, red, halfBlue, 0x80ff0000},
+		{BlendSrcOut, red, halfBlue, 0},
+		{BlendSrcOut, transparent, halfBlue, halfBlue},
+		{BlendDstOut, red, halfBlue, 0x7fff0000},
+		{BlendSrcAtop, red, halfBlue, 0xff7f0080},
+		{BlendSrcAtop, transparent, halfBlue, 0},
+		{BlendDstAtop, red, halfBlue, 0x80ff0000},
+		{BlendXor, red, halfBlue, 0x7fff0000},
+		{BlendXor, transparent, halfBlue, halfBlue},
+	}
+	for _, test := range tests {
+		if out := test.mode.Combine(test.dst, test.src); out != test.out {
+			t.Errorf("mode %d, %08x on top of %08x: expected %08x, got %08x", test.mode, test.src, test.dst, test.out, out)
+		}
+	}
+}
+
+func TestBlendModes(t *testing.T) {
+	dst := uint32(0xff4080c0)
+	src := uint32(0xff808080)
+	tests := []struct {
+		mode BlendMode
+		out  uint32
+	}{
+		{BlendMultiply, 0xff204060},
+		{BlendScreen, 0xffa0c0e0},
+		{BlendOverlay, 0xff4080c0},
+		{BlendDarken, 0xff408080},
+		{BlendLighten, 0xff8080c0},
+		{BlendAdditive, 0xffc0ffff},
+		{BlendDifference, 0xff400040},
+	}
+	for _, test := range tests {
+		if out := test.mode.Combine(dst, src); out != test.out {
+			t.Errorf("mode %d: expected %08x, got %08x", test.mode, test.out, out)
+		}
+	}
+	// Blend modes on a transparent destination just draw the source
+	if out := BlendMultiply.Combine(0, src); out != src {
+		t.Errorf("expected %08x, got %08x", src, out)
+	}
+	// A half transparent source is only half mixed in
+	if out := BlendAdditive.Combine(0xff000000, 0x80ffffff); out != 0xff808080 {
+		t.Errorf("expected ff808080, got %08x", out)
+	}
+}
+
+func TestDrawWithBlendModes(t *testing.T) {
+	b := NewBuffer(8, 8)
+	b.FastClear(0xff404040)
+	c := color.RGBA{0x40, 0x40, 0x40, 0xff}
+
+	// Additive triangles that overlap become brighter where they overlap
+	b.TriangleBlend(4, 0, 0, 8, 0, 0, 8, c, BlendAdditive)
+	b.TriangleBlend(4, 0, 0, 8, 0, 8, 8, c, BlendAdditive)
+	if b.GetPixel(7, 7) != 0xff404040 || b.GetPixel(4, 1) != 0xffc0c0c0 || b.GetPixel(6, 3) != 0xff808080 {
+		t.Errorf("unexpected pixels: %08x %08x %08x", b.GetPixel(7, 7), b.GetPixel(4, 1), b.GetPixel(6, 3))
+	}
+
+	// Multiplying a line
+	b.LineBlend(0, 7, 8, 7, color.RGBA{0x80, 0x80, 0x80, 0xff}, BlendMultiply)
+	if b.GetPixel(7, 7) != 0xff202020 {
+		t.Errorf("expected ff202020, got %08x", b.GetPixel(7, 7))
+	}
+
+	// Blitting a sprite with a blend mode
+	sprite := NewBuffer(1, 1)
+	sprite.Pixels[0] = 0xffffffff
+	b.Blit(sprite, 7, 7, &BlitOptions{Mode: BlendDifference})
+	if b.GetPixel(7, 7) != 0xffdfdfdf {
+		t.Errorf("expected ffdfdfdf, got %08x", b.GetPixel(7, 7))
+	}
+}
@@ -0,0 +1,143 @@
+package pixelpusher
+
+import (
+	"image"
+	"math"
+)
+
+// DepthTest decides if a pixel is drawn, by comparing its depth with the depth that is already in the depth buffer
+type DepthTest int
+
+const (
+	DepthLess         DepthTest = iota // Draw pixels that are nearer than the depth buffer, where smaller values are nearer
+	DepthLessEqual                     // Draw pixels that are nearer than, or as near as, the depth buffer
+	DepthGreater                       // Draw pixels that are further away than the depth buffer
+	DepthGreaterEqual                  // Draw pixels that are further away than, or as far away as, the depth buffer
+	DepthEqual                         // Draw pixels that have the same depth as the depth buffer
+	DepthNotEqual                      // Draw pixels that do not have the same depth as the depth buffer
+	DepthAlways                        // Draw all pixels
+	DepthNever                         // Draw no pixels
+)
+
+// passes checks if a pixel with depth z passes the depth test, where the depth buffer has the value stored
+func (dt DepthTest) passes(z, stored float32) bool {
+	switch dt {
+	case DepthLess:
+		return z < stored
+	case DepthLessEqual:
+		return z <= stored
+	case DepthGreater:
+		return z > stored
+	case DepthGreaterEqual:
+		return z >= stored
+	case DepthEqual:
+		return z == stored
+	case DepthNotEqual:
+		return z != stored
+	case DepthAlways:
+		return true
+	}
+	return false
+}
+
+// DepthBuffer has a depth value for each pixel of a Buffer, which is used for hiding the parts of
+// triangles that are behind what has already been drawn. The depth at (x, y) is Depth[y*Stride+x].
+// The depth buffer is attached to a Buffer by setting Buffer.Depth.
+type DepthBuffer struct {
+	Depth    []float32
+	Width    int32
+	Height   int32
+	Stride   int32
+	Test     DepthTest // How the depth of each pixel is compared with the depth buffer. The default is DepthLess.
+	ReadOnly bool      // Only test against the depth buffer, without storing the depth of the pixels that are drawn
+}
+
+// NewDepthBuffer creates a new depth buffer with the given size, where all depth values are infinitely far away
+func NewDepthBuffer(width, height int32) *DepthBuffer {
+	if width < 0 || height < 0 {
+		width, height = 0, 0
+	}
+	d := &DepthBuffer{
+		Depth:  make([]float32, width*height),
+		Width:  width,
+		Height: height,
+		Stride: width,
+	}
+	d.Clear(float32(math.Inf(1)))
+	return d
+}
+
+// Row returns the depth values of the given row, which must be within the depth buffer
+func (d *DepthBuffer) Row(y int32) []float32 {
+	offset := y * d.Stride
+	return d.Depth[offset : offset+d.Width]
+}
+
+// At returns the depth value at the given position, or positive infinity if it is outside of the depth buffer
+func (d *DepthBuffer) At(x, y int32) float32 {
+	if x < 0 || y < 0 || x >= d.Width || y >= d.Height {
+		return float32(math.Inf(1))
+	}
+	return d.Depth[y*d.Stride+x]
+}
+
+// Clear changes all depth values to z. For DepthLess, z is usually positive infinity or the far value.
+func (d *DepthBuffer) Clear(z float32) {
+	for y := int32(0); y < d.Height; y++ {
+		row := d.Row(y)
+		for x := range row {
+			row[x] = z
+		}
+	}
+}
+
+// sub returns a view into the given rectangle of the depth buffer, sharing the depth values with the depth buffer
+func (d *DepthBuffer) sub(r image.Rectangle) *DepthBuffer {
+	r = r.Intersect(image.Rect(0, 0, int(d.Width), int(d.Height)))
+	s := &DepthBuffer{Stride: d.Stride, Test: d.Test, ReadOnly: d.ReadOnly}
+	if r.Empty() {
+		return s
+	}
+	s.Width, s.Height = int32(r.Dx()), int32(r.Dy())
+	start := int32(r.Min.Y)*d.Stride + int32(r.Min.X)
+	end := start + (s.Height-1)*d.Stride + s.Width
+	s.Depth = d.Depth[start:end:end]
+	return s
+}
+
+// Clone returns a copy of the depth buffer, with its own depth values and no padding between the rows
+func (d *DepthBuffer) Clone() *DepthBuffer {
+	c := &DepthBuffer{
+		Depth:    make([]float32, d.Width*d.Height),
+		Width:    d.Width,
+		Height:   d.Height,
+		Stride:   d.Width,
+		Test:     d.Test,
+		ReadOnly: d.ReadOnly,
+	}
+	for y := int32(0); y < d.Height; y++ {
+		copy(c.Row(y), d.Row(y))
+	}
+	return c
+}
+
+// Image returns the depth buffer as a grayscale image, for debugging, where the near depth value is white
+// and the far depth value is black. Depth values that are further away than far, and values that are not
+// finite, are black.
+func (d *DepthBuffer) Image(near, far float32) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, int(d.Width), int(d.Height)))
+	if near == far {
+		return img
+	}
+	for y := int32(0); y < d.Height; y++ {
+		grayRow := img.Pix[y*int32(img.Stride):]
+		for x, z := range d.Row(y) {
+			t := (z - far) / (near - far)
+			if math.IsInf(float64(z), 0) || t != t {
+				continue
+			}
+			grayRow[x] = uint8(clampUnit(t)*255 + 0.5)
+		}
+	}
+	return img
+}
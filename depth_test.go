@@ -0,0 +1,103 @@
+package pixelpusher
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// square draws a square of two depth tested triangles, covering the pixels from (x1, y1) to (x2, y2), not included
+func square(b *Buffer, x1, y1, x2, y2, z float32, r, g, blue uint8) {
+	a := NewVertex(x1, y1, z, r, g, blue, 0xff)
+	c := NewVertex(x2, y1, z, r, g, blue, 0xff)
+	d := NewVertex(x2, y2, z, r, g, blue, 0xff)
+	e := NewVertex(x1, y2, z, r, g, blue, 0xff)
+	b.DepthTriangle(2, a, c, d)
+	b.DepthTriangle(2, a, d, e)
+}
+
+func TestDepthTriangle(t *testing.T) {
+	b := NewBuffer(32, 32)
+	b.Depth = NewDepthBuffer(32, 32)
+
+	// The nearest square is visible, regardless of the order they are drawn in
+	square(b, 0, 0, 20, 20, 0.2, 0, 0xff, 0)
+	square(b, 10, 10, 30, 30, 0.8, 0xff, 0, 0)
+	if b.GetPixel(15, 15) != 0xff00ff00 || b.GetPixel(25, 25) != 0xffff0000 {
+		t.Error("expected the far square to be behind the near square")
+	}
+	square(b, 10, 10, 30, 30, 0.1, 0, 0, 0xff)
+	if b.GetPixel(15, 15) != 0xff0000ff || b.GetPixel(5, 5) != 0xff00ff00 {
+		t.Error("expected the nearest square to be in front")
+	}
+	if z := b.Depth.At(15, 15); z != 0.1 {
+		t.Errorf("expected the depth to be stored, got %v", z)
+	}
+
+	// The depth is interpolated across the triangle
+	b.Depth.Clear(float32(math.Inf(1)))
+	b.DepthTriangle(1, NewVertex(0, 0, 0, 0xff, 0xff, 0xff, 0xff), NewVertex(30, 0, 1, 0xff, 0xff, 0xff, 0xff), NewVertex(0, 30, 0, 0xff, 0xff, 0xff, 0xff))
+	if z := b.Depth.At(15, 0); math.Abs(float64(z)-0.5) > 1e-6 {
+		t.Errorf("expected the depth to be 0.5, got %v", z)
+	}
+
+	// Read only depth buffers and other depth tests
+	b.Depth.Clear(0.5)
+	b.Depth.ReadOnly = true
+	square(b, 0, 0, 32, 32, 0.4, 0xff, 0xff, 0)
+	if b.GetPixel(31, 31) != 0xffffff00 || b.Depth.At(31, 31) != 0.5 {
+		t.Error("expected the pixels to be drawn, without storing the depth")
+	}
+	b.Depth.Test = DepthGreater
+	square(b, 0, 0, 32, 32, 0.4, 0, 0xff, 0xff)
+	if b.GetPixel(31, 31) != 0xffffff00 {
+		t.Error("expected DepthGreater to hide what is nearer")
+	}
+	b.Depth.Test = DepthNever
+	square(b, 0, 0, 32, 32, 0.9, 0, 0xff, 0xff)
+	if b.GetPixel(31, 31) != 0xffffff00 {
+		t.Error("expected DepthNever to draw nothing")
+	}
+
+	// Sub buffers share the depth buffer
+	b.Depth = NewDepthBuffer(32, 32)
+	sub := b.SubBuffer(image.Rect(8, 8, 16, 16))
+	sub.DepthTriangle(1, NewVertex(0, 0, 0.3, 0, 0, 0, 0xff), NewVertex(8, 0, 0.3, 0, 0, 0, 0xff), NewVertex(0, 8, 0.3, 0, 0, 0, 0xff))
+	if b.Depth.At(8, 8) != 0.3 || !math.IsInf(float64(b.Depth.At(7, 7)), 1) {
+		t.Error("expected the sub buffer to draw to the same depth buffer")
+	}
+
+	// Without a depth buffer, triangles are drawn without depth testing
+	b.Depth = nil
+	square(b, 0, 0, 32, 32, 0.9, 0xff, 0xff, 0xff)
+	if n := count(b, 0xffffffff); n != 32*32 {
+		t.Errorf("expected all pixels to be drawn, got %d", n)
+	}
+}
+
+func TestPerspectiveColors(t *testing.T) {
+	b := NewBuffer(32, 1)
+	b.Depth = NewDepthBuffer(32, 1)
+
+	// The far end of the line of pixels has a w of 3, so the middle of the screen is a quarter of the way in the scene
+	near := NewVertex(0, 0, 0, 0, 0, 0, 0xff)
+	far := NewVertex(32, 0, 0, 0xff, 0xff, 0xff, 0xff)
+	far.SetW(3)
+	below := NewVertex(0, 2, 0, 0, 0, 0, 0xff)
+	b.DepthTriangle(1, near, far, below)
+	if cv := b.GetPixel(16, 0); cv != 0xff404040 {
+		t.Errorf("expected a perspective correct color, got %08x", cv)
+	}
+}
+
+func TestDepthImage(t *testing.T) {
+	d := NewDepthBuffer(3, 1)
+	copy(d.Depth, []float32{1, 5, 9})
+	img := d.Image(1, 9)
+	if img.Pix[0] != 0xff || img.Pix[1] != 0x80 || img.Pix[2] != 0 {
+		t.Errorf("unexpected depth image: %v", img.Pix)
+	}
+	if img = NewDepthBuffer(2, 2).Image(0, 1); img.Pix[0] != 0 {
+		t.Error("expected infinitely far away pixels to be black")
+	}
+}
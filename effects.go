@@ -3,7 +3,6 @@ package pixelpusher
 // The source code in this file is experimental!
 
 import (
-	"github.com/xyproto/pf"
 	"sort"
 )
 
@@ -28,14 +27,33 @@ func (p PairList) Less(i, j int) bool { return p[i].Value < p[j].Value }
 // in the given "pixels" slice (of width "pitch"), discarding the discardRatio ratio of the
 // most unpopular pixel values, then scaling the remaining pixels to cover the full 0..255 range.
 func StretchContrast(cores int, pixels []uint32, pitch int32, discardRatio float32) {
+	NewBufferFromPixels(pixels, pitch).StretchContrast(cores, discardRatio)
+}
 
-	// Find all pixel values, store them in a map[uint8]int, where the int is the count
+// popularity counts how many times each pixel value (intensity) occurs in the buffer
+func (b *Buffer) popularity() map[uint8]int {
 	// TODO: Find a way to concurrently fill several maps, then combine the maps afterwards
 	popularity := make(map[uint8]int)
-	for i := range pixels {
-		v := Value(pixels[i])
-		popularity[v]++
+	for y := int32(0); y < b.Height; y++ {
+		for _, cv := range b.Row(y) {
+			popularity[Value(cv)]++
+		}
 	}
+	return popularity
+}
+
+// StretchContrast uses "cores" CPU cores to concurrently stretch the contrast of the pixels in the buffer,
+// discarding the discardRatio ratio of the most unpopular pixel values, then scaling the remaining
+// pixels to cover the full 0..255 range.
+// If b.Linear is set, the contrast is stretched in linear light.
+func (b *Buffer) StretchContrast(cores int, discardRatio float32) {
+	if b.Linear {
+		b.stretchContrastLinear(cores, discardRatio)
+		return
+	}
+
+	// Find all pixel values, store them in a map[uint8]int, where the int is the count
+	popularity := b.popularity()
 
 	// How large ratio of the values should be discarded?
 	lengthOfSelectedKeys := int(float32(len(popularity)) * (1.0 - discardRatio))
@@ -85,21 +103,23 @@ func StretchContrast(cores int, pixels []uint32, pitch int32, discardRatio float
 	}
 
 	// Map the PixelFunction concurrently to all the pixels
-	pf.Map(cores, scale, pixels)
+	b.Map(cores, scale)
 }
 
 // GlitchyStretchContrast stretches the contrast of the pixels
 // in the given "pixels" slice (of width "pitch"), discarding the discardRatio ratio of the
 // most unpopular pixel values, then scaling the remaining pixels to cover the full 0..255 range.
 func GlitchyStretchContrast(cores int, pixels []uint32, pitch int32, discardRatio float32) {
+	NewBufferFromPixels(pixels, pitch).GlitchyStretchContrast(cores, discardRatio)
+}
+
+// GlitchyStretchContrast stretches the contrast of the pixels in the buffer,
+// discarding the discardRatio ratio of the most unpopular pixel values, then scaling the remaining
+// pixels to cover the full 0..255 range.
+func (b *Buffer) GlitchyStretchContrast(cores int, discardRatio float32) {
 
 	// Find all pixel values, store them in a map[uint8]int, where the int is the count
-	// TODO: Find a way to concurrently fill several maps, then combine the maps afterwards
-	popularity := make(map[uint8]int)
-	for i := range pixels {
-		v := Value(pixels[i])
-		popularity[v]++
-	}
+	popularity := b.popularity()
 
 	// How large ratio of the values should be discarded?
 	lengthOfSelectedKeys := int(float32(len(popularity)) * (1.0 - discardRatio))
@@ -138,19 +158,22 @@ func GlitchyStretchContrast(cores int, pixels []uint32, pitch int32, discardRati
 	widthV := highestV - lowestV
 
 	// Scale all pixels
-	var r, g, b, a uint8
-	for i := range pixels {
-		r, g, b, a = ColorValueToRGBA(pixels[i])
+	var r, g, bl, a uint8
+	for y := int32(0); y < b.Height; y++ {
+		row := b.Row(y)
+		for i := range row {
+			r, g, bl, a = ColorValueToRGBA(row[i])
 
-		ratioR := float32(r-lowestV) / float32(widthV)
-		r = uint8(ratioR * float32(255))
+			ratioR := float32(r-lowestV) / float32(widthV)
+			r = uint8(ratioR * float32(255))
 
-		ratioG := float32(g-lowestV) / float32(widthV)
-		g = uint8(ratioG * float32(255))
+			ratioG := float32(g-lowestV) / float32(widthV)
+			g = uint8(ratioG * float32(255))
 
-		ratioB := float32(b-lowestV) / float32(widthV)
-		b = uint8(ratioB * float32(255))
+			ratioB := float32(bl-lowestV) / float32(widthV)
+			bl = uint8(ratioB * float32(255))
 
-		pixels[i] = RGBAToColorValue(r, g, b, a)
+			row[i] = RGBAToColorValue(r, g, bl, a)
+		}
 	}
 }
@@ -0,0 +1,247 @@
+package pixelpusher
+
+// Event is an input event, as delivered by a Backend.
+// It is one of the *Event types in this file.
+type Event interface{}
+
+// EventFunction is called for every input event
+type EventFunction func(Event) error
+
+// Key is a keyboard key code. The values are the same as the SDL2 key codes,
+// where printable keys have the value of the character they produce.
+type Key int32
+
+// Mod is a bitmask of keyboard modifiers. The values are the same as the SDL2 modifiers.
+type Mod uint16
+
+// MouseButton is a mouse button. The values are the same as the SDL2 mouse buttons.
+type MouseButton uint8
+
+// ControllerButton is a game controller button. The values are the same as the SDL2 controller buttons.
+type ControllerButton uint8
+
+// ControllerAxis is a game controller axis. The values are the same as the SDL2 controller axes.
+type ControllerAxis uint8
+
+// scancodeMask is set for key codes that are based on SDL2 scancodes, for keys that do not produce characters
+const scancodeMask = 1 << 30
+
+// Key codes
+const (
+	KeyUnknown   Key = 0
+	KeyReturn    Key = '\r'
+	KeyEscape    Key = 27
+	KeyBackspace Key = '\b'
+	KeyTab       Key = '\t'
+	KeySpace     Key = ' '
+	KeyComma     Key = ','
+	KeyMinus     Key = '-'
+	KeyPeriod    Key = '.'
+	KeySlash     Key = '/'
+	Key0         Key = '0'
+	Key1         Key = '1'
+	Key2         Key = '2'
+	Key3         Key = '3'
+	Key4         Key = '4'
+	Key5         Key = '5'
+	Key6         Key = '6'
+	Key7         Key = '7'
+	Key8         Key = '8'
+	Key9         Key = '9'
+	KeyA         Key = 'a'
+	KeyB         Key = 'b'
+	KeyC         Key = 'c'
+	KeyD         Key = 'd'
+	KeyE         Key = 'e'
+	KeyF         Key = 'f'
+	KeyG         Key = 'g'
+	KeyH         Key = 'h'
+	KeyI         Key = 'i'
+	KeyJ         Key = 'j'
+	KeyK         Key = 'k'
+	KeyL         Key = 'l'
+	KeyM         Key = 'm'
+	KeyN         Key = 'n'
+	KeyO         Key = 'o'
+	KeyP         Key = 'p'
+	KeyQ         Key = 'q'
+	KeyR         Key = 'r'
+	KeyS         Key = 's'
+	KeyT         Key = 't'
+	KeyU         Key = 'u'
+	KeyV         Key = 'v'
+	KeyW         Key = 'w'
+	KeyX         Key = 'x'
+	KeyY         Key = 'y'
+	KeyZ         Key = 'z'
+	KeyDelete    Key = 127
+	KeyF1        Key = 58 | scancodeMask
+	KeyF2        Key = 59 | scancodeMask
+	KeyF3        Key = 60 | scancodeMask
+	KeyF4        Key = 61 | scancodeMask
+	KeyF5        Key = 62 | scancodeMask
+	KeyF6        Key = 63 | scancodeMask
+	KeyF7        Key = 64 | scancodeMask
+	KeyF8        Key = 65 | scancodeMask
+	KeyF9        Key = 66 | scancodeMask
+	KeyF10       Key = 67 | scancodeMask
+	KeyF11       Key = 68 | scancodeMask
+	KeyF12       Key = 69 | scancodeMask
+	KeyInsert    Key = 73 | scancodeMask
+	KeyHome      Key = 74 | scancodeMask
+	KeyPageUp    Key = 75 | scancodeMask
+	KeyEnd       Key = 77 | scancodeMask
+	KeyPageDown  Key = 78 | scancodeMask
+	KeyRight     Key = 79 | scancodeMask
+	KeyLeft      Key = 80 | scancodeMask
+	KeyDown      Key = 81 | scancodeMask
+	KeyUp        Key = 82 | scancodeMask
+	KeyLCtrl     Key = 224 | scancodeMask
+	KeyLShift    Key = 225 | scancodeMask
+	KeyLAlt      Key = 226 | scancodeMask
+	KeyRCtrl     Key = 228 | scancodeMask
+	KeyRShift    Key = 229 | scancodeMask
+	KeyRAlt      Key = 230 | scancodeMask
+)
+
+// Keyboard modifiers
+const (
+	ModNone   Mod = 0x0000
+	ModLShift Mod = 0x0001
+	ModRShift Mod = 0x0002
+	ModLCtrl  Mod = 0x0040
+	ModRCtrl  Mod = 0x0080
+	ModLAlt   Mod = 0x0100
+	ModRAlt   Mod = 0x0200
+	ModLGUI   Mod = 0x0400
+	ModRGUI   Mod = 0x0800
+	ModNum    Mod = 0x1000
+	ModCaps   Mod = 0x2000
+	ModShift      = ModLShift | ModRShift
+	ModCtrl       = ModLCtrl | ModRCtrl
+	ModAlt        = ModLAlt | ModRAlt
+	ModGUI        = ModLGUI | ModRGUI
+)
+
+// Mouse buttons
+const (
+	MouseLeft   MouseButton = 1
+	MouseMiddle MouseButton = 2
+	MouseRight  MouseButton = 3
+	MouseX1     MouseButton = 4
+	MouseX2     MouseButton = 5
+)
+
+// Game controller buttons
+const (
+	ButtonA ControllerButton = iota
+	ButtonB
+	ButtonX
+	ButtonY
+	ButtonBack
+	ButtonGuide
+	ButtonStart
+	ButtonLeftStick
+	ButtonRightStick
+	ButtonLeftShoulder
+	ButtonRightShoulder
+	ButtonDPadUp
+	ButtonDPadDown
+	ButtonDPadLeft
+	ButtonDPadRight
+)
+
+// Game controller axes
+const (
+	AxisLeftX ControllerAxis = iota
+	AxisLeftY
+	AxisRightX
+	AxisRightY
+	AxisTriggerLeft
+	AxisTriggerRight
+)
+
+// QuitEvent is sent when the window is closed, or when a backend has no more frames to show
+type QuitEvent struct{}
+
+// KeyEvent is sent when a key is pressed or released.
+// Repeat is true if the key is held down and the event is a repeated key press.
+type KeyEvent struct {
+	Key    Key
+	Mod    Mod
+	Down   bool
+	Repeat bool
+}
+
+// TextInputEvent is sent when text is typed
+type TextInputEvent struct {
+	Text string
+}
+
+// MouseMotionEvent is sent when the mouse is moved.
+// X and Y are in window coordinates. WorldX and WorldY are in worldspace coordinates, filled in by Canvas.RunEvents.
+// Buttons is a bitmask where bit (button-1) is set for pressed buttons.
+type MouseMotionEvent struct {
+	X, Y           int32
+	XRel, YRel     int32
+	WorldX, WorldY int32
+	Buttons        uint32
+}
+
+// MouseButtonEvent is sent when a mouse button is pressed or released.
+// X and Y are in window coordinates. WorldX and WorldY are in worldspace coordinates, filled in by Canvas.RunEvents.
+// Clicks is 1 for a single click and 2 for a double click.
+type MouseButtonEvent struct {
+	X, Y           int32
+	WorldX, WorldY int32
+	Button         MouseButton
+	Down           bool
+	Clicks         uint8
+}
+
+// MouseWheelEvent is sent when the mouse wheel is scrolled.
+// Y is positive when scrolling away from the user.
+type MouseWheelEvent struct {
+	X, Y int32
+}
+
+// JoyAxisEvent is sent when a joystick axis is moved.
+// Value is in the range -32768 to 32767.
+type JoyAxisEvent struct {
+	Joystick int32
+	Axis     uint8
+	Value    int16
+}
+
+// JoyButtonEvent is sent when a joystick button is pressed or released
+type JoyButtonEvent struct {
+	Joystick int32
+	Button   uint8
+	Down     bool
+}
+
+// ControllerAxisEvent is sent when a game controller axis is moved.
+// Value is in the range -32768 to 32767.
+type ControllerAxisEvent struct {
+	Joystick int32
+	Axis     ControllerAxis
+	Value    int16
+}
+
+// ControllerButtonEvent is sent when a game controller button is pressed or released
+type ControllerButtonEvent struct {
+	Joystick int32
+	Button   ControllerButton
+	Down     bool
+}
+
+// ResizeEvent is sent when the window has changed size, for instance when switching to fullscreen.
+// Width and Height are in window coordinates.
+type ResizeEvent struct {
+	Width, Height int32
+}
+
+// FocusEvent is sent when the window gains or loses keyboard focus
+type FocusEvent struct {
+	Focused bool
+}
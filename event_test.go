@@ -0,0 +1,101 @@
+package pixelpusher
+
+import (
+	"testing"
+)
+
+func TestActionEvents(t *testing.T) {
+	var pressed, released []string
+	name := func(left, right, up, down, space, enter, esc bool) string {
+		switch {
+		case left:
+			return "left"
+		case right:
+			return "right"
+		case up:
+			return "up"
+		case down:
+			return "down"
+		case space:
+			return "space"
+		case enter:
+			return "enter"
+		case esc:
+			return "esc"
+		}
+		return ""
+	}
+	onPress := func(left, right, up, down, space, enter, esc bool) error {
+		pressed = append(pressed, name(left, right, up, down, space, enter, esc))
+		return nil
+	}
+	onRelease := func(left, right, up, down, space, enter, esc bool) error {
+		released = append(released, name(left, right, up, down, space, enter, esc))
+		return nil
+	}
+	f := ActionEvents(onPress, onRelease)
+	bindings := DefaultBindings()
+	events := []Event{
+		&KeyEvent{Key: KeyS, Down: true},
+		&KeyEvent{Key: KeyS, Mod: ModLCtrl, Down: true},
+		&KeyEvent{Key: KeyReturn, Mod: ModRAlt, Down: true},
+		&KeyEvent{Key: KeyReturn, Down: true},
+		&KeyEvent{Key: KeyReturn},
+		&JoyAxisEvent{Axis: 0, Value: -32768},
+		&JoyButtonEvent{Button: 0, Down: true},
+		&JoyButtonEvent{Button: 0},
+		&MouseMotionEvent{X: 10, Y: 10},
+		&KeyEvent{Key: KeyQ, Down: true},
+	}
+	for _, event := range events {
+		for _, actionEvent := range bindings.Translate(event) {
+			if err := f(actionEvent); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expectedPressed := []string{"down", "enter", "left", "space", "esc"}
+	expectedReleased := []string{"enter", "space"}
+	if len(pressed) != len(expectedPressed) || len(released) != len(expectedReleased) {
+		t.Fatalf("expected %v and %v, got %v and %v", expectedPressed, expectedReleased, pressed, released)
+	}
+	for i := range pressed {
+		if pressed[i] != expectedPressed[i] {
+			t.Errorf("expected %v, got %v", expectedPressed, pressed)
+		}
+	}
+	for i := range released {
+		if released[i] != expectedReleased[i] {
+			t.Errorf("expected %v, got %v", expectedReleased, released)
+		}
+	}
+
+	// Without a press function, esc quits
+	if err := ActionEvents(nil, nil)(&ActionEvent{Action: ActionEsc, Down: true}); err != ErrQuit {
+		t.Error("expected ErrQuit, got:", err)
+	}
+}
+
+func TestRunEvents(t *testing.T) {
+	canvas := New("Events")
+	headless := NewHeadlessBackend(3)
+	headless.Send(1, &MouseButtonEvent{X: 4, Y: 8, Button: MouseLeft, Down: true}, &TextInputEvent{Text: "hi"})
+	canvas.Backend = headless
+	var received []Event
+	onEvent := func(event Event) error {
+		received = append(received, event)
+		return nil
+	}
+	if err := canvas.RunEvents(nil, onEvent, nil); err != ErrQuit {
+		t.Fatal("expected ErrQuit, got:", err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(received))
+	}
+	if e, ok := received[0].(*MouseButtonEvent); !ok || e.Button != MouseLeft || !e.Down {
+		t.Errorf("unexpected event: %v", received[0])
+	}
+	if e, ok := received[1].(*TextInputEvent); !ok || e.Text != "hi" {
+		t.Errorf("unexpected event: %v", received[1])
+	}
+}
@@ -0,0 +1,149 @@
+package pixelpusher
+
+import (
+	"image"
+	"image/color"
+)
+
+// FillOptions are options for FloodFill, BoundaryFill and FillMask. The zero value fills the 4-connected
+// pixels that have exactly the same color as the start pixel, with BlendOver.
+type FillOptions struct {
+	EightConnected bool        // Also fill pixels that are only connected diagonally
+	Tolerance      uint8       // How much each channel, including alpha, may differ from the color that is compared with
+	Pattern        image.Image // Fill with this image, repeated from the upper left corner of the buffer, instead of a color
+	Mode           BlendMode   // How the color or pattern is combined with the pixels that are filled
+}
+
+// colorDistance returns the largest difference between the channels of two color values, including alpha
+func colorDistance(a, b uint32) uint8 {
+	var d uint8
+	for shift := 0; shift < 32; shift += 8 {
+		x, y := uint8(a>>shift), uint8(b>>shift)
+		if x > y {
+			x, y = y, x
+		}
+		if y-x > d {
+			d = y - x
+		}
+	}
+	return d
+}
+
+// FloodFill fills the area around (x, y) that has the same color as (x, y), with the given color.
+// pitch is the width of the pixel buffer.
+func FloodFill(pixels []uint32, x, y int32, c color.RGBA, pitch int32) {
+	NewBufferFromPixels(pixels, pitch).FloodFill(x, y, c, nil)
+}
+
+// FloodFill fills the area around (x, y) where the pixels have the same color as (x, y), within the tolerance,
+// with the given color, or with the pattern in opts. opts can be nil.
+func (b *Buffer) FloodFill(x, y int32, c color.RGBA, opts *FillOptions) {
+	if opts == nil {
+		opts = &FillOptions{}
+	}
+	b.paintMask(b.FillMask(x, y, opts), c, opts)
+}
+
+// BoundaryFill fills the area around (x, y) until pixels with the boundary color are reached, with the given color,
+// or with the pattern in opts. Pixels that are within the tolerance from the boundary color are not filled.
+// opts can be nil.
+func (b *Buffer) BoundaryFill(x, y int32, boundary, c color.RGBA, opts *FillOptions) {
+	if opts == nil {
+		opts = &FillOptions{}
+	}
+	boundaryValue := ColorToColorValue(boundary)
+	b.paintMask(b.fillMask(x, y, opts.EightConnected, func(cv uint32) bool {
+		return colorDistance(cv, boundaryValue) > opts.Tolerance
+	}), c, opts)
+}
+
+// FillMask returns the area that FloodFill would fill, as a mask that covers the buffer,
+// where the filled pixels are 0xff and the others are 0. The pixels of the buffer are not changed.
+// opts can be nil.
+func (b *Buffer) FillMask(x, y int32, opts *FillOptions) *image.Alpha {
+	if opts == nil {
+		opts = &FillOptions{}
+	}
+	start := b.GetPixel(x, y)
+	return b.fillMask(x, y, opts.EightConnected, func(cv uint32) bool {
+		return colorDistance(cv, start) <= opts.Tolerance
+	})
+}
+
+// fillMask finds the pixels that are connected to (x, y) and that match, with a span based flood fill
+// that keeps a stack of positions to continue from, instead of recursing
+func (b *Buffer) fillMask(x, y int32, eightConnected bool, match func(cv uint32) bool) *image.Alpha {
+	mask := image.NewAlpha(b.Rect())
+	if !b.Contains(x, y) {
+		return mask
+	}
+	fillable := func(x, y int32) bool {
+		return mask.Pix[y*int32(mask.Stride)+x] == 0 && match(b.Pixels[y*b.Stride+x])
+	}
+	stack := []Pos{{x, y}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !fillable(p.x, p.y) {
+			continue
+		}
+		// Find the span of pixels to the left and right that can be filled, and fill it
+		x1, x2 := p.x, p.x
+		for x1 > 0 && fillable(x1-1, p.y) {
+			x1--
+		}
+		for x2 < b.Width-1 && fillable(x2+1, p.y) {
+			x2++
+		}
+		row := mask.Pix[p.y*int32(mask.Stride):]
+		for x := x1; x <= x2; x++ {
+			row[x] = 0xff
+		}
+		// Continue from the start of each span that can be filled, in the rows above and below
+		if eightConnected {
+			x1, x2 = Max2(x1-1, 0), Min2(x2+1, b.Width-1)
+		}
+		for _, ny := range [2]int32{p.y - 1, p.y + 1} {
+			if ny < 0 || ny >= b.Height {
+				continue
+			}
+			inSpan := false
+			for x := x1; x <= x2; x++ {
+				if !fillable(x, ny) {
+					inSpan = false
+				} else if !inSpan {
+					stack = append(stack, Pos{x, ny})
+					inSpan = true
+				}
+			}
+		}
+	}
+	return mask
+}
+
+// paintMask fills the pixels where the mask is not 0, with the color or the pattern in opts
+func (b *Buffer) paintMask(mask *image.Alpha, c color.RGBA, opts *FillOptions) {
+	colorValue := ColorToColorValue(c)
+	var patternAt func(x, y int) uint32
+	var pr image.Rectangle
+	if opts.Pattern != nil {
+		pr = opts.Pattern.Bounds()
+		if !pr.Empty() {
+			patternAt = colorValueFunc(opts.Pattern)
+		}
+	}
+	for y := int32(0); y < b.Height; y++ {
+		maskRow := mask.Pix[y*int32(mask.Stride):]
+		row := b.Row(y)
+		for x := range row {
+			if maskRow[x] == 0 {
+				continue
+			}
+			cv := colorValue
+			if patternAt != nil {
+				cv = patternAt(pr.Min.X+x%pr.Dx(), pr.Min.Y+int(y)%pr.Dy())
+			}
+			row[x] = b.combine(opts.Mode, row[x], cv)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestFloodFill(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	// Two areas that are only connected diagonally
+	b := NewBuffer(8, 8)
+	b.FastClear(0xff000000)
+	b.Line(0, 4, 7, 4, white)
+	b.Line(4, 0, 4, 7, white)
+	b.SetPixel(4, 4, 0xff000000)
+
+	four := b.Clone()
+	four.FloodFill(1, 1, red, nil)
+	if four.GetPixel(0, 0) != 0xffff0000 || four.GetPixel(4, 4) != 0xff000000 || four.GetPixel(6, 6) != 0xff000000 {
+		t.Error("expected only the upper left area to be filled with 4-connectivity")
+	}
+	if n := count(four, 0xffff0000); n != 16 {
+		t.Errorf("expected 16 pixels to be filled, got %d", n)
+	}
+	eight := b.Clone()
+	eight.FloodFill(1, 1, red, &FillOptions{EightConnected: true})
+	if n := count(eight, 0xff000000); n != 0 || eight.GetPixel(4, 4) != 0xffff0000 {
+		t.Error("expected all the diagonally connected areas to be filled with 8-connectivity")
+	}
+
+	// The free function fills the same pixels
+	pixels := make([]uint32, len(b.Pixels))
+	copy(pixels, b.Pixels)
+	FloodFill(pixels, 1, 1, red, 8)
+	for i := range pixels {
+		if pixels[i] != four.Pixels[i] {
+			t.Fatalf("the free function differs at %d", i)
+		}
+	}
+
+	// Filling outside of the buffer does nothing
+	b.FloodFill(-1, 3, red, nil)
+	b.FloodFill(3, 8, red, nil)
+}
+
+func TestFloodFillTolerance(t *testing.T) {
+	// A horizontal gradient
+	b := NewBuffer(16, 4)
+	for x := int32(0); x < 16; x++ {
+		for y := int32(0); y < 4; y++ {
+			b.PixelRGB(x, y, uint8(x*8), 0, 0)
+		}
+	}
+	mask := b.FillMask(4, 1, &FillOptions{Tolerance: 16})
+	for x := 0; x < 16; x++ {
+		filled := mask.AlphaAt(x, 2).A == 0xff
+		if expected := x >= 2 && x <= 6; filled != expected {
+			t.Errorf("at x %d: expected %v, got %v", x, expected, filled)
+		}
+	}
+	// FillMask does not change the pixels
+	if b.GetPixel(4, 1) != 0xff200000 {
+		t.Error("expected the pixels to be left alone")
+	}
+}
+
+func TestBoundaryFill(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	b := NewBuffer(32, 32)
+	b.FastClear(0xff000000)
+	// Noise inside of the circle does not stop the boundary fill
+	b.Circle(16, 16, 10, white)
+	b.SetPixel(14, 14, 0xff102030)
+	b.BoundaryFill(16, 16, white, color.RGBA{0, 0, 0xff, 0xff}, nil)
+	if b.GetPixel(14, 14) != 0xff0000ff || b.GetPixel(16, 24) != 0xff0000ff || b.GetPixel(1, 1) != 0xff000000 {
+		t.Error("expected the inside of the circle to be filled")
+	}
+
+	// Filling with a pattern of stripes, from the upper left corner of the buffer
+	pattern := NewBuffer(2, 1)
+	pattern.Pixels[0], pattern.Pixels[1] = 0xff00ff00, 0xffff00ff
+	b.FloodFill(0, 0, white, &FillOptions{Pattern: pattern, Mode: BlendSrc})
+	if b.GetPixel(0, 0) != 0xff00ff00 || b.GetPixel(1, 0) != 0xffff00ff || b.GetPixel(2, 31) != 0xff00ff00 {
+		t.Error("expected the outside to be filled with the pattern")
+	}
+	if b.GetPixel(16, 16) != 0xff0000ff || b.GetPixel(16, 6) != 0xffffffff {
+		t.Error("expected the circle to be left alone")
+	}
+}
+
+func TestFloodFillRandom(t *testing.T) {
+	// Compare with a simple breadth-first flood fill, on random noise
+	r := rand.New(rand.NewSource(1))
+	b := NewBuffer(64, 48)
+	for i := range b.Pixels {
+		if r.Intn(5) < 2 {
+			b.Pixels[i] = 0xffffffff
+		}
+	}
+	for _, eightConnected := range []bool{false, true} {
+		start := Pos{int32(r.Intn(64)), int32(r.Intn(48))}
+		visited := map[Pos]bool{start: true}
+		queue := []Pos{start}
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for dy := int32(-1); dy <= 1; dy++ {
+				for dx := int32(-1); dx <= 1; dx++ {
+					q := Pos{p.x + dx, p.y + dy}
+					if (dx != 0 && dy != 0 && !eightConnected) || !b.Contains(q.x, q.y) || visited[q] || b.GetPixel(q.x, q.y) != b.GetPixel(start.x, start.y) {
+						continue
+					}
+					visited[q] = true
+					queue = append(queue, q)
+				}
+			}
+		}
+		mask := b.FillMask(start.x, start.y, &FillOptions{EightConnected: eightConnected})
+		for y := int32(0); y < 48; y++ {
+			for x := int32(0); x < 64; x++ {
+				if filled := mask.AlphaAt(int(x), int(y)).A == 0xff; filled != visited[Pos{x, y}] {
+					t.Fatalf("8-connected %v: the mask differs at (%d, %d)", eightConnected, x, y)
+				}
+			}
+		}
+	}
+
+	// A large area does not overflow the stack
+	large := NewBuffer(1024, 1024)
+	large.FloodFill(512, 512, color.RGBA{0xff, 0, 0, 0xff}, nil)
+	if count(large, 0xffff0000) != 1024*1024 {
+		t.Error("expected the whole buffer to be filled")
+	}
+}
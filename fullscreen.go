@@ -14,6 +14,12 @@ func HideCursor() {
 	sdl.ShowCursor(0)
 }
 
+// IsCursorVisible checks if the mouse cursor is shown
+func IsCursorVisible() bool {
+	visible, _ := sdl.ShowCursor(sdl.QUERY)
+	return visible == sdl.ENABLE
+}
+
 // Fullscreen checks if the current window has the WINDOW_FULLSCREEN
 // or WINDOW_FULLSCREEN_DESKTOP flag set.
 func IsFullscreen(window *sdl.Window) bool {
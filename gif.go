@@ -0,0 +1,291 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"compress/lzw"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+)
+
+// GIFEncoder encodes frames to an animated GIF.
+// Each frame gets its own palette of up to 256 colors. Alpha is ignored, like when the pixels are displayed.
+// GIF frame delays are in 1/100 seconds and most viewers do not handle delays below 2/100 seconds,
+// so for framerates above 50, some frames are skipped while keeping the total duration.
+// Frames that are identical to the previous frame are merged into it.
+// Each frame is written as soon as the next different frame is encoded, when its delay is known,
+// so only one frame is kept in memory.
+type GIFEncoder struct {
+	w         io.Writer
+	width     int
+	height    int
+	frameRate int
+	counter   int             // Number of frames given to Encode
+	pending   *image.Paletted // The last stored frame, which is written when its delay is known
+	start     int             // Start time of the pending frame, in 1/100 seconds
+	started   bool            // If the header has been written
+	previous  []uint32        // The pixels of the pending frame
+}
+
+// NewGIFEncoder creates a new GIF encoder for frames of the given size.
+// frameRate is used for calculating the frame delays.
+// The last frame and the end of the GIF are written to w when Close is called.
+func NewGIFEncoder(w io.Writer, width, height, frameRate int) *GIFEncoder {
+	if frameRate <= 0 {
+		frameRate = 60
+	}
+	return &GIFEncoder{w: w, width: width, height: height, frameRate: frameRate}
+}
+
+// centiseconds returns the start time of the given frame, in 1/100 seconds
+func (e *GIFEncoder) centiseconds(frame int) int {
+	return (frame*100 + e.frameRate/2) / e.frameRate
+}
+
+// Encode quantizes and adds a frame, and writes the previous frame if this frame is different
+func (e *GIFEncoder) Encode(pixels []uint32, pitch int32) error {
+	start := e.centiseconds(e.counter)
+	e.counter++
+	if e.pending != nil {
+		if start-e.start < 2 {
+			// Too soon after the previous frame
+			return nil
+		}
+		if e.sameAsPrevious(pixels, pitch) {
+			return nil
+		}
+		if err := e.writeFrame(e.pending, start-e.start); err != nil {
+			return err
+		}
+	}
+	if e.previous == nil {
+		e.previous = make([]uint32, e.width*e.height)
+	}
+	for y := 0; y < e.height; y++ {
+		copy(e.previous[y*e.width:(y+1)*e.width], pixels[int32(y)*pitch:])
+	}
+	e.pending = Quantize(e.previous, int32(e.width), 256)
+	e.start = start
+	return nil
+}
+
+// sameAsPrevious checks if the given pixels are the same as the pending frame, disregarding alpha
+func (e *GIFEncoder) sameAsPrevious(pixels []uint32, pitch int32) bool {
+	for y := 0; y < e.height; y++ {
+		row := pixels[int32(y)*pitch:]
+		prev := e.previous[y*e.width:]
+		for x := 0; x < e.width; x++ {
+			if row[x]&0xffffff != prev[x]&0xffffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// writeFrame writes a frame with its own color table and the given delay, in 1/100 seconds.
+// The header is written before the first frame.
+func (e *GIFEncoder) writeFrame(img *image.Paletted, delay int) error {
+	var buf bytes.Buffer
+	if !e.started {
+		// The header, the logical screen descriptor without a global color table, and an extension for looping
+		buf.WriteString("GIF89a")
+		buf.Write([]byte{byte(e.width), byte(e.width >> 8), byte(e.height), byte(e.height >> 8), 0, 0, 0})
+		buf.WriteString("\x21\xff\x0bNETSCAPE2.0\x03\x01\x00\x00\x00")
+		e.started = true
+	}
+	// The graphic control extension, with the delay
+	delay = min(delay, 0xffff)
+	buf.Write([]byte{0x21, 0xf9, 4, 0, byte(delay), byte(delay >> 8), 0, 0})
+	// The image descriptor, followed by a local color table with 2^bits colors
+	bits := 1
+	for 1<<bits < len(img.Palette) {
+		bits++
+	}
+	buf.Write([]byte{0x2c, 0, 0, 0, 0, byte(e.width), byte(e.width >> 8), byte(e.height), byte(e.height >> 8), 0x80 | byte(bits-1)})
+	for i := 0; i < 1<<bits; i++ {
+		var r, g, b uint32
+		if i < len(img.Palette) {
+			r, g, b, _ = img.Palette[i].RGBA()
+		}
+		buf.Write([]byte{byte(r >> 8), byte(g >> 8), byte(b >> 8)})
+	}
+	// The LZW compressed pixels, in blocks of up to 255 bytes
+	litWidth := max(2, bits)
+	var data bytes.Buffer
+	lw := lzw.NewWriter(&data, lzw.LSB, litWidth)
+	if _, err := lw.Write(img.Pix); err != nil {
+		return err
+	}
+	if err := lw.Close(); err != nil {
+		return err
+	}
+	buf.WriteByte(byte(litWidth))
+	for block := data.Bytes(); len(block) > 0; {
+		n := min(len(block), 255)
+		buf.WriteByte(byte(n))
+		buf.Write(block[:n])
+		block = block[n:]
+	}
+	buf.WriteByte(0)
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+// Close writes the last frame and the end of the animated GIF
+func (e *GIFEncoder) Close() error {
+	if e.pending == nil {
+		return nil
+	}
+	if err := e.writeFrame(e.pending, max(2, e.centiseconds(e.counter)-e.start)); err != nil {
+		return err
+	}
+	e.pending = nil
+	_, err := e.w.Write([]byte{0x3b})
+	return err
+}
+
+// colorBox is a box in RGB space, containing colors and how often they are used
+type colorBox struct {
+	colors []uint32 // RGB values
+	counts []int
+}
+
+// channel returns the given channel of an RGB value, 0 for red, 1 for green and 2 for blue
+func channel(rgb uint32, c uint) uint8 {
+	return uint8(rgb >> (16 - 8*c))
+}
+
+// widest returns the channel with the largest range, and that range
+func (b *colorBox) widest() (uint, int) {
+	var bestChannel uint
+	bestRange := -1
+	for c := uint(0); c < 3; c++ {
+		min, max := uint8(255), uint8(0)
+		for _, rgb := range b.colors {
+			v := channel(rgb, c)
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+		if int(max)-int(min) > bestRange {
+			bestChannel, bestRange = c, int(max)-int(min)
+		}
+	}
+	return bestChannel, bestRange
+}
+
+// Len, Less and Swap sorts by the channel given in sortChannel
+type sortableBox struct {
+	*colorBox
+	sortChannel uint
+}
+
+func (b sortableBox) Len() int { return len(b.colors) }
+func (b sortableBox) Less(i, j int) bool {
+	return channel(b.colors[i], b.sortChannel) < channel(b.colors[j], b.sortChannel)
+}
+func (b sortableBox) Swap(i, j int) {
+	b.colors[i], b.colors[j] = b.colors[j], b.colors[i]
+	b.counts[i], b.counts[j] = b.counts[j], b.counts[i]
+}
+
+// split splits the box in two at the median of its widest channel, weighted by use
+func (b *colorBox) split() (*colorBox, *colorBox) {
+	c, _ := b.widest()
+	sort.Sort(sortableBox{b, c})
+	total := 0
+	for _, count := range b.counts {
+		total += count
+	}
+	// Both halves must have at least one color, also when the last color is used the most
+	i, sum := 1, b.counts[0]
+	for ; i < len(b.counts)-1; i++ {
+		if sum*2 >= total {
+			break
+		}
+		sum += b.counts[i]
+	}
+	return &colorBox{b.colors[:i], b.counts[:i]}, &colorBox{b.colors[i:], b.counts[i:]}
+}
+
+// average returns the average color of the box, weighted by use
+func (b *colorBox) average() color.RGBA {
+	var r, g, bl, total int
+	for i, rgb := range b.colors {
+		count := b.counts[i]
+		r += int(channel(rgb, 0)) * count
+		g += int(channel(rgb, 1)) * count
+		bl += int(channel(rgb, 2)) * count
+		total += count
+	}
+	if total == 0 {
+		return color.RGBA{0, 0, 0, 255}
+	}
+	return color.RGBA{uint8(r / total), uint8(g / total), uint8(bl / total), 255}
+}
+
+// Quantize converts a pixel buffer to a paletted image with at most maxColors colors, disregarding alpha.
+// If there are more colors than that, the palette is found with the median cut algorithm.
+// pitch is the width of the pixel buffer.
+func Quantize(pixels []uint32, pitch int32, maxColors int) *image.Paletted {
+	width := int(pitch)
+	height := len(pixels) / width
+
+	// Count the colors
+	histogram := make(map[uint32]int)
+	for _, cv := range pixels {
+		histogram[cv&0xffffff]++
+	}
+	box := &colorBox{}
+	for rgb := range histogram {
+		box.colors = append(box.colors, rgb)
+	}
+	// Sort the colors, so that the result does not depend on the map order
+	sort.Slice(box.colors, func(i, j int) bool { return box.colors[i] < box.colors[j] })
+	for _, rgb := range box.colors {
+		box.counts = append(box.counts, histogram[rgb])
+	}
+
+	// Split the box with the widest range until there are enough boxes
+	boxes := []*colorBox{box}
+	for len(boxes) < maxColors {
+		best, bestRange := -1, 0
+		for i, b := range boxes {
+			if len(b.colors) < 2 {
+				continue
+			}
+			if _, r := b.widest(); r > bestRange {
+				best, bestRange = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		a, b := boxes[best].split()
+		boxes[best] = a
+		boxes = append(boxes, b)
+	}
+
+	// Create the palette and map every color to its box
+	palette := make(color.Palette, len(boxes))
+	index := make(map[uint32]uint8, len(histogram))
+	for i, b := range boxes {
+		palette[i] = b.average()
+		for _, rgb := range b.colors {
+			index[rgb] = uint8(i)
+		}
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Pix[y*img.Stride+x] = index[pixels[y*width+x]&0xffffff]
+		}
+	}
+	return img
+}
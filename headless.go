@@ -0,0 +1,156 @@
+package pixelpusher
+
+import (
+	"errors"
+	"time"
+)
+
+// HeadlessBackend is a Backend that does not open a window.
+// It makes it possible to run draw loops in tests, on servers or in CI,
+// with scripted input and with the pixels of every presented frame available afterwards.
+// The headless backend never waits, but has a pretend clock that is advanced by Delay,
+// and by Present if VSync is enabled, so that the main loop runs as if it were in real time.
+type HeadlessBackend struct {
+	// Frames is the number of iterations of the main loop before quitting. 0 means no limit.
+	Frames uint64
+	// Until is an optional function that is called after every presented frame.
+	// The main loop quits when it returns true.
+	Until func(c *Canvas, frame uint64) bool
+	// Input contains events that should be delivered during the given iteration of the main loop,
+	// after the frame has been presented. The first iteration is 0.
+	Input map[uint64][]Event
+	// KeepFrames makes Present store a copy of the pixels of every frame in Output
+	KeepFrames bool
+	// Output contains the pixels of the presented frames, if KeepFrames is true
+	Output [][]uint32
+	// ScreenWidth and ScreenHeight is the size of the pretend window.
+	// If they are 0, the size of the canvas multiplied with PixelScale is used.
+	ScreenWidth, ScreenHeight int32
+
+	frame      uint64
+	polled     bool
+	done       bool
+	queue      []Event
+	fullscreen bool
+	canvas     *Canvas
+	clock      time.Duration
+}
+
+// NewHeadlessBackend creates a new headless backend that runs for the given number of frames
+// and keeps a copy of the pixels of every presented frame.
+func NewHeadlessBackend(frames uint64) *HeadlessBackend {
+	return &HeadlessBackend{
+		Frames:     frames,
+		Input:      make(map[uint64][]Event),
+		KeepFrames: true,
+	}
+}
+
+// Send schedules events to be delivered during the given iteration of the main loop
+func (h *HeadlessBackend) Send(frame uint64, events ...Event) {
+	if h.Input == nil {
+		h.Input = make(map[uint64][]Event)
+	}
+	h.Input[frame] = append(h.Input[frame], events...)
+}
+
+// Frame returns the current iteration of the main loop
+func (h *HeadlessBackend) Frame() uint64 {
+	return h.frame
+}
+
+// Open resets the frame counter and the output
+func (h *HeadlessBackend) Open(c *Canvas) error {
+	h.canvas = c
+	h.frame = 0
+	h.polled = false
+	h.done = false
+	h.queue = nil
+	h.Output = nil
+	h.clock = 0
+	return nil
+}
+
+// Present stores a copy of the pixels, if KeepFrames is true
+func (h *HeadlessBackend) Present(c *Canvas) error {
+	if h.KeepFrames {
+		pixels := make([]uint32, len(c.Pixels))
+		copy(pixels, c.Pixels)
+		h.Output = append(h.Output, pixels)
+	}
+	if h.Until != nil && h.Until(c, h.frame) {
+		h.done = true
+	}
+	if c.VSync && c.FrameRate > 0 {
+		// Pretend to wait for the display, which refreshes at the framerate
+		frameDuration := time.Second / time.Duration(c.FrameRate)
+		h.clock += frameDuration - h.clock%frameDuration
+	}
+	return nil
+}
+
+// ScreenSize returns the size of the pretend window
+func (h *HeadlessBackend) ScreenSize() (int32, int32) {
+	if h.ScreenWidth > 0 && h.ScreenHeight > 0 {
+		return h.ScreenWidth, h.ScreenHeight
+	}
+	if h.canvas == nil {
+		return 0, 0
+	}
+	return int32(h.canvas.Width * h.canvas.PixelScale), int32(h.canvas.Height * h.canvas.PixelScale)
+}
+
+// CursorVisible returns false in fullscreen mode, like for the SDL2 backend
+func (h *HeadlessBackend) CursorVisible() bool {
+	return !h.fullscreen
+}
+
+// PollEvent returns the scripted events for the current iteration of the main loop, one by one.
+// A QuitEvent is returned after the last frame.
+func (h *HeadlessBackend) PollEvent() Event {
+	if !h.polled {
+		h.polled = true
+		h.queue = append(h.queue, h.Input[h.frame]...)
+		if h.done || (h.Frames > 0 && h.frame+1 >= h.Frames) {
+			h.queue = append(h.queue, &QuitEvent{})
+		}
+	}
+	if len(h.queue) == 0 {
+		return nil
+	}
+	event := h.queue[0]
+	h.queue = h.queue[1:]
+	return event
+}
+
+// Delay does not wait, but advances the pretend clock and marks the end of an iteration of the main loop
+func (h *HeadlessBackend) Delay(d time.Duration) {
+	h.clock += d
+	h.frame++
+	h.polled = false
+}
+
+// Now returns the pretend time since Open was called
+func (h *HeadlessBackend) Now() time.Duration {
+	return h.clock
+}
+
+// ToggleFullscreen only keeps track of the fullscreen state
+func (h *HeadlessBackend) ToggleFullscreen() bool {
+	h.fullscreen = !h.fullscreen
+	return h.fullscreen
+}
+
+// Screenshot saves the pixels of the canvas to a PNG file.
+// Set overwrite to true for overwriting any existing files.
+func (h *HeadlessBackend) Screenshot(filename string, overwrite bool) error {
+	if h.canvas == nil {
+		return errors.New("the headless backend has not been opened")
+	}
+	return SavePixelsToPNG(h.canvas.Pixels, h.canvas.Pitch, filename, overwrite)
+}
+
+// Close does nothing, since the headless backend holds no resources
+func (h *HeadlessBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package pixelpusher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHeadlessSimpleDraw(t *testing.T) {
+	// The same program as cmd/simpledraw
+	x, y := 160, 100
+	onDraw := func(canvas *Canvas) error {
+		return Plot(canvas, x, y, 255, 0, 0)
+	}
+	onPress := func(left, right, up, down, space, enter, esc bool) error {
+		if up {
+			y--
+		} else if down {
+			y++
+		}
+		if left {
+			x--
+		} else if right {
+			x++
+		}
+		if esc {
+			return errors.New("quit")
+		}
+		return nil
+	}
+
+	canvas := New("Simple Draw")
+	headless := NewHeadlessBackend(10)
+	headless.Send(0, &KeyEvent{Key: KeyRight, Down: true}, &KeyEvent{Key: KeyRight})
+	headless.Send(1, &KeyEvent{Key: KeyDown, Down: true}, &KeyEvent{Key: KeyDown})
+	canvas.Backend = headless
+
+	if err := canvas.Run(onDraw, onPress, nil, nil); err != ErrQuit {
+		t.Fatal("expected ErrQuit, got:", err)
+	}
+	if len(headless.Output) != 10 {
+		t.Fatalf("expected 10 frames, got %d", len(headless.Output))
+	}
+	red := RGBAToColorValue(255, 0, 0, 255)
+	if headless.Output[0][100*320+160] != red {
+		t.Error("the first pixel was not drawn in the first frame")
+	}
+	if headless.Output[0][100*320+161] == red {
+		t.Error("the second pixel was drawn too early")
+	}
+	if headless.Output[1][100*320+161] != red {
+		t.Error("the second pixel was not drawn after moving right")
+	}
+	if headless.Output[2][101*320+161] != red {
+		t.Error("the third pixel was not drawn after moving down")
+	}
+
+	// Pressing esc makes the press function return an error
+	headless = NewHeadlessBackend(0)
+	headless.Send(5, &KeyEvent{Key: KeyEscape, Down: true})
+	canvas.Backend = headless
+	if err := canvas.Run(onDraw, onPress, nil, nil); err == nil || err == ErrQuit {
+		t.Error("expected the error from onPress, got:", err)
+	}
+	if len(headless.Output) != 6 {
+		t.Errorf("expected 6 frames, got %d", len(headless.Output))
+	}
+}
+
+func TestHeadlessUntil(t *testing.T) {
+	canvas := New("Until")
+	headless := NewHeadlessBackend(0)
+	headless.Until = func(c *Canvas, frame uint64) bool {
+		return c.Pixels[0] == 0xffffffff
+	}
+	canvas.Backend = headless
+	counter := 0
+	onDraw := func(c *Canvas) error {
+		counter++
+		if counter == 4 {
+			FastClear(c.Pixels, 0xffffffff)
+		}
+		return nil
+	}
+	if err := canvas.Run(onDraw, nil, nil, nil); err != ErrQuit {
+		t.Fatal("expected ErrQuit, got:", err)
+	}
+	if len(headless.Output) != 4 {
+		t.Errorf("expected 4 frames, got %d", len(headless.Output))
+	}
+}
+
+func TestRunHeadless(t *testing.T) {
+	ticks := 0
+	onTick := func() error {
+		ticks++
+		return nil
+	}
+	frames, err := New("Headless").RunHeadless(3, nil, nil, nil, onTick)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 {
+		t.Errorf("expected 3 frames, got %d", len(frames))
+	}
+	if ticks != 2 {
+		t.Errorf("expected 2 ticks, got %d", ticks)
+	}
+}
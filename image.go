@@ -12,66 +12,98 @@ import (
 
 // PixelsToImage converts a pixel buffer to an image.RGBA image
 func PixelsToImage(pixels []uint32, pitch int32) *image.RGBA {
-	width := pitch
-	height := int32(len(pixels)) / pitch
-
-	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	return NewBufferFromPixels(pixels, pitch).ToImage()
+}
 
-	bs := make([]uint8, 4)
-	for y := int32(0); y < height; y++ {
-		for x := int32(0); x < width; x++ {
-			binary.LittleEndian.PutUint32(bs, pixels[y*pitch+x])
-			c := color.RGBA{bs[2], bs[1], bs[0], bs[3]}
-			img.Set(int(x), int(y), c)
+// ToImage copies the buffer to a new image.RGBA image.
+// Since a Buffer also is an image.Image, this is only needed when an *image.RGBA is required.
+func (b *Buffer) ToImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, int(b.Width), int(b.Height)))
+	for y := int32(0); y < b.Height; y++ {
+		offset := int(y) * img.Stride
+		for x, cv := range b.Row(y) {
+			// image.RGBA uses alpha-premultiplied colors
+			r, g, bl, a := ARGB(cv).RGBA()
+			i := offset + x*4
+			img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = uint8(r>>8), uint8(g>>8), uint8(bl>>8), uint8(a>>8)
 		}
 	}
-
 	return img
 }
 
-// BlitImage blits an image on top of a pixel buffer, while blending
-func BlitImage(pixels []uint32, pitch int32, img *image.RGBA) error {
-	width := pitch
-	height := int32(len(pixels)) / pitch
-
-	rectWidth := int32(img.Rect.Size().X)
-	rectHeight := int32(img.Rect.Size().Y)
-
-	if rectWidth < width || rectHeight < height {
-		return fmt.Errorf("invalid size (%d, %d) for blitting on pixel buffer of size (%d, %d)", rectWidth, rectHeight, width, height)
-	}
-
-	// Loop through target coordinates
-	for y := int32(0); y < height; y++ {
-		offset := y * pitch
-		for x := int32(0); x < width; x++ {
-			cv := ColorToColorValue(img.At(int(x), int(y)).(color.RGBA))
-			pixels[offset+x] = Blend(pixels[offset+x], cv)
+// colorValueFunc returns a function for reading ARGB uint32 color values from the given image,
+// with fast paths for the most common image types
+func colorValueFunc(img image.Image) func(x, y int) uint32 {
+	switch img := img.(type) {
+	case *Buffer:
+		return func(x, y int) uint32 {
+			return img.GetPixel(int32(x), int32(y))
 		}
+	case *image.NRGBA:
+		return func(x, y int) uint32 {
+			i := img.PixOffset(x, y)
+			return RGBAToColorValue(img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3])
+		}
+	case *image.RGBA:
+		return func(x, y int) uint32 {
+			i := img.PixOffset(x, y)
+			if img.Pix[i+3] == 0xff {
+				return RGBAToColorValue(img.Pix[i], img.Pix[i+1], img.Pix[i+2], 0xff)
+			}
+			return ToColorValue(img.RGBAAt(x, y))
+		}
+	}
+	return func(x, y int) uint32 {
+		return ToColorValue(img.At(x, y))
 	}
+}
 
-	return nil
+// BlitImage blits an image on top of a pixel buffer, while blending
+func BlitImage(pixels []uint32, pitch int32, img image.Image) error {
+	return NewBufferFromPixels(pixels, pitch).BlitImage(img)
+}
+
+// BlitImage blits an image on top of the buffer, while blending.
+// The image can be any image.Image, but must be at least as large as the buffer.
+func (b *Buffer) BlitImage(img image.Image) error {
+	if b.Linear {
+		return b.blitImage(img, BlendLinear)
+	}
+	return b.blitImage(img, Blend)
 }
 
 // BlitImageOnTop blits an image on top of a pixel buffer, disregarding any previous pixels.
 // The resulting pixels are opaque.
-func BlitImageOnTop(pixels []uint32, pitch int32, img *image.RGBA) error {
-	width := pitch
-	height := int32(len(pixels)) / pitch
+func BlitImageOnTop(pixels []uint32, pitch int32, img image.Image) error {
+	return NewBufferFromPixels(pixels, pitch).BlitImageOnTop(img)
+}
 
-	rectWidth := int32(img.Rect.Size().X)
-	rectHeight := int32(img.Rect.Size().Y)
+// BlitImageOnTop blits an image on top of the buffer, disregarding any previous pixels.
+// The image can be any image.Image, but must be at least as large as the buffer. The resulting pixels are opaque.
+func (b *Buffer) BlitImageOnTop(img image.Image) error {
+	if b.Linear {
+		return b.blitImage(img, addLinear)
+	}
+	return b.blitImage(img, Add)
+}
 
-	if rectWidth < width || rectHeight < height {
-		return fmt.Errorf("invalid size (%d, %d) for blitting on pixel buffer of size (%d, %d)", rectWidth, rectHeight, width, height)
+// blitImage combines the pixels of the buffer with the pixels of the image, using the given function
+func (b *Buffer) blitImage(img image.Image, combine func(uint32, uint32) uint32) error {
+	rect := img.Bounds()
+	rectWidth := int32(rect.Dx())
+	rectHeight := int32(rect.Dy())
+
+	if rectWidth < b.Width || rectHeight < b.Height {
+		return fmt.Errorf("invalid size (%d, %d) for blitting on pixel buffer of size (%d, %d)", rectWidth, rectHeight, b.Width, b.Height)
 	}
 
+	colorValueAt := colorValueFunc(img)
+
 	// Loop through target coordinates
-	for y := int32(0); y < height; y++ {
-		offset := y * pitch
-		for x := int32(0); x < width; x++ {
-			cv := ColorToColorValue(img.At(int(x), int(y)).(color.RGBA))
-			pixels[offset+x] = Add(pixels[offset+x], cv)
+	for y := int32(0); y < b.Height; y++ {
+		row := b.Row(y)
+		for x := range row {
+			row[x] = combine(row[x], colorValueAt(rect.Min.X+x, rect.Min.Y+int(y)))
 		}
 	}
 
@@ -1,13 +1,15 @@
 package pixelpusher
 
 import (
-	"encoding/binary"
+	"image"
 	"image/color"
+	"math"
 )
 
 // HorizontalLineFast draws a line from (x1, y) to (x2, y), but x1 must be smaller than x2!
+// x2 is not included, and the line is not clipped.
 func HorizontalLineFast(pixels []uint32, y, x1, x2 int32, c color.RGBA, pitch int32) {
-	colorValue := binary.BigEndian.Uint32([]uint8{c.A, c.R, c.G, c.B})
+	colorValue := ColorToColorValue(c)
 	xstart, xstop := x1, x2
 	offset := y * pitch
 	xstart += offset
@@ -17,215 +19,399 @@ func HorizontalLineFast(pixels []uint32, y, x1, x2 int32, c color.RGBA, pitch in
 	}
 }
 
-// HorizontalLine draws a line from (x1, y) to (x2, y)
+// HorizontalLine draws a line from (x1, y) to (x2, y), including both ends
 func HorizontalLine(pixels []uint32, y, x1, x2 int32, c color.RGBA, pitch int32) {
-	if x1 < x2 {
-		HorizontalLineFast(pixels, y, x1, x2, c, pitch)
-	} else {
-		HorizontalLineFast(pixels, y, x2, x1, c, pitch)
-	}
+	NewBufferFromPixels(pixels, pitch).HorizontalLine(y, x1, x2, c)
 }
 
 // VerticalLineFast draws a line from (x, y1) to (x, y2), but y1 must be smaller than y2!
+// y2 is not included, and the line is not clipped.
 func VerticalLineFast(pixels []uint32, x, y1, y2 int32, c color.RGBA, pitch int32) {
-	colorValue := binary.BigEndian.Uint32([]uint8{c.A, c.R, c.G, c.B})
-	for y := y1; y < y2; y += pitch {
+	colorValue := ColorToColorValue(c)
+	for y := y1 * pitch; y < y2*pitch; y += pitch {
 		pixels[y+x] = colorValue
 	}
 }
 
-// VerticalLine draws a line from (x, y1) to (x, y2)
+// VerticalLine draws a line from (x, y1) to (x, y2), including both ends
 func VerticalLine(pixels []uint32, x, y1, y2 int32, c color.RGBA, pitch int32) {
-	if y1 < y2 {
-		VerticalLineFast(pixels, x, y1, y2, c, pitch)
+	NewBufferFromPixels(pixels, pitch).VerticalLine(x, y1, y2, c)
+}
+
+// Line draws a line from (x1, y1) to (x2, y2) to the pixel buffer, including both ends.
+// The line is clipped to the pixel buffer. pixels are the pixels, pitch is the width of the pixel buffer.
+func Line(pixels []uint32, x1, y1, x2, y2 int32, c color.RGBA, pitch int32) {
+	NewBufferFromPixels(pixels, pitch).Line(x1, y1, x2, y2, c)
+}
+
+// LineFast draws a line from (x1, y1) to (x2, y2), including both ends, with Bresenham's algorithm.
+// The pixels are the same as for Line and Buffer.ClippedLine, in both directions.
+// The line is not clipped, so both ends must be within the pixel buffer!
+// pitch is the width of the pixel buffer.
+func LineFast(pixels []uint32, x1, y1, x2, y2 int32, c color.RGBA, pitch int32) {
+	colorValue := ColorToColorValue(c)
+	// Step along the major axis, from the end where the major coordinate is smallest, like Buffer.ClippedLine.
+	// du and dv are the lengths along the major and minor axis, and stepU and stepV are the steps in the buffer.
+	var du, dv, stepU, stepV int32
+	if Abs(y2-y1) > Abs(x2-x1) {
+		if y2 < y1 {
+			x1, y1, x2, y2 = x2, y2, x1, y1
+		}
+		du, dv, stepU, stepV = y2-y1, Abs(x2-x1), pitch, 1
+		if x2 < x1 {
+			stepV = -1
+		}
 	} else {
-		VerticalLineFast(pixels, x, y2, y1, c, pitch)
+		if x2 < x1 {
+			x1, y1, x2, y2 = x2, y2, x1, y1
+		}
+		du, dv, stepU, stepV = x2-x1, Abs(y2-y1), 1, pitch
+		if y2 < y1 {
+			stepV = -pitch
+		}
+	}
+	i, e := y1*pitch+x1, du
+	for k := int32(0); k <= du; k++ {
+		pixels[i] = colorValue
+		i += stepU
+		if e += 2 * dv; e >= 2*du {
+			e -= 2 * du
+			i += stepV
+		}
 	}
 }
 
-// Line draws a line in a completely wrong way to the pixel buffer.
-// pixels are the pixels, pitch is the width of the pixel buffer.
-func Line(pixels []uint32, x1, y1, x2, y2 int32, c color.RGBA, pitch int32) {
-	//fmt.Printf("Line from (%d, %d) to (%d, %d)\n", x1, y1, x2, y2)
+// Cohen-Sutherland outcodes, for where a point is relative to a clip rectangle
+const (
+	outLeft = 1 << iota
+	outRight
+	outTop
+	outBottom
+)
+
+// outcode returns the Cohen-Sutherland outcode for the given point and clip rectangle,
+// where maxX and maxY are included
+func outcode(x, y, minX, minY, maxX, maxY int64) int {
+	code := 0
+	if x < minX {
+		code |= outLeft
+	} else if x > maxX {
+		code |= outRight
+	}
+	if y < minY {
+		code |= outTop
+	} else if y > maxY {
+		code |= outBottom
+	}
+	return code
+}
+
+// ClipLine clips the line from (x1, y1) to (x2, y2) to the given rectangle, with the Cohen-Sutherland algorithm.
+// The returned ends are rounded to the nearest pixel within the rectangle, and ok is false if no part of the
+// line is within the rectangle. The result can be drawn with LineFast, but since the ends are rounded, some of
+// the pixels may differ from those of the unclipped line. Use Buffer.ClippedLine to draw the same pixels as the
+// unclipped line.
+func ClipLine(x1, y1, x2, y2 int32, r image.Rectangle) (cx1, cy1, cx2, cy2 int32, ok bool) {
+	if r.Empty() {
+		return 0, 0, 0, 0, false
+	}
+	minX, minY, maxX, maxY := int64(r.Min.X), int64(r.Min.Y), int64(r.Max.X-1), int64(r.Max.Y-1)
+	ax, ay, bx, by := int64(x1), int64(y1), int64(x2), int64(y2)
+	codeA, codeB := outcode(ax, ay, minX, minY, maxX, maxY), outcode(bx, by, minX, minY, maxX, maxY)
+	for codeA|codeB != 0 {
+		if codeA&codeB != 0 {
+			// Both ends are on the outside of the same edge
+			return 0, 0, 0, 0, false
+		}
+		// Move the end that is outside to the edge of the rectangle
+		code := codeA
+		if code == 0 {
+			code = codeB
+		}
+		var x, y int64
+		switch {
+		case code&outTop != 0:
+			x, y = ax+divRound((bx-ax)*(minY-ay), by-ay), minY
+		case code&outBottom != 0:
+			x, y = ax+divRound((bx-ax)*(maxY-ay), by-ay), maxY
+		case code&outLeft != 0:
+			x, y = minX, ay+divRound((by-ay)*(minX-ax), bx-ax)
+		default:
+			x, y = maxX, ay+divRound((by-ay)*(maxX-ax), bx-ax)
+		}
+		if code == codeA {
+			ax, ay = x, y
+			codeA = outcode(ax, ay, minX, minY, maxX, maxY)
+		} else {
+			bx, by = x, y
+			codeB = outcode(bx, by, minX, minY, maxX, maxY)
+		}
+	}
+	return int32(ax), int32(ay), int32(bx), int32(by), true
+}
+
+// divRound divides a by b, rounding to the nearest integer. b must not be 0.
+func divRound(a, b int64) int64 {
+	if b < 0 {
+		a, b = -a, -b
+	}
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
+
+// floorDiv divides a by b, rounding down. b must be positive.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// HorizontalLine draws a line from (x1, y) to (x2, y), including both ends, clipped to the buffer
+func (b *Buffer) HorizontalLine(y, x1, x2 int32, c color.RGBA) {
+	b.HorizontalLineBlend(y, x1, x2, c, BlendSrc)
+}
+
+// HorizontalLineBlend draws a line from (x1, y) to (x2, y), including both ends, with the given blend mode,
+// clipped to the buffer
+func (b *Buffer) HorizontalLineBlend(y, x1, x2 int32, c color.RGBA, mode BlendMode) {
+	if x2 < x1 {
+		x1, x2 = x2, x1
+	}
+	if y < 0 || y >= b.Height {
+		return
+	}
+	x1, x2 = Max2(x1, 0), Min2(x2, b.Width-1)
+	if x1 > x2 {
+		return
+	}
+	b.blendSpan(b.Pixels[y*b.Stride+x1:y*b.Stride+x2+1], ColorToColorValue(c), mode)
+}
+
+// VerticalLine draws a line from (x, y1) to (x, y2), including both ends, clipped to the buffer
+func (b *Buffer) VerticalLine(x, y1, y2 int32, c color.RGBA) {
+	b.VerticalLineBlend(x, y1, y2, c, BlendSrc)
+}
+
+// VerticalLineBlend draws a line from (x, y1) to (x, y2), including both ends, with the given blend mode,
+// clipped to the buffer
+func (b *Buffer) VerticalLineBlend(x, y1, y2 int32, c color.RGBA, mode BlendMode) {
+	if y2 < y1 {
+		y1, y2 = y2, y1
+	}
+	if x < 0 || x >= b.Width {
+		return
+	}
+	y1, y2 = Max2(y1, 0), Min2(y2, b.Height-1)
+	colorValue := ColorToColorValue(c)
+	for y := y1; y <= y2; y++ {
+		i := y*b.Stride + x
+		b.Pixels[i] = b.combine(mode, b.Pixels[i], colorValue)
+	}
+}
+
+// Line draws a line from (x1, y1) to (x2, y2), including both ends, clipped to the buffer
+func (b *Buffer) Line(x1, y1, x2, y2 int32, c color.RGBA) {
+	b.LineBlend(x1, y1, x2, y2, c, BlendSrc)
+}
+
+// LineBlend draws a line from (x1, y1) to (x2, y2), including both ends, with the given blend mode,
+// clipped to the buffer
+func (b *Buffer) LineBlend(x1, y1, x2, y2 int32, c color.RGBA, mode BlendMode) {
+	b.ClippedLine(x1, y1, x2, y2, b.Rect(), c, mode)
+}
+
+// ClippedLine draws a line from (x1, y1) to (x2, y2), including both ends, with the given blend mode.
+// Only the pixels that are within both the clip rectangle and the buffer are drawn.
+// The pixels that are drawn are the same as for the unclipped line, and the line covers the same
+// pixels when it is drawn in the other direction.
+func (b *Buffer) ClippedLine(x1, y1, x2, y2 int32, clip image.Rectangle, c color.RGBA, mode BlendMode) {
+	clip = clip.Intersect(b.Rect())
+	if clip.Empty() {
+		return
+	}
+	colorValue := ColorToColorValue(c)
 	if y1 == y2 {
-		HorizontalLine(pixels, y1, x1, x2, c, pitch)
+		if y1 >= int32(clip.Min.Y) && y1 < int32(clip.Max.Y) {
+			b.SubBuffer(clip).HorizontalLineBlend(y1-int32(clip.Min.Y), x1-int32(clip.Min.X), x2-int32(clip.Min.X), c, mode)
+		}
 		return
 	}
 	if x1 == x2 {
-		VerticalLine(pixels, x1, y1, y2, c, pitch)
+		if x1 >= int32(clip.Min.X) && x1 < int32(clip.Max.X) {
+			b.SubBuffer(clip).VerticalLineBlend(x1-int32(clip.Min.X), y1-int32(clip.Min.Y), y2-int32(clip.Min.Y), c, mode)
+		}
 		return
 	}
 
-	// First figure out if it's an X or Y major triangle
-
-	xdiff := Abs(x1 - x2)
-	ydiff := Abs(y1 - y2)
+	// Step along the major axis, where u is the major and v is the minor coordinate
+	steep := Abs(y2-y1) > Abs(x2-x1)
+	u1, v1, u2, v2 := int64(x1), int64(y1), int64(x2), int64(y2)
+	minU, maxU, minV, maxV := int64(clip.Min.X), int64(clip.Max.X-1), int64(clip.Min.Y), int64(clip.Max.Y-1)
+	if steep {
+		u1, v1, u2, v2 = v1, u1, v2, u2
+		minU, maxU, minV, maxV = minV, maxV, minU, maxU
+	}
+	if u2 < u1 {
+		u1, v1, u2, v2 = u2, v2, u1, v1
+	}
+	du, dv, sv := u2-u1, v2-v1, int64(1)
+	if dv < 0 {
+		dv, sv = -dv, -1
+		// Mirror the minor axis, so that v increases
+		v1, minV, maxV = -v1, -maxV, -minV
+	}
 
-	colorValue := binary.BigEndian.Uint32([]uint8{c.A, c.R, c.G, c.B})
+	// The pixel at step k is (u1 + k, v1 + floor((2*k*dv + du) / (2*du))).
+	// Find the range of steps that is within the clip rectangle, without stepping through the rest.
+	kMin, kMax := max(0, minU-u1), min(du, maxU-u1)
+	if dv > 0 {
+		kMin = max(kMin, floorDiv(2*du*(minV-v1)-du+2*dv-1, 2*dv))
+		kMax = min(kMax, floorDiv(2*du*(maxV-v1+1)-du-1, 2*dv))
+	}
+	if kMin > kMax {
+		return
+	}
 
-	if xdiff > ydiff {
-		//fmt.Println("X MAJOR")
-		startx := x1
-		starty := y1
-		stopx := x2
-		stopy := y2
-		if x2 < x1 {
-			startx = x2
-			starty = y2
-			stopx = x1
-			stopy = y1
+	// Bresenham's algorithm, starting at step kMin
+	num := 2*kMin*dv + du
+	v, e := v1+floorDiv(num, 2*du), num%(2*du)
+	for k := kMin; k <= kMax; k++ {
+		x, y := int32(u1+k), int32(v*sv)
+		if steep {
+			x, y = y, x
 		}
+		i := y*b.Stride + x
+		b.Pixels[i] = b.combine(mode, b.Pixels[i], colorValue)
+		if e += 2 * dv; e >= 2*du {
+			e -= 2 * du
+			v++
+		}
+	}
+}
 
-		// We're going along X
-		y := float32(starty)
-		ystep := float32(ydiff) / float32(xdiff)
-		if stopy < starty {
-			// Move in the other direction along Y
-			ystep = -ystep
+// withCoverage returns the color value with the alpha multiplied by the given coverage, from 0 to 1
+func withCoverage(colorValue uint32, coverage float32) uint32 {
+	a := uint32(float32(colorValue>>24)*coverage + 0.5)
+	return a<<24 | colorValue&0xffffff
+}
+
+// plotCoverage blends a color value, with the alpha multiplied by the given coverage, with the pixel at (x, y),
+// if it is within the buffer
+func (b *Buffer) plotCoverage(x, y int32, colorValue uint32, coverage float32, mode BlendMode) {
+	if coverage > 0 && b.Contains(x, y) {
+		i := y*b.Stride + x
+		b.Pixels[i] = b.combine(mode, b.Pixels[i], withCoverage(colorValue, coverage))
+	}
+}
+
+// clipLineFloat clips the line from (x1, y1) to (x2, y2) to the rectangle from (minX, minY) to (maxX, maxY),
+// with the Liang-Barsky algorithm. ok is false if no part of the line is within the rectangle.
+func clipLineFloat(x1, y1, x2, y2, minX, minY, maxX, maxY float32) (cx1, cy1, cx2, cy2 float32, ok bool) {
+	t0, t1 := float32(0), float32(1)
+	dx, dy := x2-x1, y2-y1
+	for _, edge := range [4][2]float32{{-dx, x1 - minX}, {dx, maxX - x1}, {-dy, y1 - minY}, {dy, maxY - y1}} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, 0, 0, false
+			}
+			continue
 		}
-		// Draw the line
-		for x := startx; x < stopx; x++ {
-			//fmt.Printf("\t(%d, %d)\n", int32(x), int32(y))
-			pixels[int32(y)*pitch+int32(x)] = colorValue
-			y += ystep
+		t := q / p
+		if p < 0 && t > t0 {
+			t0 = t
+		} else if p > 0 && t < t1 {
+			t1 = t
 		}
-	} else {
-		//fmt.Println("Y MAJOR")
-		startx := x1
-		starty := y1
-		stopx := x2
-		stopy := y2
-		if y2 < y1 {
-			startx = x2
-			starty = y2
-			stopx = x1
-			stopy = y1
-		}
-
-		// We're going along Y
-		x := float32(startx)
-		xstep := float32(xdiff) / float32(ydiff)
-		if stopx < startx {
-			// Move in the other direction along X
-			xstep = -xstep
-		}
-		// Draw the line
-		starty *= pitch
-		stopy *= pitch
-		for y := starty; y < stopy; y += pitch {
-			//fmt.Printf("\t(%d, %d)\n", int32(x), int32(y/pitch))
-			pixels[y+int32(x)] = colorValue
-			x += xstep
-		}
-	}
-}
-
-//func plot(pixels []uint32, x, y int32, brightness float32, c color.RGBA, pitch int32) {
-//	b := uint8(brightness * 255.0)
-//	// Use the brightness to scale the given alpha value
-//	//colorValue := binary.BigEndian.Uint32([]uint8{uint8(float32(c.A) * brightness * 255.0), c.R, c.G, c.B})
-//	colorValue := binary.BigEndian.Uint32([]uint8{0xff, b, b, b})
-//	fmt.Println("PLOTTING AT", x, ",", y)
-//	pixels[y*pitch+x] = colorValue
-//}
-//
-//// integer part of x
-//func ipart(x float32) float32 {
-//	return float32(math.Floor(float64(x)))
-//}
-//
-//// round x
-//func round(x float32) float32 {
-//	return ipart(x + 0.5)
-//}
-//
-//// fractional part of x
-//func fpart(x float32) float32 {
-//	return x - float32(math.Floor(float64(x)))
-//}
-//
-//// 1 - (fractional part of x)
-//func rfpart(x float32) float32 {
-//	return 1 - fpart(x)
-//}
-//
-//func abs(a float32) float32 {
-//	if a >= 0 {
-//		return a
-//	}
-//	return -a
-//}
-//
-//// Line draws an antialiased line using Xiaolin Wu's algorithm
-//// https://en.wikipedia.org/wiki/Xiaolin_Wu%27s_line_algorithm
-//func ALine(pixels []uint32, ox0, oy0, ox1, oy1 int32, c color.RGBA, pitch int32) {
-//
-//	x0 := float32(ox0)
-//	y0 := float32(ox0)
-//	x1 := float32(ox1)
-//	y1 := float32(ox1)
-//
-//	steep := abs(y1-y0) > abs(x1-x0)
-//
-//	if steep {
-//		x0, y0 = y0, x0
-//		x1, y1 = y1, x1
-//	}
-//	if x0 > x1 {
-//		x0, x1 = x1, x0
-//		y0, y1 = y1, y0
-//	}
-//
-//	dx := x1 - x0
-//	dy := y1 - y0
-//
-//	gradient := dy / dx
-//	if dx == 0.0 {
-//		gradient = 1.0
-//	}
-//
-//	// handle first endpoint
-//	xend := round(x0)
-//	yend := y0 + gradient*(xend-x0)
-//
-//	xgap := rfpart(float32(x0) + 0.5)
-//	xpxl1 := xend // this will be used in the main loop
-//	ypxl1 := ipart(float32(yend))
-//	if steep {
-//		plot(pixels, int32(ypxl1), int32(xpxl1), rfpart(yend)*xgap, c, pitch)
-//		plot(pixels, int32(ypxl1+1), int32(xpxl1), fpart(yend)*xgap, c, pitch)
-//	} else {
-//		plot(pixels, int32(xpxl1), int32(ypxl1), rfpart(yend)*xgap, c, pitch)
-//		plot(pixels, int32(xpxl1), int32(ypxl1+1), fpart(yend)*xgap, c, pitch)
-//	}
-//	intery := yend + gradient // first y-intersection for the main loop
-//
-//	// handle second endpoint
-//	xend = round(x1)
-//	yend = y1 + gradient*(xend-x1)
-//	xgap = fpart(x1 + 0.5)
-//	xpxl2 := xend //this will be used in the main loop
-//	ypxl2 := ipart(yend)
-//	if steep {
-//		plot(pixels, int32(ypxl2), int32(xpxl2), rfpart(yend)*xgap, c, pitch)
-//		plot(pixels, int32(ypxl2+1), int32(xpxl2), fpart(yend)*xgap, c, pitch)
-//	} else {
-//		plot(pixels, int32(xpxl2), int32(ypxl2), rfpart(yend)*xgap, c, pitch)
-//		plot(pixels, int32(xpxl2), int32(ypxl2+1), fpart(yend)*xgap, c, pitch)
-//	}
-//
-//	// main loop
-//	if steep {
-//		for x := xpxl1 + 1; x < xpxl2-1; x++ {
-//			plot(pixels, int32(ipart(intery)), int32(x), rfpart(intery), c, pitch)
-//			plot(pixels, int32(ipart(intery)+1), int32(x), fpart(intery), c, pitch)
-//			intery = intery + gradient
-//		}
-//	} else {
-//		for x := xpxl1 + 1; x < xpxl2-1; x++ {
-//			plot(pixels, int32(x), int32(ipart(intery)), rfpart(intery), c, pitch)
-//			plot(pixels, int32(x), int32(ipart(intery)+1), fpart(intery), c, pitch)
-//			intery = intery + gradient
-//		}
-//	}
-//}
+	}
+	if t0 > t1 {
+		return 0, 0, 0, 0, false
+	}
+	return x1 + t0*dx, y1 + t0*dy, x1 + t1*dx, y1 + t1*dy, true
+}
+
+// ALine draws an anti-aliased line from (x1, y1) to (x2, y2) to the pixel buffer, blended on top with BlendOver.
+// pixels are the pixels, pitch is the width of the pixel buffer.
+func ALine(pixels []uint32, x1, y1, x2, y2 int32, c color.RGBA, pitch int32) {
+	NewBufferFromPixels(pixels, pitch).ALine(float32(x1), float32(y1), float32(x2), float32(y2), c)
+}
+
+// ALine draws an anti-aliased line from (x1, y1) to (x2, y2), blended on top with BlendOver, clipped to the buffer.
+// The positions have subpixel precision, and the center of the pixel at (x, y) is at (x, y).
+func (b *Buffer) ALine(x1, y1, x2, y2 float32, c color.RGBA) {
+	b.ALineBlend(x1, y1, x2, y2, c, BlendOver)
+}
+
+// ALineBlend draws an anti-aliased line from (x1, y1) to (x2, y2) with Xiaolin Wu's algorithm, clipped to the buffer.
+// The alpha of the color is multiplied by how much of each pixel is covered, before it is combined with the
+// given blend mode. Blend modes that do not use the alpha of the source, like BlendSrc, give jagged lines.
+// https://en.wikipedia.org/wiki/Xiaolin_Wu%27s_line_algorithm
+func (b *Buffer) ALineBlend(x1, y1, x2, y2 float32, c color.RGBA, mode BlendMode) {
+	// Only draw the part of the line that is within the buffer, with a margin for the partially covered pixels
+	var ok bool
+	x1, y1, x2, y2, ok = clipLineFloat(x1, y1, x2, y2, -2, -2, float32(b.Width)+1, float32(b.Height)+1)
+	if !ok {
+		return
+	}
+
+	colorValue := ColorToColorValue(c)
+	steep := float32(math.Abs(float64(y2-y1))) > float32(math.Abs(float64(x2-x1)))
+	if steep {
+		x1, y1, x2, y2 = y1, x1, y2, x2
+	}
+	if x2 < x1 {
+		x1, y1, x2, y2 = x2, y2, x1, y1
+	}
+	plot := func(x, y int32, coverage float32) {
+		if steep {
+			x, y = y, x
+		}
+		b.plotCoverage(x, y, colorValue, coverage, mode)
+	}
+
+	dx, dy := x2-x1, y2-y1
+	gradient := float32(1)
+	if dx != 0 {
+		gradient = dy / dx
+	}
+
+	// The first end
+	xend := floor(x1 + 0.5)
+	yend := y1 + gradient*(xend-x1)
+	xgap := 1 - (x1 + 0.5 - floor(x1+0.5))
+	xpxl1, ypxl1 := int32(xend), int32(floor(yend))
+	fy := yend - floor(yend)
+
+	// The last end
+	xend2 := floor(x2 + 0.5)
+	yend2 := y2 + gradient*(xend2-x2)
+	xgap2 := x2 + 0.5 - floor(x2+0.5)
+	xpxl2, ypxl2 := int32(xend2), int32(floor(yend2))
+	fy2 := yend2 - floor(yend2)
+
+	if xpxl1 == xpxl2 {
+		// Both ends are in the same column, so only draw it once
+		xgap = clampUnit(x2 - x1)
+		plot(xpxl1, ypxl1, (1-fy)*xgap)
+		plot(xpxl1, ypxl1+1, fy*xgap)
+		return
+	}
+	plot(xpxl1, ypxl1, (1-fy)*xgap)
+	plot(xpxl1, ypxl1+1, fy*xgap)
+	plot(xpxl2, ypxl2, (1-fy2)*xgap2)
+	plot(xpxl2, ypxl2+1, fy2*xgap2)
+
+	// The pixels between the ends
+	intery := yend + gradient
+	for x := xpxl1 + 1; x < xpxl2; x++ {
+		iy := floor(intery)
+		fy := intery - iy
+		plot(x, int32(iy), 1-fy)
+		plot(x, int32(iy)+1, fy)
+		intery += gradient
+	}
+}
@@ -0,0 +1,168 @@
+package pixelpusher
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestLineEndpoints(t *testing.T) {
+	b := NewBuffer(16, 16)
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	lines := [][4]int32{{1, 1, 14, 5}, {14, 2, 3, 13}, {7, 0, 7, 15}, {0, 9, 15, 9}, {5, 5, 5, 5}, {2, 12, 12, 2}}
+	for _, l := range lines {
+		b.FastClear(0)
+		b.Line(l[0], l[1], l[2], l[3], white)
+		if b.GetPixel(l[0], l[1]) == 0 || b.GetPixel(l[2], l[3]) == 0 {
+			t.Errorf("expected both ends of the line from (%d, %d) to (%d, %d) to be drawn", l[0], l[1], l[2], l[3])
+		}
+		// One pixel for each step along the major axis
+		count, expected := int32(0), Max2(Abs(l[2]-l[0]), Abs(l[3]-l[1]))+1
+		for _, cv := range b.Pixels {
+			if cv != 0 {
+				count++
+			}
+		}
+		if count != expected {
+			t.Errorf("expected the line from (%d, %d) to (%d, %d) to cover %d pixels, got %d", l[0], l[1], l[2], l[3], expected, count)
+		}
+		// The same pixels are drawn in both directions, and by LineFast
+		reverse := NewBuffer(16, 16)
+		reverse.Line(l[2], l[3], l[0], l[1], white)
+		fast := make([]uint32, 16*16)
+		LineFast(fast, l[0], l[1], l[2], l[3], white, 16)
+		for i := range b.Pixels {
+			if b.Pixels[i] != reverse.Pixels[i] {
+				t.Errorf("the line from (%d, %d) to (%d, %d) differs when drawn in the other direction", l[0], l[1], l[2], l[3])
+				break
+			}
+		}
+		for i := range b.Pixels {
+			if b.Pixels[i] != fast[i] {
+				t.Errorf("the line from (%d, %d) to (%d, %d) differs when drawn with LineFast", l[0], l[1], l[2], l[3])
+				break
+			}
+		}
+	}
+}
+
+func TestLineFast(t *testing.T) {
+	// LineFast draws the same pixels as Line, for random lines in both directions
+	r := rand.New(rand.NewSource(1))
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	b := NewBuffer(300, 300)
+	fast := make([]uint32, 300*300)
+	for i := 0; i < 2000; i++ {
+		x1, y1, x2, y2 := r.Int31n(300), r.Int31n(300), r.Int31n(300), r.Int31n(300)
+		b.FastClear(0)
+		b.Line(x1, y1, x2, y2, white)
+		for _, ends := range [2][4]int32{{x1, y1, x2, y2}, {x2, y2, x1, y1}} {
+			clear(fast)
+			LineFast(fast, ends[0], ends[1], ends[2], ends[3], white, 300)
+			for j := range fast {
+				if fast[j] != b.Pixels[j] {
+					t.Fatalf("line %d from (%d, %d) to (%d, %d): LineFast differs at (%d, %d)", i, ends[0], ends[1], ends[2], ends[3], j%300, j/300)
+				}
+			}
+		}
+	}
+}
+
+func TestLineClipping(t *testing.T) {
+	// Lines that are clipped should cover the same pixels as unclipped lines, within the clip rectangle
+	r := rand.New(rand.NewSource(1))
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	large := NewBuffer(300, 300)
+	clip := image.Rect(120, 110, 180, 200)
+	for i := 0; i < 500; i++ {
+		x1, y1, x2, y2 := r.Int31n(300), r.Int31n(300), r.Int31n(300), r.Int31n(300)
+		large.FastClear(0)
+		large.Line(x1, y1, x2, y2, white)
+		clipped := NewBuffer(300, 300)
+		clipped.ClippedLine(x1, y1, x2, y2, clip, white, BlendSrc)
+		// The same line, drawn to a smaller buffer, with the ends far outside of it
+		small := NewBuffer(60, 90)
+		small.Line(x1-120, y1-110, x2-120, y2-110, white)
+		for y := int32(0); y < 300; y++ {
+			for x := int32(0); x < 300; x++ {
+				inside := image.Pt(int(x), int(y)).In(clip)
+				expected := uint32(0)
+				if inside {
+					expected = large.GetPixel(x, y)
+				}
+				if clipped.GetPixel(x, y) != expected {
+					t.Fatalf("line %d from (%d, %d) to (%d, %d): ClippedLine differs at (%d, %d)", i, x1, y1, x2, y2, x, y)
+				}
+				if inside && small.GetPixel(x-120, y-110) != expected {
+					t.Fatalf("line %d from (%d, %d) to (%d, %d): the small buffer differs at (%d, %d)", i, x1, y1, x2, y2, x, y)
+				}
+			}
+		}
+	}
+	// Lines with ends very far away should not panic or take a long time
+	b := NewBuffer(8, 8)
+	b.Line(-1<<30, -1<<30, 1<<30, 1<<30, white)
+	if b.GetPixel(0, 0) == 0 || b.GetPixel(7, 7) == 0 {
+		t.Error("expected the diagonal to be drawn")
+	}
+	Line(b.Pixels, -100, 3, 100, 4, white, 8)
+}
+
+func TestClipLine(t *testing.T) {
+	r := image.Rect(0, 0, 10, 10)
+	if x1, y1, x2, y2, ok := ClipLine(-10, 5, 20, 5, r); !ok || x1 != 0 || y1 != 5 || x2 != 9 || y2 != 5 {
+		t.Errorf("unexpected clipped line: (%d, %d) to (%d, %d), %v", x1, y1, x2, y2, ok)
+	}
+	if x1, y1, x2, y2, ok := ClipLine(-5, -5, 15, 15, r); !ok || x1 != 0 || y1 != 0 || x2 != 9 || y2 != 9 {
+		t.Errorf("unexpected clipped line: (%d, %d) to (%d, %d), %v", x1, y1, x2, y2, ok)
+	}
+	if _, _, _, _, ok := ClipLine(-5, 0, 0, -5, r); ok {
+		t.Error("expected the line to be outside of the rectangle")
+	}
+	if _, _, _, _, ok := ClipLine(20, 0, 20, 9, r); ok {
+		t.Error("expected the line to be outside of the rectangle")
+	}
+	// The clipped line can be drawn without clipping
+	pixels := make([]uint32, 100)
+	x1, y1, x2, y2, _ := ClipLine(-30, 2, 40, 8, r)
+	LineFast(pixels, x1, y1, x2, y2, color.RGBA{0xff, 0, 0, 0xff}, 10)
+}
+
+func TestALine(t *testing.T) {
+	b := NewBuffer(16, 16)
+	b.FastClear(0xff000000)
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+
+	// A horizontal line covers the pixels on the line completely, except for half of the end pixels
+	b.ALine(2, 5, 12, 5, white)
+	if b.GetPixel(2, 5) != 0xff808080 || b.GetPixel(12, 5) != 0xff808080 {
+		t.Errorf("expected the ends to be half covered, got %08x and %08x", b.GetPixel(2, 5), b.GetPixel(12, 5))
+	}
+	for x := int32(3); x < 12; x++ {
+		if b.GetPixel(x, 5) != 0xffffffff || b.GetPixel(x, 4) != 0xff000000 || b.GetPixel(x, 6) != 0xff000000 {
+			t.Fatalf("unexpected pixels at x %d: %08x %08x %08x", x, b.GetPixel(x, 4), b.GetPixel(x, 5), b.GetPixel(x, 6))
+		}
+	}
+
+	// A line that is not horizontal is spread out over two pixels in each column
+	b.FastClear(0xff000000)
+	b.ALine(1, 1, 14, 6.5, white)
+	for x := int32(2); x < 14; x++ {
+		sum, drawn := 0, 0
+		for y := int32(0); y < 16; y++ {
+			if v := Red(b.GetPixel(x, y)); v > 0 {
+				sum += int(v)
+				drawn++
+			}
+		}
+		if sum < 250 || sum > 260 || drawn > 2 {
+			t.Errorf("expected the coverage in column %d to add up to 1, got %d over %d pixels", x, sum, drawn)
+		}
+	}
+
+	// Lines that are partly or completely outside of the buffer
+	ALine(b.Pixels, -100, -30, 100, 50, white, 16)
+	b.ALine(-1e9, 3, 1e9, 3.5, white)
+	b.ALine(20, 20, 40, 30, white)
+}
@@ -0,0 +1,362 @@
+package pixelpusher
+
+import (
+	"math"
+	"sort"
+)
+
+// linearTableSize is the number of entries in the table for converting from linear light to sRGB
+const linearTableSize = 4096
+
+var (
+	// srgbToLinearTable converts from an sRGB byte to linear light, from 0 to 1
+	srgbToLinearTable [256]float32
+	// linearToSRGBTable converts from linear light, scaled to 0 .. linearTableSize-1, to an sRGB byte
+	linearToSRGBTable [linearTableSize]uint8
+)
+
+func init() {
+	for i := range srgbToLinearTable {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		srgbToLinearTable[i] = float32(v)
+	}
+	for i := range linearToSRGBTable {
+		v := float64(i) / (linearTableSize - 1)
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		linearToSRGBTable[i] = uint8(math.Round(v * 255))
+	}
+}
+
+// SRGBToLinear converts a color channel from sRGB to linear light, from 0 to 1, by using a lookup table
+func SRGBToLinear(v uint8) float32 {
+	return srgbToLinearTable[v]
+}
+
+// LinearToSRGB converts a color channel from linear light (0 to 1) to sRGB, by using a lookup table
+func LinearToSRGB(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 0xff
+	}
+	return linearToSRGBTable[int32(v*(linearTableSize-1)+0.5)]
+}
+
+// linearColor is a color in linear light, with straight alpha. All values are from 0 to 1.
+type linearColor struct {
+	r, g, b, a float32
+}
+
+// toLinear converts an ARGB uint32 color value to linear light. Alpha is not changed by the conversion.
+func toLinear(cv uint32) linearColor {
+	return linearColor{
+		srgbToLinearTable[(cv>>16)&0xff],
+		srgbToLinearTable[(cv>>8)&0xff],
+		srgbToLinearTable[cv&0xff],
+		float32(cv>>24) / 255,
+	}
+}
+
+// colorValue converts a color in linear light back to an ARGB uint32 color value
+func (c linearColor) colorValue() uint32 {
+	a := uint32(c.a*255 + 0.5)
+	if a > 0xff {
+		a = 0xff
+	}
+	return a<<24 | uint32(LinearToSRGB(c.r))<<16 | uint32(LinearToSRGB(c.g))<<8 | uint32(LinearToSRGB(c.b))
+}
+
+// mixLinear mixes a destination and source channel in linear light with the given artist blend mode
+func (m BlendMode) mixLinear(d, s float32) float32 {
+	switch m {
+	case BlendMultiply:
+		return d * s
+	case BlendScreen:
+		return d + s - d*s
+	case BlendOverlay:
+		if d < 0.5 {
+			return 2 * d * s
+		}
+		return 1 - 2*(1-d)*(1-s)
+	case BlendDarken:
+		if d < s {
+			return d
+		}
+		return s
+	case BlendLighten:
+		if d > s {
+			return d
+		}
+		return s
+	case BlendAdditive:
+		if d+s > 1 {
+			return 1
+		}
+		return d + s
+	case BlendDifference:
+		if d > s {
+			return d - s
+		}
+		return s - d
+	}
+	return s
+}
+
+// CombineLinear is like Combine, but the colors are converted to linear light before they are combined,
+// and back to sRGB afterwards. This avoids the dark fringes and muddy gradients of blending sRGB values directly.
+func (m BlendMode) CombineLinear(dst, src uint32) uint32 {
+	switch m {
+	case BlendSrc:
+		return src
+	case BlendDst:
+		return dst
+	}
+	if m == BlendOver && src>>24 == 0xff {
+		return src
+	}
+	d, s := toLinear(dst), toLinear(src)
+
+	var fa, fb float32
+	if m >= BlendMultiply {
+		// Mix the colors where the destination is opaque, then draw the mixed color on top
+		s.r = (1-d.a)*s.r + d.a*m.mixLinear(d.r, s.r)
+		s.g = (1-d.a)*s.g + d.a*m.mixLinear(d.g, s.g)
+		s.b = (1-d.a)*s.b + d.a*m.mixLinear(d.b, s.b)
+		fa, fb = 1, 1-s.a
+	} else {
+		ia, ib := m.porterDuffFactors(uint32(s.a*255+0.5), uint32(d.a*255+0.5))
+		fa, fb = float32(ia)/255, float32(ib)/255
+	}
+
+	// Porter-Duff compositing with premultiplied colors
+	out := linearColor{a: fa*s.a + fb*d.a}
+	if out.a <= 0 {
+		return 0
+	}
+	out.r = (fa*s.r*s.a + fb*d.r*d.a) / out.a
+	out.g = (fa*s.g*s.a + fb*d.g*d.a) / out.a
+	out.b = (fa*s.b*s.a + fb*d.b*d.a) / out.a
+	return out.colorValue()
+}
+
+// BlendLinear draws the second color value on top of the first one, using the alpha values of both,
+// in linear light. This is the same as BlendOver.CombineLinear.
+func BlendLinear(c1, c2 uint32) uint32 {
+	return BlendOver.CombineLinear(c1, c2)
+}
+
+// addLinear is like Add, but adds the colors in linear light
+func addLinear(c1, c2 uint32) uint32 {
+	return BlendAdditive.CombineLinear(c1|0xff000000, c2)
+}
+
+// combine combines two color values with the given blend mode, in linear light if b.Linear is set
+func (b *Buffer) combine(mode BlendMode, dst, src uint32) uint32 {
+	if b.Linear {
+		return mode.CombineLinear(dst, src)
+	}
+	return mode.Combine(dst, src)
+}
+
+// colorSum is a sum of colors, for calculating averages.
+// The colors are weighted by alpha, so that transparent pixels do not darken the average.
+// If all the pixels are transparent, the average of the unweighted colors is used.
+type colorSum struct {
+	r, g, b, a float32 // Weighted by alpha
+	ur, ug, ub float32 // Not weighted by alpha
+	n          float32
+}
+
+// add adds a color value to the sum, in linear light if linear is true.
+// Use a negative weight to remove a color value that has been added before.
+func (cs *colorSum) add(cv uint32, linear bool, weight float32) {
+	var c linearColor
+	if linear {
+		c = toLinear(cv)
+	} else {
+		c = linearColor{float32((cv >> 16) & 0xff), float32((cv >> 8) & 0xff), float32(cv & 0xff), float32(cv>>24) / 255}
+	}
+	wa := c.a * weight
+	cs.r += c.r * wa
+	cs.g += c.g * wa
+	cs.b += c.b * wa
+	cs.a += wa
+	cs.ur += c.r * weight
+	cs.ug += c.g * weight
+	cs.ub += c.b * weight
+	cs.n += weight
+}
+
+// average returns the average color value
+func (cs *colorSum) average(linear bool) uint32 {
+	if cs.n <= 0.5 {
+		return 0
+	}
+	c := linearColor{cs.ur / cs.n, cs.ug / cs.n, cs.ub / cs.n, 0}
+	if cs.a > 0.001 {
+		c = linearColor{cs.r / cs.a, cs.g / cs.a, cs.b / cs.a, cs.a / cs.n}
+	}
+	if linear {
+		return c.colorValue()
+	}
+	r, g, b := minUint32(uint32(c.r+0.5), 0xff), minUint32(uint32(c.g+0.5), 0xff), minUint32(uint32(c.b+0.5), 0xff)
+	return minUint32(uint32(c.a*255+0.5), 0xff)<<24 | r<<16 | g<<8 | b
+}
+
+// Blur blurs the pixel buffer with a box blur, concurrently.
+// radius is the number of pixels in each direction that are averaged.
+func Blur(cores int, pixels []uint32, pitch int32, radius int32) {
+	NewBufferFromPixels(pixels, pitch).Blur(cores, radius)
+}
+
+// Blur blurs the buffer with a box blur, concurrently, in linear light if b.Linear is set.
+// radius is the number of pixels in each direction that are averaged.
+func (b *Buffer) Blur(cores int, radius int32) {
+	if radius <= 0 || b.Width == 0 || b.Height == 0 {
+		return
+	}
+	// Blur horizontally, then vertically, each time from a copy of the pixels
+	src := b.Clone()
+	splitRows(cores, 0, b.Height, func(minY, maxY int32) {
+		for y := minY; y < maxY; y++ {
+			blurLine(b.Pixels[y*b.Stride:], 1, src.Pixels[y*src.Stride:], 1, b.Width, radius, b.Linear)
+		}
+	})
+	src = b.Clone()
+	// Split the columns instead of the rows between the cores
+	splitRows(cores, 0, b.Width, func(minX, maxX int32) {
+		for x := minX; x < maxX; x++ {
+			blurLine(b.Pixels[x:], b.Stride, src.Pixels[x:], src.Stride, b.Height, radius, b.Linear)
+		}
+	})
+}
+
+// blurLine blurs a row or a column of pixels, with a moving average.
+// dstStep and srcStep are the distances between the pixels, and length is the number of pixels.
+func blurLine(dst []uint32, dstStep int32, src []uint32, srcStep int32, length, radius int32, linear bool) {
+	var sum colorSum
+	for i := int32(0); i < radius && i < length; i++ {
+		sum.add(src[i*srcStep], linear, 1)
+	}
+	for i := int32(0); i < length; i++ {
+		if j := i + radius; j < length {
+			sum.add(src[j*srcStep], linear, 1)
+		}
+		if j := i - radius - 1; j >= 0 {
+			sum.add(src[j*srcStep], linear, -1)
+		}
+		dst[i*dstStep] = sum.average(linear)
+	}
+}
+
+// Scale returns a new buffer with the given size, with a scaled copy of the pixels.
+// When downscaling, each new pixel is the average of the pixels it covers, in linear light if b.Linear is set.
+// When upscaling, the nearest pixel is used.
+func (b *Buffer) Scale(width, height int32) *Buffer {
+	scaled := NewBuffer(width, height)
+	scaled.Linear = b.Linear
+	if b.Width == 0 || b.Height == 0 {
+		return scaled
+	}
+	for y := int32(0); y < height; y++ {
+		// The source rows that are covered by this row
+		minY := y * b.Height / height
+		maxY := Max2((y+1)*b.Height/height, minY+1)
+		row := scaled.Row(y)
+		for x := int32(0); x < width; x++ {
+			minX := x * b.Width / width
+			maxX := Max2((x+1)*b.Width/width, minX+1)
+			if maxX-minX == 1 && maxY-minY == 1 {
+				row[x] = b.Pixels[minY*b.Stride+minX]
+				continue
+			}
+			var sum colorSum
+			for sy := minY; sy < maxY; sy++ {
+				for _, cv := range b.Pixels[sy*b.Stride+minX : sy*b.Stride+maxX] {
+					sum.add(cv, b.Linear, 1)
+				}
+			}
+			row[x] = sum.average(b.Linear)
+		}
+	}
+	return scaled
+}
+
+// StretchContrastLinear is like StretchContrast, but stretches the contrast in linear light
+func StretchContrastLinear(cores int, pixels []uint32, pitch int32, discardRatio float32) {
+	NewBufferFromPixels(pixels, pitch).stretchContrastLinear(cores, discardRatio)
+}
+
+// stretchContrastLinear stretches the contrast of the buffer in linear light,
+// discarding the discardRatio ratio of the most unpopular intensities
+func (b *Buffer) stretchContrastLinear(cores int, discardRatio float32) {
+	// Count the intensities, in linear light
+	popularity := make(map[uint8]int)
+	for y := int32(0); y < b.Height; y++ {
+		for _, cv := range b.Row(y) {
+			c := toLinear(cv)
+			popularity[uint8((c.r+c.g+c.b)/3*255+0.5)]++
+		}
+	}
+	if len(popularity) == 0 {
+		return
+	}
+	sortablePopularity := make(PairList, 0, len(popularity))
+	for k, v := range popularity {
+		sortablePopularity = append(sortablePopularity, Pair{k, v})
+	}
+	sort.Sort(sortablePopularity)
+
+	// Discard the least popular intensities, and find the range of the rest
+	selected := sortablePopularity[int(float32(len(sortablePopularity))*(1.0-discardRatio)):]
+	if len(selected) == 0 {
+		selected = sortablePopularity[len(sortablePopularity)-1:]
+	}
+	low, high := uint8(255), uint8(0)
+	for _, pair := range selected {
+		if pair.Key < low {
+			low = pair.Key
+		}
+		if pair.Key > high {
+			high = pair.Key
+		}
+	}
+	if low >= high {
+		return
+	}
+	// The intensities were rounded when counting, so find the exact range of the selected pixels
+	lowV, highV := float32(1), float32(0)
+	for y := int32(0); y < b.Height; y++ {
+		for _, cv := range b.Row(y) {
+			c := toLinear(cv)
+			v := (c.r + c.g + c.b) / 3
+			if key := uint8(v*255 + 0.5); key < low || key > high {
+				continue
+			}
+			if v < lowV {
+				lowV = v
+			}
+			if v > highV {
+				highV = v
+			}
+		}
+	}
+	scale := 1 / (highV - lowV)
+	b.Map(cores, func(cv uint32) uint32 {
+		c := toLinear(cv)
+		c.r = (c.r - lowV) * scale
+		c.g = (c.g - lowV) * scale
+		c.b = (c.b - lowV) * scale
+		return c.colorValue()
+	})
+}
@@ -0,0 +1,101 @@
+package pixelpusher
+
+import (
+	"testing"
+)
+
+func TestSRGBLinearTables(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		if v := LinearToSRGB(SRGBToLinear(uint8(i))); v != uint8(i) {
+			t.Errorf("converting %d to linear light and back gave %d", i, v)
+		}
+	}
+	if SRGBToLinear(0) != 0 || SRGBToLinear(255) != 1 {
+		t.Error("expected black and white to be the same in linear light")
+	}
+	// Middle gray in sRGB is about 21% in linear light
+	if v := SRGBToLinear(128); v < 0.21 || v > 0.22 {
+		t.Errorf("unexpected linear value for 128: %f", v)
+	}
+}
+
+func TestBlendLinear(t *testing.T) {
+	// Half transparent white on top of black is darker when blended in sRGB than in linear light
+	if cv := Blend(0xff000000, 0x80ffffff); cv != 0xff808080 {
+		t.Errorf("expected ff808080, got %08x", cv)
+	}
+	if cv := BlendLinear(0xff000000, 0x80ffffff); cv != 0xffbcbcbc {
+		t.Errorf("expected ffbcbcbc, got %08x", cv)
+	}
+	// Red and green mixed in linear light does not give a dark yellow
+	if cv := BlendLinear(0xffff0000, 0x8000ff00); Red(cv) < 0xb0 || Green(cv) < 0xb0 {
+		t.Errorf("expected a bright yellow, got %08x", cv)
+	}
+	// The Porter-Duff operators give the same alpha as when blending in sRGB
+	for _, mode := range []BlendMode{BlendOver, BlendSrcIn, BlendDstOut, BlendXor, BlendMultiply} {
+		if a1, a2 := Alpha(mode.Combine(0x80ff0000, 0xc00000ff)), Alpha(mode.CombineLinear(0x80ff0000, 0xc00000ff)); Abs(int32(a1)-int32(a2)) > 1 {
+			t.Errorf("mode %d: the alpha differs, %d and %d", mode, a1, a2)
+		}
+	}
+}
+
+func TestScaleLinear(t *testing.T) {
+	// A checkerboard of black and white, downscaled to a single pixel
+	b := NewBuffer(2, 2)
+	copy(b.Pixels, []uint32{0xff000000, 0xffffffff, 0xffffffff, 0xff000000})
+	if cv := b.Scale(1, 1).Pixels[0]; cv != 0xff808080 {
+		t.Errorf("expected ff808080 when scaling in sRGB, got %08x", cv)
+	}
+	b.Linear = true
+	if cv := b.Scale(1, 1).Pixels[0]; cv != 0xffbcbcbc {
+		t.Errorf("expected ffbcbcbc when scaling in linear light, got %08x", cv)
+	}
+	// Transparent pixels do not darken the result
+	copy(b.Pixels, []uint32{0x00000000, 0xffff0000, 0xffff0000, 0x00000000})
+	if cv := b.Scale(1, 1).Pixels[0]; cv != 0x80ff0000 {
+		t.Errorf("expected 80ff0000, got %08x", cv)
+	}
+	// Upscaling repeats the pixels
+	if up := b.Scale(4, 4); up.GetPixel(3, 0) != 0xffff0000 || up.GetPixel(3, 3) != 0 {
+		t.Error("unexpected pixels after upscaling")
+	}
+}
+
+func TestBlur(t *testing.T) {
+	b := NewBuffer(5, 5)
+	b.FastClear(0xff000000)
+	b.SetPixel(2, 2, 0xffffffff)
+	Blur(2, b.Pixels, 5, 1)
+	// The white pixel is spread out over 3x3 pixels
+	expected := uint32(0xff1c1c1c)
+	for y := int32(1); y < 4; y++ {
+		for x := int32(1); x < 4; x++ {
+			if b.GetPixel(x, y) != expected {
+				t.Errorf("expected %08x at (%d, %d), got %08x", expected, x, y, b.GetPixel(x, y))
+			}
+		}
+	}
+	if b.GetPixel(0, 0) != 0xff000000 || b.GetPixel(4, 2) != 0xff000000 {
+		t.Error("expected the corners to stay black")
+	}
+	// Blurring only a part of the buffer
+	b.FastClear(0xffffffff)
+	sub := b.SubBuffer(b.Rect().Inset(1))
+	sub.SetPixel(1, 1, 0xff000000)
+	sub.Linear = true
+	sub.Blur(4, 1)
+	if b.GetPixel(0, 0) != 0xffffffff || sub.GetPixel(1, 1) == 0xff000000 || sub.GetPixel(1, 1) == 0xffffffff {
+		t.Error("expected only the sub buffer to be blurred")
+	}
+}
+
+func TestStretchContrastLinear(t *testing.T) {
+	pixels := []uint32{0xff404040, 0xff808080, 0xff606060, 0xff404040}
+	StretchContrastLinear(1, pixels, 2, 1)
+	if pixels[0] != 0xff000000 || pixels[1] != 0xffffffff {
+		t.Errorf("expected the darkest pixel to become black and the brightest to become white, got %08x", pixels)
+	}
+	if v := Red(pixels[2]); v < 0x90 || v > 0xc0 {
+		t.Errorf("unexpected value for the middle pixel: %08x", pixels[2])
+	}
+}
@@ -17,78 +17,41 @@ func Sort2(a, b int32) (int32, int32) {
 
 // Sort3 sorts three numbers
 func Sort3(a, b, c int32) (int32, int32, int32) {
-	if a < b && a < c {
-		x, y := Sort2(b, c)
-		return a, x, y
-	}
-	if b < a && b < c {
-		x, y := Sort2(a, c)
-		return b, x, y
-	}
-	x, y := Sort2(a, b)
-	return c, x, y
+	a, b = Sort2(a, b)
+	b, c = Sort2(b, c)
+	a, b = Sort2(a, b)
+	return a, b, c
 }
 
 // MinMax3Byte finds the smallest and largest of three bytes
 func MinMax3Byte(a, b, c uint8) (uint8, uint8) {
-	if a < b && a < c {
-		if b > c {
-			return a, b
-		}
-		return a, c
+	min, max := a, a
+	if b < min {
+		min = b
+	} else if b > max {
+		max = b
 	}
-	if b < a && b < c {
-		if a > c {
-			return b, a
-		}
-		return b, c
+	if c < min {
+		min = c
+	} else if c > max {
+		max = c
 	}
-	if a > b {
-		return c, a
-	}
-	return c, b
+	return min, max
 }
 
 // MinMax3 finds the smallest and largest of three numbers
 func MinMax3(a, b, c int32) (int32, int32) {
-	if a < b && a < c {
-		if b > c {
-			return a, b
-		}
-		return a, c
-	}
-	if b < a && b < c {
-		if a > c {
-			return b, a
-		}
-		return b, c
-	}
-	if a > b {
-		return c, a
-	}
-	return c, b
+	return Min3(a, b, c), Max3(a, b, c)
 }
 
 // Min3 finds the smallest of three numbers
 func Min3(a, b, c int32) int32 {
-	if a < b && a < c {
-		return a
-	}
-	if b < a && b < c {
-		return b
-	}
-	return c
+	return Min2(Min2(a, b), c)
 }
 
 // Max3 finds the largest of three numbers
 func Max3(a, b, c int32) int32 {
-	if c > a && c > b {
-		return c
-	}
-	if b > a && b > c {
-		return b
-	}
-	return a
+	return Max2(Max2(a, b), c)
 }
 
 // MinMax find the smallest and greatest of two given numbers
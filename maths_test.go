@@ -2,6 +2,7 @@ package pixelpusher
 
 import (
 	"fmt"
+	"testing"
 )
 
 func ExampleScale() {
@@ -17,3 +18,33 @@ func ExampleScale() {
 	// 255
 	// 0
 }
+
+func TestMinMax3(t *testing.T) {
+	for _, abc := range [][3]int32{{0, 0, 8}, {8, 0, 0}, {0, 8, 8}, {8, 8, 0}, {3, 1, 2}, {5, 5, 5}, {-1, 4, 2}} {
+		min, max := MinMax3(abc[0], abc[1], abc[2])
+		expectedMin, expectedMax := abc[0], abc[0]
+		for _, x := range abc[1:] {
+			if x < expectedMin {
+				expectedMin = x
+			}
+			if x > expectedMax {
+				expectedMax = x
+			}
+		}
+		if min != expectedMin || max != expectedMax {
+			t.Errorf("MinMax3(%d, %d, %d): expected (%d, %d), got (%d, %d)", abc[0], abc[1], abc[2], expectedMin, expectedMax, min, max)
+		}
+		if bmin, bmax := MinMax3Byte(uint8(abc[0]+1), uint8(abc[1]+1), uint8(abc[2]+1)); int32(bmin) != expectedMin+1 || int32(bmax) != expectedMax+1 {
+			t.Errorf("MinMax3Byte(%d, %d, %d): got (%d, %d)", abc[0]+1, abc[1]+1, abc[2]+1, bmin, bmax)
+		}
+	}
+}
+
+func TestSort3(t *testing.T) {
+	for _, abc := range [][3]int32{{0, 0, 8}, {8, 0, 0}, {0, 8, 0}, {8, 8, 0}, {3, 1, 2}, {2, 3, 1}, {5, 5, 5}, {-1, 4, 2}} {
+		x, y, z := Sort3(abc[0], abc[1], abc[2])
+		if x > y || y > z || x+y+z != abc[0]+abc[1]+abc[2] || x != Min3(abc[0], abc[1], abc[2]) || z != Max3(abc[0], abc[1], abc[2]) {
+			t.Errorf("Sort3(%d, %d, %d): got (%d, %d, %d)", abc[0], abc[1], abc[2], x, y, z)
+		}
+	}
+}
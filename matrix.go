@@ -0,0 +1,320 @@
+package pixelpusher
+
+import (
+	"math"
+)
+
+// Mat3 is a 3x3 matrix, stored row by row, so that the element at row r and column c is at index r*3+c.
+// Vectors are columns that are multiplied from the right.
+type Mat3 [9]float32
+
+// Mat4 is a 4x4 matrix, stored row by row, so that the element at row r and column c is at index r*4+c.
+// Vectors are columns that are multiplied from the right, so m.Mul(n) is a transformation that applies n first,
+// then m. The projections follow the OpenGL conventions, with a right-handed coordinate system where
+// the camera looks along -z, and depth values from -1 at the near plane to 1 at the far plane.
+type Mat4 [16]float32
+
+// IdentityMat3 returns the 3x3 identity matrix
+func IdentityMat3() Mat3 {
+	return Mat3{
+		1, 0, 0,
+		0, 1, 0,
+		0, 0, 1,
+	}
+}
+
+// Mul returns the matrix product m*n
+func (m Mat3) Mul(n Mat3) Mat3 {
+	var p Mat3
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			p[r*3+c] = m[r*3]*n[c] + m[r*3+1]*n[3+c] + m[r*3+2]*n[6+c]
+		}
+	}
+	return p
+}
+
+// MulVec3 returns the vector m*v
+func (m Mat3) MulVec3(v Vec3) Vec3 {
+	return Vec3{
+		m[0]*v.X + m[1]*v.Y + m[2]*v.Z,
+		m[3]*v.X + m[4]*v.Y + m[5]*v.Z,
+		m[6]*v.X + m[7]*v.Y + m[8]*v.Z,
+	}
+}
+
+// Transpose returns m with the rows and columns swapped
+func (m Mat3) Transpose() Mat3 {
+	return Mat3{
+		m[0], m[3], m[6],
+		m[1], m[4], m[7],
+		m[2], m[5], m[8],
+	}
+}
+
+// Determinant returns the determinant of m
+func (m Mat3) Determinant() float32 {
+	return m[0]*(m[4]*m[8]-m[5]*m[7]) - m[1]*(m[3]*m[8]-m[5]*m[6]) + m[2]*(m[3]*m[7]-m[4]*m[6])
+}
+
+// Inverse returns the inverse of m. ok is false if m can not be inverted, because its determinant is 0.
+func (m Mat3) Inverse() (inv Mat3, ok bool) {
+	det := m.Determinant()
+	if det == 0 {
+		return Mat3{}, false
+	}
+	inv = Mat3{
+		m[4]*m[8] - m[5]*m[7], m[2]*m[7] - m[1]*m[8], m[1]*m[5] - m[2]*m[4],
+		m[5]*m[6] - m[3]*m[8], m[0]*m[8] - m[2]*m[6], m[2]*m[3] - m[0]*m[5],
+		m[3]*m[7] - m[4]*m[6], m[1]*m[6] - m[0]*m[7], m[0]*m[4] - m[1]*m[3],
+	}
+	for i := range inv {
+		inv[i] /= det
+	}
+	return inv, true
+}
+
+// Mat4 returns m as the upper left part of a 4x4 matrix, that has no translation
+func (m Mat3) Mat4() Mat4 {
+	return Mat4{
+		m[0], m[1], m[2], 0,
+		m[3], m[4], m[5], 0,
+		m[6], m[7], m[8], 0,
+		0, 0, 0, 1,
+	}
+}
+
+// IdentityMat4 returns the 4x4 identity matrix
+func IdentityMat4() Mat4 {
+	return Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Translation returns a matrix that moves points by (x, y, z)
+func Translation(x, y, z float32) Mat4 {
+	return Mat4{
+		1, 0, 0, x,
+		0, 1, 0, y,
+		0, 0, 1, z,
+		0, 0, 0, 1,
+	}
+}
+
+// Scaling returns a matrix that scales by x, y and z along each axis
+func Scaling(x, y, z float32) Mat4 {
+	return Mat4{
+		x, 0, 0, 0,
+		0, y, 0, 0,
+		0, 0, z, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Rotation returns a matrix that rotates by the given angle, in radians, around the given axis.
+// The rotation is counterclockwise when looking from the tip of the axis towards the origin.
+// If the axis has a length of 0, the identity matrix is returned.
+func Rotation(axis Vec3, angle float32) Mat4 {
+	axis = axis.Normalize()
+	if axis == (Vec3{}) {
+		return IdentityMat4()
+	}
+	s64, c64 := math.Sincos(float64(angle))
+	s, c := float32(s64), float32(c64)
+	t := 1 - c
+	x, y, z := axis.X, axis.Y, axis.Z
+	return Mat4{
+		t*x*x + c, t*x*y - s*z, t*x*z + s*y, 0,
+		t*x*y + s*z, t*y*y + c, t*y*z - s*x, 0,
+		t*x*z - s*y, t*y*z + s*x, t*z*z + c, 0,
+		0, 0, 0, 1,
+	}
+}
+
+// RotationX returns a matrix that rotates by the given angle, in radians, around the x axis
+func RotationX(angle float32) Mat4 {
+	return Rotation(Vec3{1, 0, 0}, angle)
+}
+
+// RotationY returns a matrix that rotates by the given angle, in radians, around the y axis
+func RotationY(angle float32) Mat4 {
+	return Rotation(Vec3{0, 1, 0}, angle)
+}
+
+// RotationZ returns a matrix that rotates by the given angle, in radians, around the z axis
+func RotationZ(angle float32) Mat4 {
+	return Rotation(Vec3{0, 0, 1}, angle)
+}
+
+// LookAt returns a view matrix for a camera at eye that looks towards center, where up is the direction
+// that is up on the screen
+func LookAt(eye, center, up Vec3) Mat4 {
+	f := center.Sub(eye).Normalize()
+	s := f.Cross(up).Normalize()
+	u := s.Cross(f)
+	return Mat4{
+		s.X, s.Y, s.Z, -s.Dot(eye),
+		u.X, u.Y, u.Z, -u.Dot(eye),
+		-f.X, -f.Y, -f.Z, f.Dot(eye),
+		0, 0, 0, 1,
+	}
+}
+
+// Perspective returns a perspective projection matrix, where fovy is the vertical field of view in radians,
+// aspect is the width divided by the height, and near and far are the distances to the clipping planes
+func Perspective(fovy, aspect, near, far float32) Mat4 {
+	f := float32(1 / math.Tan(float64(fovy)/2))
+	return Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, (far + near) / (near - far), 2 * far * near / (near - far),
+		0, 0, -1, 0,
+	}
+}
+
+// Orthographic returns an orthographic projection matrix, for the box with the given sides.
+// near and far are the distances to the clipping planes, along -z.
+func Orthographic(left, right, bottom, top, near, far float32) Mat4 {
+	return Mat4{
+		2 / (right - left), 0, 0, -(right + left) / (right - left),
+		0, 2 / (top - bottom), 0, -(top + bottom) / (top - bottom),
+		0, 0, -2 / (far - near), -(far + near) / (far - near),
+		0, 0, 0, 1,
+	}
+}
+
+// Mul returns the matrix product m*n, which is the transformation n followed by m
+func (m Mat4) Mul(n Mat4) Mat4 {
+	var p Mat4
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			p[r*4+c] = m[r*4]*n[c] + m[r*4+1]*n[4+c] + m[r*4+2]*n[8+c] + m[r*4+3]*n[12+c]
+		}
+	}
+	return p
+}
+
+// MulVec4 returns the vector m*v
+func (m Mat4) MulVec4(v Vec4) Vec4 {
+	return Vec4{
+		m[0]*v.X + m[1]*v.Y + m[2]*v.Z + m[3]*v.W,
+		m[4]*v.X + m[5]*v.Y + m[6]*v.Z + m[7]*v.W,
+		m[8]*v.X + m[9]*v.Y + m[10]*v.Z + m[11]*v.W,
+		m[12]*v.X + m[13]*v.Y + m[14]*v.Z + m[15]*v.W,
+	}
+}
+
+// MulPoint transforms the point p, with w set to 1, and divides the result by w, unless w is 0
+func (m Mat4) MulPoint(p Vec3) Vec3 {
+	v := m.MulVec4(p.Vec4(1))
+	if v.W == 0 || v.W == 1 {
+		return v.Vec3()
+	}
+	return v.PerspectiveDivide()
+}
+
+// MulDirection transforms the direction d, with w set to 0, so that it is not translated
+func (m Mat4) MulDirection(d Vec3) Vec3 {
+	return m.MulVec4(d.Vec4(0)).Vec3()
+}
+
+// Translate returns m followed by a translation by (x, y, z), which is applied first
+func (m Mat4) Translate(x, y, z float32) Mat4 {
+	return m.Mul(Translation(x, y, z))
+}
+
+// Rotate returns m followed by a rotation around the given axis, which is applied first
+func (m Mat4) Rotate(axis Vec3, angle float32) Mat4 {
+	return m.Mul(Rotation(axis, angle))
+}
+
+// Scale returns m followed by a scaling along each axis, which is applied first
+func (m Mat4) Scale(x, y, z float32) Mat4 {
+	return m.Mul(Scaling(x, y, z))
+}
+
+// Transpose returns m with the rows and columns swapped
+func (m Mat4) Transpose() Mat4 {
+	var t Mat4
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			t[c*4+r] = m[r*4+c]
+		}
+	}
+	return t
+}
+
+// Mat3 returns the upper left 3x3 part of m, which has the rotation and scaling, but not the translation
+func (m Mat4) Mat3() Mat3 {
+	return Mat3{
+		m[0], m[1], m[2],
+		m[4], m[5], m[6],
+		m[8], m[9], m[10],
+	}
+}
+
+// inverseParts returns the determinants of the 2x2 parts of the upper two rows, and of the lower two rows,
+// that the determinant and the inverse of m are made of
+func (m Mat4) inverseParts() (s, c [6]float64) {
+	a := func(r, c int) float64 { return float64(m[r*4+c]) }
+	s = [6]float64{
+		a(0, 0)*a(1, 1) - a(1, 0)*a(0, 1),
+		a(0, 0)*a(1, 2) - a(1, 0)*a(0, 2),
+		a(0, 0)*a(1, 3) - a(1, 0)*a(0, 3),
+		a(0, 1)*a(1, 2) - a(1, 1)*a(0, 2),
+		a(0, 1)*a(1, 3) - a(1, 1)*a(0, 3),
+		a(0, 2)*a(1, 3) - a(1, 2)*a(0, 3),
+	}
+	c = [6]float64{
+		a(2, 0)*a(3, 1) - a(3, 0)*a(2, 1),
+		a(2, 0)*a(3, 2) - a(3, 0)*a(2, 2),
+		a(2, 0)*a(3, 3) - a(3, 0)*a(2, 3),
+		a(2, 1)*a(3, 2) - a(3, 1)*a(2, 2),
+		a(2, 1)*a(3, 3) - a(3, 1)*a(2, 3),
+		a(2, 2)*a(3, 3) - a(3, 2)*a(2, 3),
+	}
+	return s, c
+}
+
+// Determinant returns the determinant of m
+func (m Mat4) Determinant() float32 {
+	s, c := m.inverseParts()
+	return float32(s[0]*c[5] - s[1]*c[4] + s[2]*c[3] + s[3]*c[2] - s[4]*c[1] + s[5]*c[0])
+}
+
+// Inverse returns the inverse of m. ok is false if m can not be inverted, because its determinant is 0.
+func (m Mat4) Inverse() (inv Mat4, ok bool) {
+	s, c := m.inverseParts()
+	det := s[0]*c[5] - s[1]*c[4] + s[2]*c[3] + s[3]*c[2] - s[4]*c[1] + s[5]*c[0]
+	if det == 0 {
+		return Mat4{}, false
+	}
+	a := func(r, c int) float64 { return float64(m[r*4+c]) }
+	for i, v := range [16]float64{
+		a(1, 1)*c[5] - a(1, 2)*c[4] + a(1, 3)*c[3],
+		-a(0, 1)*c[5] + a(0, 2)*c[4] - a(0, 3)*c[3],
+		a(3, 1)*s[5] - a(3, 2)*s[4] + a(3, 3)*s[3],
+		-a(2, 1)*s[5] + a(2, 2)*s[4] - a(2, 3)*s[3],
+
+		-a(1, 0)*c[5] + a(1, 2)*c[2] - a(1, 3)*c[1],
+		a(0, 0)*c[5] - a(0, 2)*c[2] + a(0, 3)*c[1],
+		-a(3, 0)*s[5] + a(3, 2)*s[2] - a(3, 3)*s[1],
+		a(2, 0)*s[5] - a(2, 2)*s[2] + a(2, 3)*s[1],
+
+		a(1, 0)*c[4] - a(1, 1)*c[2] + a(1, 3)*c[0],
+		-a(0, 0)*c[4] + a(0, 1)*c[2] - a(0, 3)*c[0],
+		a(3, 0)*s[4] - a(3, 1)*s[2] + a(3, 3)*s[0],
+		-a(2, 0)*s[4] + a(2, 1)*s[2] - a(2, 3)*s[0],
+
+		-a(1, 0)*c[3] + a(1, 1)*c[1] - a(1, 2)*c[0],
+		a(0, 0)*c[3] - a(0, 1)*c[1] + a(0, 2)*c[0],
+		-a(3, 0)*s[3] + a(3, 1)*s[1] - a(3, 2)*s[0],
+		a(2, 0)*s[3] - a(2, 1)*s[1] + a(2, 2)*s[0],
+	} {
+		inv[i] = float32(v / det)
+	}
+	return inv, true
+}
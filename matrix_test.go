@@ -0,0 +1,138 @@
+package pixelpusher
+
+import (
+	"math"
+	"testing"
+)
+
+// closeToMat4 checks if each element of a and b differ by less than 1e-5
+func closeToMat4(a, b Mat4) bool {
+	for i := range a {
+		if !closeTo(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMat3(t *testing.T) {
+	m := Mat3{
+		2, 0, 1,
+		1, 3, 0,
+		0, 1, 4,
+	}
+	if d := m.Determinant(); d != 25 {
+		t.Error(d, "!=", 25)
+	}
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatal("expected the matrix to be invertible")
+	}
+	p := m.Mul(inv)
+	for i, e := range IdentityMat3() {
+		if !closeTo(p[i], e) {
+			t.Fatal(p, "!=", IdentityMat3())
+		}
+	}
+	if v := m.MulVec3(Vec3{1, 1, 1}); v != (Vec3{3, 4, 5}) {
+		t.Error(v, "!=", Vec3{3, 4, 5})
+	}
+	if tr := m.Transpose(); tr[1] != 1 || tr[3] != 0 || tr.Transpose() != m {
+		t.Error(tr, "is not the transpose of", m)
+	}
+	if _, ok := (Mat3{1, 2, 3, 2, 4, 6, 0, 0, 1}).Inverse(); ok {
+		t.Error("expected a singular matrix to not be invertible")
+	}
+	if m.Mat4().Mat3() != m {
+		t.Error(m.Mat4(), "does not contain", m)
+	}
+}
+
+func TestMat4(t *testing.T) {
+	m := Translation(1, 2, 3).Rotate(Vec3{1, 1, 0}, 0.7).Scale(2, 3, 4)
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatal("expected the matrix to be invertible")
+	}
+	if p := m.Mul(inv); !closeToMat4(p, IdentityMat4()) {
+		t.Error(p, "!=", IdentityMat4())
+	}
+	if d := m.Determinant(); !closeTo(d, 24) {
+		t.Error(d, "!=", 24)
+	}
+	if _, ok := Scaling(1, 0, 1).Inverse(); ok {
+		t.Error("expected a singular matrix to not be invertible")
+	}
+	if tr := m.Transpose(); tr[12] != m[3] || tr.Transpose() != m {
+		t.Error(tr, "is not the transpose of", m)
+	}
+
+	// Translations move points, but not directions
+	if p := Translation(1, 2, 3).MulPoint(Vec3{1, 1, 1}); p != (Vec3{2, 3, 4}) {
+		t.Error(p, "!=", Vec3{2, 3, 4})
+	}
+	if d := Translation(1, 2, 3).MulDirection(Vec3{1, 1, 1}); d != (Vec3{1, 1, 1}) {
+		t.Error(d, "!=", Vec3{1, 1, 1})
+	}
+	if p := Scaling(2, 3, 4).MulPoint(Vec3{1, 1, 1}); p != (Vec3{2, 3, 4}) {
+		t.Error(p, "!=", Vec3{2, 3, 4})
+	}
+
+	// Rotations are counterclockwise when looking towards the origin from the tip of the axis
+	if p := RotationZ(math.Pi / 2).MulPoint(Vec3{1, 0, 0}); !closeToVec3(p, Vec3{0, 1, 0}) {
+		t.Error(p, "!=", Vec3{0, 1, 0})
+	}
+	if p := RotationX(math.Pi / 2).MulPoint(Vec3{0, 1, 0}); !closeToVec3(p, Vec3{0, 0, 1}) {
+		t.Error(p, "!=", Vec3{0, 0, 1})
+	}
+	if p := RotationY(math.Pi / 2).MulPoint(Vec3{0, 0, 1}); !closeToVec3(p, Vec3{1, 0, 0}) {
+		t.Error(p, "!=", Vec3{1, 0, 0})
+	}
+	if r := Rotation(Vec3{}, 1); r != IdentityMat4() {
+		t.Error(r, "!=", IdentityMat4())
+	}
+	// The translation is applied last
+	if p := IdentityMat4().Translate(1, 0, 0).Rotate(Vec3{0, 0, 1}, math.Pi).MulPoint(Vec3{1, 0, 0}); !closeToVec3(p, Vec3{0, 0, 0}) {
+		t.Error(p, "!=", Vec3{0, 0, 0})
+	}
+}
+
+func TestLookAt(t *testing.T) {
+	// The camera is moved to the origin, looking along -z
+	view := LookAt(Vec3{0, 0, 5}, Vec3{}, Vec3{0, 1, 0})
+	if p := view.MulPoint(Vec3{}); !closeToVec3(p, Vec3{0, 0, -5}) {
+		t.Error(p, "!=", Vec3{0, 0, -5})
+	}
+	view = LookAt(Vec3{3, 0, 0}, Vec3{}, Vec3{0, 1, 0})
+	if p := view.MulPoint(Vec3{0, 1, 0}); !closeToVec3(p, Vec3{0, 1, -3}) {
+		t.Error(p, "!=", Vec3{0, 1, -3})
+	}
+	if p := view.MulPoint(Vec3{0, 0, -1}); !closeToVec3(p, Vec3{1, 0, -3}) {
+		t.Error(p, "!=", Vec3{1, 0, -3})
+	}
+}
+
+func TestProjections(t *testing.T) {
+	// The near and far planes end up at -1 and 1, and the field of view fills the screen
+	proj := Perspective(math.Pi/2, 2, 1, 10)
+	if p := proj.MulPoint(Vec3{0, 0, -1}); !closeTo(p.Z, -1) {
+		t.Error(p.Z, "!=", -1)
+	}
+	if p := proj.MulPoint(Vec3{0, 0, -10}); !closeTo(p.Z, 1) {
+		t.Error(p.Z, "!=", 1)
+	}
+	if p := proj.MulPoint(Vec3{4, 2, -2}); !closeTo(p.X, 1) || !closeTo(p.Y, 1) {
+		t.Error(p, "is not in the upper right corner")
+	}
+	if v := proj.MulVec4(Vec4{0, 0, -3, 1}); v.W != 3 {
+		t.Error(v.W, "!=", 3)
+	}
+
+	ortho := Orthographic(-2, 2, -1, 1, 1, 3)
+	if p := ortho.MulPoint(Vec3{2, -1, -1}); !closeToVec3(p, Vec3{1, -1, -1}) {
+		t.Error(p, "!=", Vec3{1, -1, -1})
+	}
+	if p := ortho.MulPoint(Vec3{0, 0, -3}); !closeToVec3(p, Vec3{0, 0, 1}) {
+		t.Error(p, "!=", Vec3{0, 0, 1})
+	}
+}
@@ -0,0 +1,180 @@
+package pixelpusher
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Mesh is a 3D shape made of triangles. Each triangle has the indices of three vertices.
+// Normals, UVs and Colors are optional, but if they are present, there is one for each vertex.
+// Triangles where the vertices are counterclockwise, as seen from the front, are facing the viewer.
+type Mesh struct {
+	Positions []Vec3
+	Normals   []Vec3
+	UVs       []Vec2
+	Colors    []color.RGBA
+	Triangles [][3]int
+	Groups    []MeshGroup // Optional ranges of triangles that belong together, like the groups in an OBJ file
+}
+
+// MeshGroup is a range of triangles in a mesh that belong together, with a material
+type MeshGroup struct {
+	Object    string    // The name of the object that the triangles are a part of
+	Name      string    // The name of the group
+	Material  *Material // The material of the triangles, or nil
+	Smoothing int       // The smoothing group of the triangles, where 0 means that they are not smoothed together
+	First     int       // The index of the first triangle
+	Count     int       // The number of triangles
+}
+
+// NewMesh creates a new, empty mesh
+func NewMesh() *Mesh {
+	return &Mesh{}
+}
+
+// NewCube creates a cube from -1 to 1 along each axis, with a normal and texture coordinates for each side
+func NewCube() *Mesh {
+	m := NewMesh()
+	for _, side := range [6][3]Vec3{
+		// The normal, and two directions along the side where the cross product is the normal
+		{{1, 0, 0}, {0, 0, -1}, {0, 1, 0}},
+		{{-1, 0, 0}, {0, 0, 1}, {0, 1, 0}},
+		{{0, 1, 0}, {1, 0, 0}, {0, 0, -1}},
+		{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}},
+		{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}},
+		{{0, 0, -1}, {-1, 0, 0}, {0, 1, 0}},
+	} {
+		n, u, v := side[0], side[1], side[2]
+		first := len(m.Positions)
+		for _, uv := range [4]Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+			m.AddVertex(n.Add(u.Mul(uv.X*2-1)).Add(v.Mul(uv.Y*2-1)), n, uv)
+		}
+		m.AddTriangle(first, first+1, first+2)
+		m.AddTriangle(first, first+2, first+3)
+	}
+	return m
+}
+
+// AddVertex adds a vertex with a position, a normal and a texture coordinate, and returns its index
+func (m *Mesh) AddVertex(position, normal Vec3, uv Vec2) int {
+	m.Positions = append(m.Positions, position)
+	m.Normals = append(m.Normals, normal)
+	m.UVs = append(m.UVs, uv)
+	return len(m.Positions) - 1
+}
+
+// AddTriangle adds a triangle with the vertices at the given indices
+func (m *Mesh) AddTriangle(a, b, c int) {
+	m.Triangles = append(m.Triangles, [3]int{a, b, c})
+}
+
+// Vertex returns the position and the color of the vertex at the given index, for drawing with functions like
+// ShadedTriangle. Vertices are white if the mesh has no colors.
+func (m *Mesh) Vertex(i int) *Vertex {
+	p, c := m.Positions[i], color.RGBA{0xff, 0xff, 0xff, 0xff}
+	if len(m.Colors) == len(m.Positions) {
+		c = m.Colors[i]
+	}
+	return NewVertex(p.X, p.Y, p.Z, c.R, c.G, c.B, c.A)
+}
+
+// checkTriangles checks that the triangles only refer to vertices that exist
+func (m *Mesh) checkTriangles() error {
+	for _, t := range m.Triangles {
+		for _, i := range t {
+			if i < 0 || i >= len(m.Positions) {
+				return fmt.Errorf("vertex index %d is out of range, there are %d", i, len(m.Positions))
+			}
+		}
+	}
+	return nil
+}
+
+// Bounds returns the smallest and the largest x, y and z of the positions.
+// Two zero vectors are returned if there are no positions.
+func (m *Mesh) Bounds() (lo, hi Vec3) {
+	if len(m.Positions) == 0 {
+		return Vec3{}, Vec3{}
+	}
+	lo, hi = m.Positions[0], m.Positions[0]
+	for _, p := range m.Positions[1:] {
+		lo = Vec3{min(lo.X, p.X), min(lo.Y, p.Y), min(lo.Z, p.Z)}
+		hi = Vec3{max(hi.X, p.X), max(hi.Y, p.Y), max(hi.Z, p.Z)}
+	}
+	return lo, hi
+}
+
+// Transform transforms the positions and normals of the mesh with the given matrix
+func (m *Mesh) Transform(matrix Mat4) {
+	for i, p := range m.Positions {
+		m.Positions[i] = matrix.MulPoint(p)
+	}
+	normalMatrix := normalMatrix(matrix)
+	for i, n := range m.Normals {
+		m.Normals[i] = normalMatrix.MulVec3(n).Normalize()
+	}
+}
+
+// FitCube moves and scales the mesh so that it is centered at the origin and fits within the cube from -1 to 1
+func (m *Mesh) FitCube() {
+	lo, hi := m.Bounds()
+	size := hi.Sub(lo)
+	largest := max(size.X, size.Y, size.Z)
+	if largest == 0 {
+		return
+	}
+	center := lo.Add(hi).Mul(0.5)
+	s := 2 / largest
+	m.Transform(Scaling(s, s, s).Translate(-center.X, -center.Y, -center.Z))
+}
+
+// faceNormal returns the normal of the triangle with the given corners, which points towards the front
+func faceNormal(a, b, c Vec3) Vec3 {
+	return b.Sub(a).Cross(c.Sub(a)).Normalize()
+}
+
+// SmoothNormals sets the normal of each vertex to the average of the normals of the triangles it is a corner of,
+// weighted by the angle of each corner. Vertices that are not shared between triangles get the normal of their
+// triangle, so meshes with sharp edges should have separate vertices on each side of the edges.
+func (m *Mesh) SmoothNormals() {
+	m.Normals = m.vertexNormals(nil)
+}
+
+// vertexNormals returns the angle weighted average of the normals of the triangles around each vertex.
+// If shared is not nil, the vertices with the same index in shared get the average of the triangles around
+// all of them, as if they were one vertex.
+func (m *Mesh) vertexNormals(shared []int) []Vec3 {
+	key := func(i int) int {
+		if shared != nil {
+			return shared[i]
+		}
+		return i
+	}
+	sums := make([]Vec3, len(m.Positions))
+	for _, t := range m.Triangles {
+		a, b, c := m.Positions[t[0]], m.Positions[t[1]], m.Positions[t[2]]
+		n := faceNormal(a, b, c)
+		for i, p := range [3]Vec3{a, b, c} {
+			// The angle of the corner
+			e1, e2 := [3]Vec3{b, c, a}[i].Sub(p).Normalize(), [3]Vec3{c, a, b}[i].Sub(p).Normalize()
+			angle := float32(math.Acos(float64(max(-1, min(1, e1.Dot(e2))))))
+			sums[key(t[i])] = sums[key(t[i])].Add(n.Mul(angle))
+		}
+	}
+	normals := make([]Vec3, len(m.Positions))
+	for i := range normals {
+		normals[i] = sums[key(i)].Normalize()
+	}
+	return normals
+}
+
+// normalMatrix returns the matrix that transforms normals, which is the inverse transpose of the upper left
+// part of the given matrix, so that normals stay perpendicular to the surface when the scaling is not uniform
+func normalMatrix(matrix Mat4) Mat3 {
+	inv, ok := matrix.Mat3().Inverse()
+	if !ok {
+		return matrix.Mat3()
+	}
+	return inv.Transpose()
+}
@@ -0,0 +1,116 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestMesh(t *testing.T) {
+	m := NewCube()
+	if len(m.Positions) != 24 || len(m.Triangles) != 12 {
+		t.Errorf("expected 24 vertices and 12 triangles, got %d and %d", len(m.Positions), len(m.Triangles))
+	}
+	// The triangles are counterclockwise as seen from the outside
+	for _, tri := range m.Triangles {
+		a, b, c := m.Positions[tri[0]], m.Positions[tri[1]], m.Positions[tri[2]]
+		if n := faceNormal(a, b, c); n != m.Normals[tri[0]] {
+			t.Error(n, "!=", m.Normals[tri[0]])
+		}
+	}
+
+	m.Transform(Translation(1, 2, 3).Scale(2, 1, 1))
+	if lo, hi := m.Bounds(); lo != (Vec3{-1, 1, 2}) || hi != (Vec3{3, 3, 4}) {
+		t.Error(lo, hi, "!=", Vec3{-1, 1, 2}, Vec3{3, 3, 4})
+	}
+	m.FitCube()
+	if lo, hi := m.Bounds(); lo != (Vec3{-1, -0.5, -0.5}) || hi != (Vec3{1, 0.5, 0.5}) {
+		t.Error(lo, hi, "!=", Vec3{-1, -0.5, -0.5}, Vec3{1, 0.5, 0.5})
+	}
+
+	// Shared vertices get the average of the normals around them
+	m = NewMesh()
+	m.Positions = []Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	m.AddTriangle(0, 1, 2)
+	m.AddTriangle(0, 1, 3)
+	m.SmoothNormals()
+	if n := m.Normals[0]; !closeToVec3(n, Vec3{0, -1, 1}.Normalize()) {
+		t.Error(n, "!=", Vec3{0, -1, 1}.Normalize())
+	}
+	if n := m.Normals[2]; n != (Vec3{0, 0, 1}) {
+		t.Error(n, "!=", Vec3{0, 0, 1})
+	}
+}
+
+func TestDrawMesh(t *testing.T) {
+	b := NewBuffer(40, 40)
+	b.Depth = NewDepthBuffer(40, 40)
+	opts := NewMeshOptions()
+	opts.View = LookAt(Vec3{0, 0, 5}, Vec3{}, Vec3{0, 1, 0})
+	opts.Projection = Orthographic(-2, 2, -2, 2, 1, 10)
+	opts.Color = color.RGBA{0xff, 0, 0, 0xff}
+
+	// The front of the cube faces the light, and covers the middle half of the buffer
+	b.DrawMesh(4, NewCube(), opts)
+	if cv := b.GetPixel(20, 20); cv != 0xffff0000 {
+		t.Errorf("expected red, got %08x", cv)
+	}
+	if n := count(b, 0xffff0000); n != 20*20 {
+		t.Errorf("expected 400 pixels, got %d", n)
+	}
+	if z := b.Depth.At(20, 20); !closeTo(z, 1.0/3) {
+		t.Error(z, "!=", 1.0/3)
+	}
+
+	// Only the back of the cube is drawn when the front is culled, and it only has the ambient light
+	b.FastClear(0)
+	b.Depth.Clear(1)
+	opts.Cull = CullFront
+	b.DrawMesh(1, NewCube(), opts)
+	if cv := b.GetPixel(20, 20); cv != 0xff330000 {
+		t.Errorf("expected dark red, got %08x", cv)
+	}
+
+	// A nearer cube hides the parts of the first cube that are behind it, regardless of the order
+	b.Depth.Clear(1)
+	opts.Cull = CullBack
+	opts.Model = Translation(1, 1, 1).Scale(0.5, 0.5, 0.5)
+	opts.Color = color.RGBA{0, 0xff, 0, 0xff}
+	b.DrawMesh(1, NewCube(), opts)
+	opts.Model = IdentityMat4()
+	opts.Color = color.RGBA{0, 0, 0xff, 0xff}
+	b.DrawMesh(1, NewCube(), opts)
+	if b.GetPixel(29, 10) != 0xff00ff00 || b.GetPixel(15, 25) != 0xff0000ff {
+		t.Error("expected the small cube to be in front of the large cube")
+	}
+
+	// Triangles that cross the near plane are clipped, and triangles behind the camera are not drawn
+	b.FastClear(0)
+	b.Depth.Clear(1)
+	opts.View = LookAt(Vec3{0, 0, 0.5}, Vec3{}, Vec3{0, 1, 0})
+	opts.Projection = Perspective(1.5, 1, 1, 10)
+	b.DrawMesh(1, NewCube(), opts)
+	if n := count(b, 0); n != 40*40 {
+		t.Errorf("expected nothing in front of the near plane, got %d pixels", 40*40-n)
+	}
+	opts.Cull = CullNone
+	b.DrawMesh(1, NewCube(), opts)
+	if b.GetPixel(20, 20) == 0 || b.GetPixel(0, 0) == 0 {
+		t.Error("expected the back and the sides of the cube to be drawn")
+	}
+}
+
+func TestClipNear(t *testing.T) {
+	v := func(z float32) clipVertex { return clipVertex{pos: Vec4{0, 0, z, 1}} }
+	if p := clipNear([3]clipVertex{v(0), v(0.5), v(-0.5)}); len(p) != 3 {
+		t.Errorf("expected 3 corners, got %d", len(p))
+	}
+	if p := clipNear([3]clipVertex{v(0), v(0.5), v(-2)}); len(p) != 4 || p[2].pos.Z != -1 || p[3].pos.Z != -1 {
+		t.Errorf("expected 4 corners, with two on the near plane, got %v", p)
+	}
+	if p := clipNear([3]clipVertex{v(-2), v(-3), v(0)}); len(p) != 3 {
+		t.Errorf("expected 3 corners, got %d", len(p))
+	}
+	if p := clipNear([3]clipVertex{v(-2), v(-3), v(-4)}); len(p) != 0 {
+		t.Errorf("expected no corners, got %d", len(p))
+	}
+}
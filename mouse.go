@@ -0,0 +1,101 @@
+package pixelpusher
+
+// MouseState is the state of the mouse, in worldspace coordinates
+type MouseState struct {
+	X, Y    int32  // Position in worldspace pixels, clamped to the canvas
+	Inside  bool   // True if the mouse pointer is within the canvas, and not on the letterbox borders
+	Buttons uint32 // Bitmask of pressed buttons, where bit (button-1) is set for pressed buttons
+	WheelX  int32  // Horizontal scrolling since the previous frame
+	WheelY  int32  // Vertical scrolling since the previous frame, positive away from the user
+	Visible bool   // True if the mouse cursor is shown
+}
+
+// Pressed checks if the given mouse button is held down
+func (m *MouseState) Pressed(button MouseButton) bool {
+	return m.Buttons&(1<<(button-1)) != 0
+}
+
+// letterbox returns the largest rectangle with the aspect ratio of width x height
+// that fits within screenWidth x screenHeight, centered.
+func letterbox(screenWidth, screenHeight, width, height int32) (int32, int32, int32, int32) {
+	if width <= 0 || height <= 0 {
+		return 0, 0, screenWidth, screenHeight
+	}
+	w, h := screenWidth, screenWidth*height/width
+	if h > screenHeight {
+		w, h = screenHeight*width/height, screenHeight
+	}
+	return (screenWidth - w) / 2, (screenHeight - h) / 2, w, h
+}
+
+// ScreenSize returns the size of the window, in screenspace pixels.
+// This is the size reported by the backend while Run is running, or Width*PixelScale x Height*PixelScale.
+func (c *Canvas) ScreenSize() (int32, int32) {
+	if c.screenWidth > 0 && c.screenHeight > 0 {
+		return c.screenWidth, c.screenHeight
+	}
+	return int32(c.Width * c.PixelScale), int32(c.Height * c.PixelScale)
+}
+
+// Viewport returns the area of the window that the canvas is drawn to, in screenspace pixels.
+// In fullscreen mode, the canvas keeps its aspect ratio and is letterboxed.
+func (c *Canvas) Viewport() (x, y, w, h int32) {
+	screenWidth, screenHeight := c.ScreenSize()
+	return letterbox(screenWidth, screenHeight, int32(c.Width), int32(c.Height))
+}
+
+// ScreenToWorld converts a position in screenspace pixels to worldspace pixels.
+// The returned position is clamped to the canvas. inside is false if the position is outside the canvas.
+func (c *Canvas) ScreenToWorld(sx, sy int32) (x, y int32, inside bool) {
+	vx, vy, vw, vh := c.Viewport()
+	if vw <= 0 || vh <= 0 {
+		return 0, 0, false
+	}
+	// Use the floor of the division, also for negative numbers
+	x = (sx - vx) * int32(c.Width)
+	y = (sy - vy) * int32(c.Height)
+	if x < 0 {
+		x -= vw - 1
+	}
+	if y < 0 {
+		y -= vh - 1
+	}
+	x /= vw
+	y /= vh
+	inside = x >= 0 && x < int32(c.Width) && y >= 0 && y < int32(c.Height)
+	return Clamp(x, 0, int32(c.Width)), Clamp(y, 0, int32(c.Height)), inside
+}
+
+// WorldToScreen converts a position in worldspace pixels to the upper left corner of that pixel, in screenspace pixels
+func (c *Canvas) WorldToScreen(x, y int32) (int32, int32) {
+	vx, vy, vw, vh := c.Viewport()
+	return vx + x*vw/int32(c.Width), vy + y*vh/int32(c.Height)
+}
+
+// Mouse returns the current state of the mouse, in worldspace coordinates
+func (c *Canvas) Mouse() MouseState {
+	return c.mouse
+}
+
+// updateMouse updates the mouse state, and fills in the worldspace coordinates of mouse events
+func (c *Canvas) updateMouse(event Event) {
+	switch e := event.(type) {
+	case *MouseMotionEvent:
+		e.WorldX, e.WorldY, c.mouse.Inside = c.ScreenToWorld(e.X, e.Y)
+		c.mouse.X, c.mouse.Y = e.WorldX, e.WorldY
+		c.mouse.Buttons = e.Buttons
+	case *MouseButtonEvent:
+		e.WorldX, e.WorldY, c.mouse.Inside = c.ScreenToWorld(e.X, e.Y)
+		c.mouse.X, c.mouse.Y = e.WorldX, e.WorldY
+		if e.Down {
+			c.mouse.Buttons |= 1 << (e.Button - 1)
+		} else {
+			c.mouse.Buttons &^= 1 << (e.Button - 1)
+		}
+	case *MouseWheelEvent:
+		c.mouse.WheelX += e.X
+		c.mouse.WheelY += e.Y
+	case *ResizeEvent:
+		c.screenWidth, c.screenHeight = e.Width, e.Height
+	}
+}
@@ -0,0 +1,66 @@
+package pixelpusher
+
+import (
+	"testing"
+)
+
+func TestScreenToWorld(t *testing.T) {
+	c := New("Mouse")
+	// Windowed, with a pixel scale of 4
+	if x, y, inside := c.ScreenToWorld(7, 401); x != 1 || y != 100 || !inside {
+		t.Errorf("expected (1, 100), got (%d, %d), inside: %v", x, y, inside)
+	}
+	// Fullscreen 1920x1080, letterboxed to 1728x1080 at (96, 0)
+	c.screenWidth, c.screenHeight = 1920, 1080
+	if x, y, w, h := c.Viewport(); x != 96 || y != 0 || w != 1728 || h != 1080 {
+		t.Errorf("unexpected viewport: (%d, %d, %d, %d)", x, y, w, h)
+	}
+	if x, y, inside := c.ScreenToWorld(96, 0); x != 0 || y != 0 || !inside {
+		t.Errorf("expected (0, 0), got (%d, %d), inside: %v", x, y, inside)
+	}
+	if x, y, inside := c.ScreenToWorld(1823, 1079); x != 319 || y != 199 || !inside {
+		t.Errorf("expected (319, 199), got (%d, %d), inside: %v", x, y, inside)
+	}
+	if x, y, inside := c.ScreenToWorld(95, 540); x != 0 || y != 100 || inside {
+		t.Errorf("expected (0, 100) outside, got (%d, %d), inside: %v", x, y, inside)
+	}
+	if x, y := c.WorldToScreen(160, 100); x != 960 || y != 540 {
+		t.Errorf("expected (960, 540), got (%d, %d)", x, y)
+	}
+}
+
+func TestMouseEvents(t *testing.T) {
+	c := New("Mouse")
+	headless := NewHeadlessBackend(4)
+	headless.Send(0, &MouseMotionEvent{X: 40, Y: 80})
+	headless.Send(1, &MouseButtonEvent{X: 44, Y: 84, Button: MouseLeft, Down: true}, &MouseWheelEvent{Y: 1}, &MouseWheelEvent{Y: 2})
+	headless.Send(2, &MouseButtonEvent{X: 44, Y: 84, Button: MouseLeft})
+	c.Backend = headless
+	var states []MouseState
+	onDraw := func(c *Canvas) error {
+		states = append(states, c.Mouse())
+		return nil
+	}
+	var worldX, worldY int32
+	onEvent := func(event Event) error {
+		if e, ok := event.(*MouseButtonEvent); ok {
+			worldX, worldY = e.WorldX, e.WorldY
+		}
+		return nil
+	}
+	if err := c.RunEvents(onDraw, onEvent, nil); err != ErrQuit {
+		t.Fatal("expected ErrQuit, got:", err)
+	}
+	if worldX != 11 || worldY != 21 {
+		t.Errorf("expected the event to be at (11, 21), got (%d, %d)", worldX, worldY)
+	}
+	if states[1].X != 10 || states[1].Y != 20 || states[1].Pressed(MouseLeft) || !states[1].Visible {
+		t.Errorf("unexpected mouse state: %+v", states[1])
+	}
+	if states[2].X != 11 || states[2].Y != 21 || !states[2].Pressed(MouseLeft) || states[2].WheelY != 3 {
+		t.Errorf("unexpected mouse state: %+v", states[2])
+	}
+	if states[3].Pressed(MouseLeft) || states[3].WheelY != 0 {
+		t.Errorf("unexpected mouse state: %+v", states[3])
+	}
+}
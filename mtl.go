@@ -0,0 +1,203 @@
+package pixelpusher
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Material is a material from a Wavefront MTL file, which describes how the surface of a mesh looks
+type Material struct {
+	Name        string
+	Ambient     color.RGBA // Ka
+	Diffuse     color.RGBA // Kd
+	Specular    color.RGBA // Ks
+	Emissive    color.RGBA // Ke
+	Shininess   float32    // Ns, the specular exponent
+	Opacity     float32    // d, or 1 - Tr, where 1 is opaque
+	AmbientMap  string     // map_Ka, the path to a texture, as written in the file
+	DiffuseMap  string     // map_Kd
+	SpecularMap string     // map_Ks
+	BumpMap     string     // map_Bump or bump
+}
+
+// maxLineLength is the length of the longest line that can be read from a text file with a mesh or materials,
+// like an OBJ file with a face that has many corners
+const maxLineLength = 64 * 1024 * 1024
+
+// newLineScanner returns a scanner for the lines of a text file with a mesh or materials
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+	return scanner
+}
+
+// objFields splits a line from an OBJ or MTL file into fields, without the comment that starts with #
+func objFields(line string) []string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.Fields(line)
+}
+
+// parseFloats parses the given fields as float32 values
+func parseFloats(fields []string) ([]float32, error) {
+	values := make([]float32, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		values[i] = float32(v)
+	}
+	return values, nil
+}
+
+// parseMTLColor parses an MTL color with red, green and blue from 0 to 1. If only red is given,
+// it is used for all three.
+func parseMTLColor(fields []string) (color.RGBA, error) {
+	if len(fields) > 0 && (fields[0] == "spectral" || fields[0] == "xyz") {
+		return color.RGBA{}, fmt.Errorf("unsupported color type %q", fields[0])
+	}
+	if len(fields) != 1 && len(fields) != 3 {
+		return color.RGBA{}, fmt.Errorf("expected 1 or 3 numbers, got %d", len(fields))
+	}
+	values, err := parseFloats(fields)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	if len(values) == 1 {
+		values = []float32{values[0], values[0], values[0]}
+	}
+	channel := func(v float32) uint8 {
+		return uint8(clampUnit(v)*255 + 0.5)
+	}
+	return color.RGBA{channel(values[0]), channel(values[1]), channel(values[2]), 0xff}, nil
+}
+
+// textureOptions are the options that can come before the path of a texture map, and how many values they have.
+// -o, -s and -t have from 1 to 3 values.
+var textureOptions = map[string]int{
+	"-blendu": 1, "-blendv": 1, "-boost": 1, "-mm": 2, "-o": 3, "-s": 3, "-t": 3,
+	"-texres": 1, "-clamp": 1, "-bm": 1, "-imfchan": 1, "-type": 1, "-cc": 1,
+}
+
+// texturePath returns the path of a texture map, after the options, like "-s 1 1 1".
+// The path may contain spaces.
+func texturePath(fields []string) (string, error) {
+	for len(fields) > 0 {
+		count, ok := textureOptions[fields[0]]
+		if !ok {
+			break
+		}
+		option := fields[0]
+		fields = fields[1:]
+		for i := 0; i < count && len(fields) > 0; i++ {
+			if _, err := strconv.ParseFloat(fields[0], 32); err != nil && count == 3 && i > 0 {
+				// The rest of the values are optional
+				break
+			}
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return "", fmt.Errorf("missing texture path after %s", option)
+		}
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("missing texture path")
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// ReadMTL reads materials from a Wavefront MTL file, and returns them by name
+func ReadMTL(r io.Reader) (map[string]*Material, error) {
+	materials := make(map[string]*Material)
+	var current *Material
+	scanner := newLineScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := objFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		keyword, args := fields[0], fields[1:]
+		if keyword == "newmtl" {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("line %d: missing material name", lineNumber)
+			}
+			current = &Material{Name: strings.Join(args, " "), Opacity: 1}
+			materials[current.Name] = current
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: %q before the first newmtl", lineNumber, keyword)
+		}
+		var err error
+		switch keyword {
+		case "Ka":
+			current.Ambient, err = parseMTLColor(args)
+		case "Kd":
+			current.Diffuse, err = parseMTLColor(args)
+		case "Ks":
+			current.Specular, err = parseMTLColor(args)
+		case "Ke":
+			current.Emissive, err = parseMTLColor(args)
+		case "Ns", "d", "Tr":
+			var values []float32
+			if len(args) != 1 {
+				err = fmt.Errorf("expected 1 number, got %d", len(args))
+			} else if values, err = parseFloats(args); err == nil {
+				switch keyword {
+				case "Ns":
+					current.Shininess = values[0]
+				case "d":
+					current.Opacity = values[0]
+				case "Tr":
+					current.Opacity = 1 - values[0]
+				}
+			}
+		case "map_Ka", "map_Kd", "map_Ks", "map_Bump", "map_bump", "bump":
+			var path string
+			if path, err = texturePath(args); err != nil {
+				break
+			}
+			switch keyword {
+			case "map_Ka":
+				current.AmbientMap = path
+			case "map_Kd":
+				current.DiffuseMap = path
+			case "map_Ks":
+				current.SpecularMap = path
+			default:
+				current.BumpMap = path
+			}
+		}
+		// Other keywords, like Ni and illum, are ignored
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %s", lineNumber, keyword, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %s", lineNumber+1, err)
+	}
+	return materials, nil
+}
+
+// LoadMTL loads materials from a Wavefront MTL file, and returns them by name
+func LoadMTL(filename string) (map[string]*Material, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	materials, err := ReadMTL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return materials, nil
+}
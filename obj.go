@@ -0,0 +1,301 @@
+package pixelpusher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// objCorner is a corner of a face in an OBJ file, with indices into the positions, texture coordinates and normals.
+// vt and vn are -1 if they are not given.
+type objCorner struct {
+	v, vt, vn int
+}
+
+// objFace is a polygon in an OBJ file, and the group it belongs to
+type objFace struct {
+	corners []objCorner
+	group   MeshGroup
+}
+
+// objVertexKey decides which corners of the faces share a vertex in the mesh.
+// Corners without a normal only share a vertex within the same smoothing group, or within the same face.
+type objVertexKey struct {
+	corner    objCorner
+	smoothing int
+	face      int
+}
+
+// objIndex converts an index from an OBJ file, which starts at 1, or counts backwards from the end if it is negative,
+// to an index that starts at 0. count is how many values the index may refer to.
+func objIndex(field string, count int) (int, error) {
+	i, err := strconv.Atoi(field)
+	if err != nil {
+		return 0, fmt.Errorf("invalid index %q", field)
+	}
+	switch {
+	case i > 0 && i <= count:
+		return i - 1, nil
+	case i < 0 && -i <= count:
+		return count + i, nil
+	}
+	return 0, fmt.Errorf("index %d is out of range, there are %d", i, count)
+}
+
+// mtlLibraryNames returns the names of the material libraries in the arguments of mtllib.
+// The names may contain spaces, so the fields are joined until a field ends with ".mtl".
+func mtlLibraryNames(fields []string) []string {
+	var names, name []string
+	for _, field := range fields {
+		name = append(name, field)
+		if strings.HasSuffix(strings.ToLower(field), ".mtl") {
+			names = append(names, strings.Join(name, " "))
+			name = nil
+		}
+	}
+	if len(name) > 0 {
+		names = append(names, strings.Join(name, " "))
+	}
+	return names
+}
+
+// ReadOBJ reads a mesh from a Wavefront OBJ file, with positions, texture coordinates, normals, and faces with
+// any number of corners, which are divided into triangles, assuming that they are convex. The groups, objects,
+// smoothing groups and materials of the faces are kept in Mesh.Groups. Corners without a normal get the
+// average normal of the faces around them within the same smoothing group, or the normal of their face.
+// If no normals are given and there are no smoothing groups, the mesh has no normals.
+// openMTL opens the material libraries that are referenced with mtllib. If it is nil, or if it returns an error
+// that is fs.ErrNotExist, the material libraries are not read, and the materials only have a name.
+func ReadOBJ(r io.Reader, openMTL func(name string) (io.ReadCloser, error)) (*Mesh, error) {
+	var (
+		positions, normals []Vec3
+		uvs                []Vec2
+		faces              []objFace
+		group              MeshGroup
+		materials          = make(map[string]*Material)
+	)
+	scanner := newLineScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := objFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		keyword, args := fields[0], fields[1:]
+		switch keyword {
+		case "v", "vn", "vt":
+			values, err := parseFloats(args)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %s", lineNumber, keyword, err)
+			}
+			switch {
+			case keyword == "vt" && len(values) >= 1:
+				uvs = append(uvs, Vec2{values[0], 0})
+				if len(values) >= 2 {
+					uvs[len(uvs)-1].Y = values[1]
+				}
+			case keyword != "vt" && len(values) >= 3:
+				v := Vec3{values[0], values[1], values[2]}
+				if keyword == "v" {
+					positions = append(positions, v)
+				} else {
+					normals = append(normals, v.Normalize())
+				}
+			default:
+				return nil, fmt.Errorf("line %d: %s: expected more numbers, got %d", lineNumber, keyword, len(values))
+			}
+		case "f":
+			if len(args) < 3 {
+				return nil, fmt.Errorf("line %d: f: a face needs at least 3 corners, got %d", lineNumber, len(args))
+			}
+			face := objFace{group: group}
+			for _, arg := range args {
+				parts := strings.Split(arg, "/")
+				if len(parts) > 3 {
+					return nil, fmt.Errorf("line %d: f: invalid corner %q", lineNumber, arg)
+				}
+				c := objCorner{-1, -1, -1}
+				var err error
+				c.v, err = objIndex(parts[0], len(positions))
+				if err == nil && len(parts) > 1 && parts[1] != "" {
+					c.vt, err = objIndex(parts[1], len(uvs))
+				}
+				if err == nil && len(parts) > 2 && parts[2] != "" {
+					c.vn, err = objIndex(parts[2], len(normals))
+				}
+				if err != nil {
+					return nil, fmt.Errorf("line %d: f: corner %q: %s", lineNumber, arg, err)
+				}
+				face.corners = append(face.corners, c)
+			}
+			faces = append(faces, face)
+		case "o":
+			group.Object = strings.Join(args, " ")
+		case "g":
+			group.Name = strings.Join(args, " ")
+		case "s":
+			if len(args) != 1 {
+				return nil, fmt.Errorf("line %d: s: expected a smoothing group or \"off\"", lineNumber)
+			}
+			if args[0] == "off" {
+				group.Smoothing = 0
+				break
+			}
+			s, err := strconv.Atoi(args[0])
+			if err != nil || s < 0 {
+				return nil, fmt.Errorf("line %d: s: invalid smoothing group %q", lineNumber, args[0])
+			}
+			group.Smoothing = s
+		case "usemtl":
+			name := strings.Join(args, " ")
+			if materials[name] == nil {
+				materials[name] = &Material{Name: name, Opacity: 1}
+			}
+			group.Material = materials[name]
+		case "mtllib":
+			if openMTL == nil {
+				break
+			}
+			for _, name := range mtlLibraryNames(args) {
+				f, err := openMTL(name)
+				if errors.Is(err, fs.ErrNotExist) {
+					// Material libraries are often left out when sharing OBJ files
+					continue
+				} else if err != nil {
+					return nil, fmt.Errorf("line %d: mtllib: %s", lineNumber, err)
+				}
+				library, err := ReadMTL(f)
+				f.Close()
+				if err != nil {
+					return nil, fmt.Errorf("line %d: mtllib: %s: %s", lineNumber, name, err)
+				}
+				for materialName, material := range library {
+					if m := materials[materialName]; m != nil {
+						// The material is already used, so update it
+						*m = *material
+					} else {
+						materials[materialName] = material
+					}
+				}
+			}
+		}
+		// Other keywords, like l for lines and the keywords for curves and surfaces, are ignored
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %s", lineNumber+1, err)
+	}
+	return objMesh(positions, normals, uvs, faces), nil
+}
+
+// objMesh creates a mesh from the faces of an OBJ file, where the faces are divided into triangles
+func objMesh(positions, normals []Vec3, uvs []Vec2, faces []objFace) *Mesh {
+	// Find out if the mesh needs normals and texture coordinates
+	hasNormals, hasUVs, smoothing := false, false, false
+	for _, face := range faces {
+		smoothing = smoothing || face.group.Smoothing != 0
+		for _, c := range face.corners {
+			hasNormals = hasNormals || c.vn >= 0
+			hasUVs = hasUVs || c.vt >= 0
+		}
+	}
+	computeNormals := hasNormals || smoothing
+
+	m := NewMesh()
+	vertices := make(map[objVertexKey]int)
+	var missingNormals []bool // The vertices that need a computed normal
+	// Vertices in the same smoothing group with the same position are smoothed together, even if they have
+	// different texture coordinates, so shared has the index of the first of them for each vertex
+	var shared []int
+	smoothed := make(map[[2]int]int)
+	for i, face := range faces {
+		var indices []int
+		for _, c := range face.corners {
+			key := objVertexKey{corner: c}
+			if c.vn < 0 && computeNormals {
+				key.smoothing, key.face = face.group.Smoothing, -1
+				if face.group.Smoothing == 0 {
+					key.face = i
+				}
+			}
+			index, ok := vertices[key]
+			if !ok {
+				index = len(m.Positions)
+				vertices[key] = index
+				m.Positions = append(m.Positions, positions[c.v])
+				if hasUVs {
+					var uv Vec2
+					if c.vt >= 0 {
+						uv = uvs[c.vt]
+					}
+					m.UVs = append(m.UVs, uv)
+				}
+				if computeNormals {
+					var n Vec3
+					if c.vn >= 0 {
+						n = normals[c.vn]
+					}
+					m.Normals = append(m.Normals, n)
+					missingNormals = append(missingNormals, c.vn < 0)
+					first := index
+					if c.vn < 0 && face.group.Smoothing != 0 {
+						positionKey := [2]int{c.v, face.group.Smoothing}
+						if j, ok := smoothed[positionKey]; ok {
+							first = j
+						} else {
+							smoothed[positionKey] = index
+						}
+					}
+					shared = append(shared, first)
+				}
+			}
+			indices = append(indices, index)
+		}
+		// Start a new group when the group changes
+		if last := len(m.Groups) - 1; last < 0 || !sameGroup(m.Groups[last], face.group) {
+			g := face.group
+			g.First, g.Count = len(m.Triangles), 0
+			m.Groups = append(m.Groups, g)
+		}
+		for j := 2; j < len(indices); j++ {
+			m.AddTriangle(indices[0], indices[j-1], indices[j])
+			m.Groups[len(m.Groups)-1].Count++
+		}
+	}
+	if computeNormals {
+		computed := m.vertexNormals(shared)
+		for i, missing := range missingNormals {
+			if missing {
+				m.Normals[i] = computed[i]
+			}
+		}
+	}
+	return m
+}
+
+// sameGroup checks if two groups have the same object, name, material and smoothing group
+func sameGroup(a, b MeshGroup) bool {
+	return a.Object == b.Object && a.Name == b.Name && a.Material == b.Material && a.Smoothing == b.Smoothing
+}
+
+// LoadOBJ loads a mesh from a Wavefront OBJ file. The material libraries are loaded from the same directory.
+func LoadOBJ(filename string) (*Mesh, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir := filepath.Dir(filename)
+	m, err := ReadOBJ(f, func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, name))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return m, nil
+}
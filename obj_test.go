@@ -0,0 +1,204 @@
+package pixelpusher
+
+import (
+	"errors"
+	"image/color"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const testMTL = `# Two materials
+newmtl red
+Ka 0.1 0.1 0.1
+Kd 1 0 0
+Ks 0.5
+Ns 100
+d 0.5
+map_Kd -s 1 1 -o 0.5 -clamp on red texture.png
+
+newmtl blue
+Kd 0 0 1
+Tr 0.25
+bump blue_bump.png
+illum 2
+`
+
+const testOBJ = `# A square and a triangle
+mtllib other.mtl test materials.mtl
+o square
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+vt 0 0
+vt 1 0
+vt 1 1
+vt 0 1
+vn 0 0 2
+usemtl red
+f 1/1/1 2/2/1 3/3/1 4/4/1
+o triangle
+g first second
+s 1
+usemtl blue
+v 0 0 1
+v 1 0 1
+v 0 1 1
+f -3 -2 -1
+`
+
+func TestReadOBJ(t *testing.T) {
+	m, err := ReadOBJ(strings.NewReader("mtllib missing.mtl\n"+testOBJ), func(name string) (io.ReadCloser, error) {
+		if name != "test materials.mtl" {
+			return nil, fs.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader(testMTL)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Positions) != 7 || len(m.Triangles) != 3 {
+		t.Fatalf("expected 7 vertices and 3 triangles, got %d and %d", len(m.Positions), len(m.Triangles))
+	}
+	// The square is divided into two triangles, that share the vertices
+	if m.Triangles[0] != [3]int{0, 1, 2} || m.Triangles[1] != [3]int{0, 2, 3} {
+		t.Error("unexpected triangles:", m.Triangles[:2])
+	}
+	if m.UVs[2] != (Vec2{1, 1}) || m.UVs[4] != (Vec2{}) {
+		t.Error("unexpected texture coordinates:", m.UVs)
+	}
+	// Given normals are normalized, and missing normals are computed from the faces
+	if m.Normals[0] != (Vec3{0, 0, 1}) || m.Normals[6] != (Vec3{0, 0, 1}) {
+		t.Error("unexpected normals:", m.Normals)
+	}
+	// Negative indices count backwards from the last vertex
+	if m.Positions[m.Triangles[2][0]] != (Vec3{0, 0, 1}) {
+		t.Error(m.Positions[m.Triangles[2][0]], "!=", Vec3{0, 0, 1})
+	}
+
+	if len(m.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(m.Groups))
+	}
+	square, triangle := m.Groups[0], m.Groups[1]
+	if square.Object != "square" || square.First != 0 || square.Count != 2 || square.Smoothing != 0 {
+		t.Errorf("unexpected group: %+v", square)
+	}
+	if triangle.Object != "triangle" || triangle.Name != "first second" || triangle.First != 2 || triangle.Count != 1 || triangle.Smoothing != 1 {
+		t.Errorf("unexpected group: %+v", triangle)
+	}
+
+	// The materials are read from the material library
+	red, blue := square.Material, triangle.Material
+	if red == nil || blue == nil {
+		t.Fatal("expected materials")
+	}
+	if red.Name != "red" || red.Diffuse != (color.RGBA{0xff, 0, 0, 0xff}) || red.Ambient != (color.RGBA{0x1a, 0x1a, 0x1a, 0xff}) {
+		t.Errorf("unexpected material: %+v", red)
+	}
+	if red.Specular != (color.RGBA{0x80, 0x80, 0x80, 0xff}) || red.Shininess != 100 || red.Opacity != 0.5 || red.DiffuseMap != "red texture.png" {
+		t.Errorf("unexpected material: %+v", red)
+	}
+	if blue.Diffuse != (color.RGBA{0, 0, 0xff, 0xff}) || blue.Opacity != 0.75 || blue.BumpMap != "blue_bump.png" {
+		t.Errorf("unexpected material: %+v", blue)
+	}
+}
+
+func TestReadOBJSmoothing(t *testing.T) {
+	// Two faces that share an edge, first without smoothing, then in the same smoothing group
+	const faces = "v 0 0 0\nv 1 0 0\nv 0 1 0\nv 0 0 1\n%s\nf 1 2 3\nf 1 4 2\n"
+	m, err := ReadOBJ(strings.NewReader(strings.Replace(faces, "%s", "s off", 1)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Positions) != 4 || m.Normals != nil {
+		t.Errorf("expected 4 shared vertices without normals, got %d and %v", len(m.Positions), m.Normals)
+	}
+	m, err = ReadOBJ(strings.NewReader(strings.Replace(faces, "%s", "s 2", 1)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Positions) != 4 || !closeToVec3(m.Normals[0], Vec3{0, 1, 1}.Normalize()) {
+		t.Errorf("expected 4 shared vertices with smooth normals, got %d and %v", len(m.Positions), m.Normals)
+	}
+	// Faces in the same smoothing group are smoothed together across a seam in the texture coordinates
+	seam := strings.Replace(strings.Replace(faces, "%s", "s 1\nvt 0 0\nvt 1 0\nvt 0 1\nvt 1 1", 1), "f 1 2 3\nf 1 4 2", "f 1/1 2/2 3/3\nf 1/4 4/3 2/1", 1)
+	m, err = ReadOBJ(strings.NewReader(seam), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Positions) != 6 || !closeToVec3(m.Normals[0], Vec3{0, 1, 1}.Normalize()) || !closeToVec3(m.Normals[3], m.Normals[0]) || !closeToVec3(m.Normals[1], m.Normals[5]) {
+		t.Errorf("expected 6 vertices with smooth normals across the seam, got %d and %v", len(m.Positions), m.Normals)
+	}
+	// When some faces have normals, faces without smoothing get their own vertices with the face normal
+	m, err = ReadOBJ(strings.NewReader(strings.Replace(faces, "%s", "vn 1 0 0\nf 1//1 2//1 3//1", 1)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Positions) != 9 || m.Normals[3] != (Vec3{0, 0, 1}) || m.Normals[6] != (Vec3{0, 1, 0}) {
+		t.Errorf("expected 9 vertices with face normals, got %d and %v", len(m.Positions), m.Normals)
+	}
+}
+
+func TestReadOBJLines(t *testing.T) {
+	// Comments at the end of lines are ignored, and lines can be longer than the default limit of bufio.Scanner
+	const vertices = "v 0 0 0 # origin\nv 1 0 0\nv 0 1 0#no space\n"
+	m, err := ReadOBJ(strings.NewReader(vertices+"f"+strings.Repeat(" 1 2 3", 20000)+"\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Positions) != 3 || len(m.Triangles) != 60000-2 {
+		t.Errorf("expected 3 vertices and %d triangles, got %d and %d", 60000-2, len(m.Positions), len(m.Triangles))
+	}
+	_, err = ReadOBJ(strings.NewReader(vertices+"f"+strings.Repeat(" 1 2 3", 20000)+" 4\n"), nil)
+	if message := "line 4: f: corner \"4\": index 4 is out of range, there are 3"; err == nil || err.Error() != message {
+		t.Errorf("expected %q, got %v", message, err)
+	}
+	// Read errors also have line numbers
+	_, err = ReadOBJ(io.MultiReader(strings.NewReader(vertices), iotest.ErrReader(errors.New("broken"))), nil)
+	if err == nil || err.Error() != "line 4: broken" {
+		t.Errorf("expected %q, got %v", "line 4: broken", err)
+	}
+	materials, err := ReadMTL(strings.NewReader("newmtl gray # a comment\nKd 0.5 # 0.5 0.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gray := materials["gray"]; gray == nil || gray.Diffuse != (color.RGBA{0x80, 0x80, 0x80, 0xff}) {
+		t.Errorf("unexpected materials: %v", materials)
+	}
+}
+
+func TestReadOBJErrors(t *testing.T) {
+	for _, test := range []struct {
+		obj, message string
+	}{
+		{"v 0 0 0\nv 1 x 0", "line 2: v: invalid number \"x\""},
+		{"v 0 0\n", "line 1: v: expected more numbers, got 2"},
+		{"v 0 0 0\nv 1 0 0\nf 1 2", "line 3: f: a face needs at least 3 corners, got 2"},
+		{"v 0 0 0\n\nf 1 2 3", "line 3: f: corner \"2\": index 2 is out of range, there are 1"},
+		{"v 0 0 0\nf 1 1 -2", "line 2: f: corner \"-2\": index -2 is out of range, there are 1"},
+		{"v 0 0 0\nf 0 1 1", "line 2: f: corner \"0\": index 0 is out of range, there are 1"},
+		{"v 0 0 0\nf 1/1 1 1", "line 2: f: corner \"1/1\": index 1 is out of range, there are 0"},
+		{"s on", "line 1: s: invalid smoothing group \"on\""},
+		{"mtllib locked.mtl", "line 1: mtllib: permission denied"},
+		{"mtllib bad.mtl", "line 1: mtllib: bad.mtl: line 2: Kd: expected 1 or 3 numbers, got 2"},
+	} {
+		_, err := ReadOBJ(strings.NewReader(test.obj), func(name string) (io.ReadCloser, error) {
+			if name == "bad.mtl" {
+				return io.NopCloser(strings.NewReader("newmtl bad\nKd 1 1\n")), nil
+			}
+			return nil, errors.New("permission denied")
+		})
+		if err == nil || err.Error() != test.message {
+			t.Errorf("expected %q, got %v", test.message, err)
+		}
+	}
+	if _, err := ReadMTL(strings.NewReader("Kd 1 1 1")); err == nil || err.Error() != "line 1: \"Kd\" before the first newmtl" {
+		t.Error("unexpected error:", err)
+	}
+	if _, err := ReadMTL(strings.NewReader("newmtl a\nmap_Kd -s 1 1 1")); err == nil || err.Error() != "line 2: map_Kd: missing texture path after -s" {
+		t.Error("unexpected error:", err)
+	}
+}
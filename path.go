@@ -0,0 +1,178 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math"
+)
+
+// DefaultTolerance is the tolerance that is used when Path.Tolerance is 0
+const DefaultTolerance = 0.25
+
+// maxFlattenDepth is the largest number of times a curve is divided in two, when it is flattened
+const maxFlattenDepth = 16
+
+// Path is a shape made of lines, Bezier curves and arcs, with one or more contours.
+// A Path can be filled with Buffer.FillPath or stroked with Buffer.StrokePath.
+// The curves are flattened into lines when they are added, so Tolerance must be set before adding curves.
+// The zero value is an empty path.
+type Path struct {
+	Tolerance float32 // How far the lines may be from the curves, in pixels. 0 means DefaultTolerance.
+
+	contours [][]Point
+	closed   []bool
+}
+
+// NewPath creates a new, empty path
+func NewPath() *Path {
+	return &Path{Tolerance: DefaultTolerance}
+}
+
+// tolerance returns the tolerance for flattening curves
+func (p *Path) tolerance() float32 {
+	if p.Tolerance <= 0 {
+		return DefaultTolerance
+	}
+	return p.Tolerance
+}
+
+// current returns the current point, and false if there is no current contour
+func (p *Path) current() (Point, bool) {
+	if len(p.contours) == 0 || p.closed[len(p.contours)-1] {
+		if len(p.contours) > 0 {
+			// After a contour is closed, the next contour starts where the closed contour started
+			return p.contours[len(p.contours)-1][0], false
+		}
+		return Point{}, false
+	}
+	contour := p.contours[len(p.contours)-1]
+	return contour[len(contour)-1], true
+}
+
+// add adds a point to the current contour, and starts a new contour if there is none
+func (p *Path) add(pt Point) {
+	if start, ok := p.current(); !ok {
+		p.contours = append(p.contours, []Point{start})
+		p.closed = append(p.closed, false)
+	}
+	i := len(p.contours) - 1
+	p.contours[i] = append(p.contours[i], pt)
+}
+
+// MoveTo starts a new contour at (x, y)
+func (p *Path) MoveTo(x, y float32) {
+	if i := len(p.contours) - 1; i >= 0 && len(p.contours[i]) == 1 && !p.closed[i] {
+		// The previous contour is only a single point, so replace it
+		p.contours[i][0] = Point{x, y}
+		return
+	}
+	p.contours = append(p.contours, []Point{{x, y}})
+	p.closed = append(p.closed, false)
+}
+
+// LineTo adds a line from the current point to (x, y). If there is no current point, the line starts at (0, 0).
+func (p *Path) LineTo(x, y float32) {
+	p.add(Point{x, y})
+}
+
+// QuadTo adds a quadratic Bezier curve from the current point to (x, y), with the control point (cx, cy)
+func (p *Path) QuadTo(cx, cy, x, y float32) {
+	start, _ := p.current()
+	p.flattenQuad(start, Point{cx, cy}, Point{x, y}, 0)
+}
+
+// flattenQuad adds lines that follow the quadratic Bezier curve from p0 to p2, by dividing it in two
+// until each part is within the tolerance from a straight line
+func (p *Path) flattenQuad(p0, p1, p2 Point, depth int) {
+	// The distance between the curve and the line from p0 to p2 is at most a quarter of this
+	if p0.Sub(p1.Mul(2)).Add(p2).Len()/4 <= p.tolerance() || depth >= maxFlattenDepth {
+		p.add(p2)
+		return
+	}
+	// De Casteljau's algorithm
+	p01, p12 := p0.Add(p1).Mul(0.5), p1.Add(p2).Mul(0.5)
+	mid := p01.Add(p12).Mul(0.5)
+	p.flattenQuad(p0, p01, mid, depth+1)
+	p.flattenQuad(mid, p12, p2, depth+1)
+}
+
+// CubicTo adds a cubic Bezier curve from the current point to (x, y), with the control points (c1x, c1y) and (c2x, c2y)
+func (p *Path) CubicTo(c1x, c1y, c2x, c2y, x, y float32) {
+	start, _ := p.current()
+	p.flattenCubic(start, Point{c1x, c1y}, Point{c2x, c2y}, Point{x, y}, 0)
+}
+
+// flattenCubic adds lines that follow the cubic Bezier curve from p0 to p3, by dividing it in two
+// until each part is within the tolerance from a straight line
+func (p *Path) flattenCubic(p0, p1, p2, p3 Point, depth int) {
+	// The distance between the curve and the line from p0 to p3 is at most three quarters of the largest of these
+	d1, d2 := p0.Sub(p1.Mul(2)).Add(p2).Len(), p1.Sub(p2.Mul(2)).Add(p3).Len()
+	if 0.75*float32(math.Max(float64(d1), float64(d2))) <= p.tolerance() || depth >= maxFlattenDepth {
+		p.add(p3)
+		return
+	}
+	// De Casteljau's algorithm
+	p01, p12, p23 := p0.Add(p1).Mul(0.5), p1.Add(p2).Mul(0.5), p2.Add(p3).Mul(0.5)
+	p012, p123 := p01.Add(p12).Mul(0.5), p12.Add(p23).Mul(0.5)
+	mid := p012.Add(p123).Mul(0.5)
+	p.flattenCubic(p0, p01, p012, mid, depth+1)
+	p.flattenCubic(mid, p123, p23, p3, depth+1)
+}
+
+// Arc adds a part of an ellipse with the given center and radii, from the start angle and clockwise to the end angle.
+// The angles are in radians, as for Buffer.Arc. If there is a current point, a line is added from it to the start
+// of the arc, otherwise a new contour is started there.
+func (p *Path) Arc(cx, cy, rx, ry, start, end float32) {
+	s, span := normalizeAngles(start, end)
+	// The largest angle step where the lines are within the tolerance from the arc
+	r := math.Max(math.Abs(float64(rx)), math.Abs(float64(ry)))
+	step := 2 * math.Pi
+	if t := float64(p.tolerance()); r > t {
+		step = 2 * math.Acos(1-t/r)
+	}
+	n := int(math.Min(math.Ceil(span/step), 4096))
+	if n < 1 {
+		n = 1
+	}
+	at := func(a float64) Point {
+		return Point{cx + rx*float32(math.Cos(a)), cy + ry*float32(math.Sin(a))}
+	}
+	if _, ok := p.current(); ok {
+		p.add(at(s))
+	} else {
+		p.MoveTo(at(s).X, at(s).Y)
+	}
+	for i := 1; i <= n; i++ {
+		p.add(at(s + span*float64(i)/float64(n)))
+	}
+}
+
+// Close closes the current contour with a line back to where it started.
+// The next contour starts at the same point, unless MoveTo is called.
+func (p *Path) Close() {
+	if _, ok := p.current(); ok {
+		p.closed[len(p.closed)-1] = true
+	}
+}
+
+// Contours returns the flattened contours of the path, where the curves have been replaced by lines
+func (p *Path) Contours() [][]Point {
+	return p.contours
+}
+
+// FillPath fills the path with the given fill rule, blended on top with BlendOver, clipped to the buffer, concurrently.
+// All contours are closed when filling. cores is the number of goroutines that will be used.
+func (b *Buffer) FillPath(cores int, p *Path, rule FillRule, c color.RGBA) {
+	b.PolygonBlend(cores, p.contours, rule, c, BlendOver)
+}
+
+// FillPathBlend fills the path with the given fill rule and blend mode, clipped to the buffer, concurrently.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) FillPathBlend(cores int, p *Path, rule FillRule, c color.RGBA, mode BlendMode) {
+	b.PolygonBlend(cores, p.contours, rule, c, mode)
+}
+
+// StrokePath draws anti-aliased lines along the path, with the given stroke style, clipped to the buffer.
+// Pixels where the lines overlap are only drawn once. If style is nil, the lines are one pixel wide.
+func (b *Buffer) StrokePath(p *Path, style *StrokeStyle, c color.RGBA) {
+	b.strokeContours(p.contours, p.closed, style, c)
+}
@@ -0,0 +1,136 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestPathCurves(t *testing.T) {
+	p := NewPath()
+	p.MoveTo(0, 0)
+	p.QuadTo(50, 100, 100, 0)
+	p.CubicTo(120, -50, 180, 50, 200, 0)
+	contours := p.Contours()
+	if len(contours) != 1 {
+		t.Fatalf("expected one contour, got %d", len(contours))
+	}
+	points := contours[0]
+	if last := points[len(points)-1]; last != (Point{200, 0}) || points[0] != (Point{0, 0}) {
+		t.Errorf("unexpected ends: %v and %v", points[0], last)
+	}
+	// The quadratic curve goes through (50, 50), halfway along the curve
+	found := false
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		if a.X <= 50 && b.X >= 50 {
+			y := a.Y + (b.Y-a.Y)*(50-a.X)/(b.X-a.X)
+			found = math.Abs(float64(y-50)) <= DefaultTolerance
+		}
+	}
+	if !found {
+		t.Error("expected the flattened curve to be within the tolerance from the curve")
+	}
+
+	// A lower tolerance gives more lines
+	fine := &Path{Tolerance: 0.01}
+	fine.MoveTo(0, 0)
+	fine.QuadTo(50, 100, 100, 0)
+	coarse := &Path{Tolerance: 5}
+	coarse.MoveTo(0, 0)
+	coarse.QuadTo(50, 100, 100, 0)
+	if len(fine.Contours()[0]) <= len(coarse.Contours()[0]) {
+		t.Error("expected more lines with a lower tolerance")
+	}
+}
+
+func TestPathContours(t *testing.T) {
+	var p Path
+	p.LineTo(10, 0)
+	p.LineTo(10, 10)
+	p.Close()
+	p.LineTo(0, 10)
+	p.MoveTo(5, 5)
+	p.MoveTo(20, 20)
+	p.Arc(20, 20, 5, 5, 0, math.Pi)
+	contours := p.Contours()
+	if len(contours) != 3 {
+		t.Fatalf("expected three contours, got %d", len(contours))
+	}
+	// The first contour starts at (0, 0), and the second one starts where the first one started
+	if contours[0][0] != (Point{}) || contours[1][0] != (Point{}) || contours[1][1] != (Point{0, 10}) {
+		t.Errorf("unexpected contours: %v", contours[:2])
+	}
+	// The arc is connected to the current point with a line
+	arc := contours[2]
+	if arc[0] != (Point{20, 20}) || arc[1] != (Point{25, 20}) {
+		t.Errorf("unexpected start of the arc: %v", arc[:2])
+	}
+	if last := arc[len(arc)-1]; math.Abs(float64(last.X-15)) > 1e-4 || math.Abs(float64(last.Y-20)) > 1e-4 {
+		t.Errorf("unexpected end of the arc: %v", last)
+	}
+}
+
+func TestFillPath(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	b := NewBuffer(64, 64)
+
+	// A circle with a hole, from two arcs
+	p := NewPath()
+	p.Arc(32, 32, 30, 30, 0, 2*math.Pi)
+	p.Close()
+	p.Arc(32, 32, 10, 10, 0, 2*math.Pi)
+	p.Close()
+	b.FillPath(2, p, EvenOdd, white)
+	if b.GetPixel(32, 32) != 0 || b.GetPixel(32, 12) != 0xffffffff || b.GetPixel(1, 1) != 0 {
+		t.Error("unexpected filled path")
+	}
+	if area, expected := count(b, 0xffffffff), math.Pi*(30*30-10*10); math.Abs(float64(area)-expected) > expected*0.02 {
+		t.Errorf("expected an area of about %.0f, got %d", expected, area)
+	}
+	b.FillPathBlend(2, p, NonZero, white, BlendXor)
+	if b.GetPixel(32, 32) == 0 || b.GetPixel(32, 12) != 0 {
+		t.Error("expected the hole to be filled with the non-zero rule")
+	}
+}
+
+func TestStrokePathDashes(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	p := NewPath()
+	p.MoveTo(2.5, 5)
+	p.LineTo(42.5, 5)
+	b := NewBuffer(48, 10)
+	b.StrokePath(p, &StrokeStyle{Width: 1, Dashes: []float32{4, 6}}, white)
+	// Dashes from 2.5 to 6.5, 12.5 to 16.5, 22.5 to 26.5 and 32.5 to 36.5
+	for x := int32(0); x < 48; x++ {
+		on := x >= 3 && (x-3)%10 < 4 && x < 40
+		if got := b.GetPixel(x, 5) == 0xffffffff; got != on {
+			t.Errorf("at x %d: expected %v, got %08x", x, on, b.GetPixel(x, 5))
+		}
+	}
+
+	// With an offset and an odd number of lengths, which are repeated
+	dashes := dashPolyline([]Point{{0, 0}, {10, 0}, {10, 10}}, false, []float32{3}, 1)
+	expected := [][]Point{{{0, 0}, {2, 0}}, {{5, 0}, {8, 0}}, {{10, 1}, {10, 4}}, {{10, 7}, {10, 10}}}
+	if len(dashes) != len(expected) {
+		t.Fatalf("expected %d dashes, got %v", len(expected), dashes)
+	}
+	for i, dash := range dashes {
+		if dash[0] != expected[i][0] || dash[len(dash)-1] != expected[i][1] {
+			t.Errorf("dash %d: expected %v, got %v", i, expected[i], dash)
+		}
+	}
+
+	// Dashes that go around a corner keep the corner
+	dashes = dashPolyline([]Point{{0, 0}, {10, 0}, {10, 10}}, false, []float32{12, 2}, 0)
+	if len(dashes[0]) != 3 || dashes[0][1] != (Point{10, 0}) || dashes[0][2] != (Point{10, 2}) {
+		t.Errorf("unexpected dash: %v", dashes[0])
+	}
+
+	// Zero length dashes with round caps are dots
+	b.FastClear(0)
+	b.StrokePath(p, &StrokeStyle{Width: 2, Cap: RoundCap, Dashes: []float32{0, 10}}, white)
+	if b.GetPixel(2, 5) == 0 || b.GetPixel(12, 5) == 0 || b.GetPixel(7, 5) != 0 {
+		t.Error("expected dots")
+	}
+}
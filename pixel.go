@@ -169,41 +169,23 @@ func SetWrap(pixels []uint32, pos, size int32, colorValue uint32) {
 	pixels[i] = colorValue
 }
 
-// Blend blends two color values, based on the alpha value
+// Blend draws the second color value on top of the first one, using the alpha values of both.
+// This is Porter-Duff source over compositing, the same as BlendOver.Combine.
 func Blend(c1, c2 uint32) uint32 {
-	mf := float32(255.0)
-
-	a1 := float32(Alpha(c1)) / mf
-	a2 := float32(Alpha(c2)) / mf
-
-	// Weight the color values by alpha, then take the average
-	r := uint8((float32(Red(c1))*a1 + float32(Red(c2))*a2) / 2.0)
-	g := uint8((float32(Green(c1))*a1 + float32(Green(c2))*a2) / 2.0)
-	b := uint8((float32(Blue(c1))*a1 + float32(Blue(c2))*a2) / 2.0)
-
-	// Let the new alpha value be the product of the two given alpha values
-	//a := uint8(a1 * a2 * mf)
-
-	// Let the new alpha value be the average of the two given alpha values
-	a := uint8(((a1 + a2) / 2) * mf)
-
-	// Combine the new values to an uint32 (ARGB), and return that
-	return RGBAToColorValue(r, g, b, a)
+	return sourceOver(c1, c2, false)
 }
 
-// Add adds one color value on top of another.
+// Add adds one color value on top of another, saturating at the maximum value.
 // Only considers the alpha value of the second color value.
 // Returns an opaque color.
 func Add(c1, c2 uint32) uint32 {
-	mf := float32(255.0)
-
-	a2 := float32(Alpha(c2)) / mf
+	a2 := c2 >> 24
 
 	// Let the second color be affected by the alpha value, but not the first one
-	r := uint8((float32(Red(c1)) + float32(Red(c2))*a2) / 2.0)
-	g := uint8((float32(Green(c1)) + float32(Green(c2))*a2) / 2.0)
-	b := uint8((float32(Blue(c1)) + float32(Blue(c2))*a2) / 2.0)
+	r := minUint32(((c1>>16)&0xff)+div255(((c2>>16)&0xff)*a2), 0xff)
+	g := minUint32(((c1>>8)&0xff)+div255(((c2>>8)&0xff)*a2), 0xff)
+	b := minUint32((c1&0xff)+div255((c2&0xff)*a2), 0xff)
 
 	// Combine the new values to an uint32 (ARGB), and return that
-	return RGBAToColorValue(r, g, b, 255)
+	return 0xff000000 | r<<16 | g<<8 | b
 }
@@ -24,18 +24,32 @@ func TestColorPacking(t *testing.T) {
 func TestBlend(t *testing.T) {
 	red := RGBAToColorValue(255, 0, 0, 127)
 	blue := RGBAToColorValue(0, 0, 255, 255)
-	r, g, b, a := ColorValueToRGBA(Blend(red, blue))
+	// Half transparent red on top of opaque blue
+	r, g, b, a := ColorValueToRGBA(Blend(blue, red))
 	//fmt.Println(r, g, b, a)
-	if r != 63 {
+	if r != 127 {
 		t.Fail()
 	}
 	if g != 0 {
 		t.Fail()
 	}
-	if b != 127 {
+	if b != 128 {
 		t.Fail()
 	}
-	if a != 191 {
+	if a != 255 {
 		t.Fail()
 	}
+	// An opaque color on top of black is not darkened
+	if Blend(0xff000000, 0xff808080) != 0xff808080 {
+		t.Fail()
+	}
+}
+
+func TestAdd(t *testing.T) {
+	if cv := Add(0xff808080, 0xff808080); cv != 0xffffffff {
+		t.Errorf("expected the colors to saturate, got %x", cv)
+	}
+	if cv := Add(0xff102030, 0x80ff0000); cv != 0xff902030 {
+		t.Errorf("expected ff902030, got %x", cv)
+	}
 }
@@ -0,0 +1,470 @@
+package pixelpusher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// plyTypeSizes are the sizes in bytes of the types of properties in PLY files, by both of their names
+var plyTypeSizes = map[string]int{
+	"char": 1, "int8": 1, "uchar": 1, "uint8": 1,
+	"short": 2, "int16": 2, "ushort": 2, "uint16": 2,
+	"int": 4, "int32": 4, "uint": 4, "uint32": 4,
+	"float": 4, "float32": 4, "double": 8, "float64": 8,
+}
+
+// isPLYFloat checks if the given PLY type is a floating point type
+func isPLYFloat(typ string) bool {
+	switch typ {
+	case "float", "float32", "double", "float64":
+		return true
+	}
+	return false
+}
+
+// plyProperty is a property of an element in a PLY file. Lists have a type for the number of values.
+type plyProperty struct {
+	name, typ, countType string
+}
+
+// plyElement is an element in the header of a PLY file, like vertex or face, and how many of them there are
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// find returns the index of the first property with one of the given names, or -1
+func (e *plyElement) find(names ...string) int {
+	for i, p := range e.properties {
+		for _, name := range names {
+			if p.name == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// plyReader reads the header and the elements of a PLY file
+type plyReader struct {
+	r          *bufio.Reader
+	ascii      bool
+	order      binary.ByteOrder // The byte order of binary files
+	lineNumber int
+	fields     []string // The values that are left on the current line of an ASCII file
+	buf        [8]byte
+}
+
+// readLine reads the next line of the header, or of the elements of an ASCII file
+func (p *plyReader) readLine() (string, error) {
+	line, err := p.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err == io.EOF {
+		return "", io.ErrUnexpectedEOF
+	} else if err != nil {
+		return "", err
+	}
+	p.lineNumber++
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readHeader reads the header, up to and including end_header, and returns the elements
+func (p *plyReader) readHeader() ([]*plyElement, error) {
+	if line, err := p.readLine(); err != nil || strings.TrimSpace(line) != "ply" {
+		return nil, errors.New("not a PLY file")
+	}
+	var elements []*plyElement
+	hasFormat := false
+	for {
+		line, err := p.readLine()
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("the header has no end_header")
+		} else if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword, args := fields[0], fields[1:]
+		switch keyword {
+		case "comment", "obj_info":
+		case "format":
+			if len(args) != 2 {
+				return nil, fmt.Errorf("line %d: format: expected a format and a version", p.lineNumber)
+			}
+			switch args[0] {
+			case "ascii":
+				p.ascii = true
+			case "binary_little_endian":
+				p.order = binary.LittleEndian
+			case "binary_big_endian":
+				p.order = binary.BigEndian
+			default:
+				return nil, fmt.Errorf("line %d: format: unsupported format %q", p.lineNumber, args[0])
+			}
+			hasFormat = true
+		case "element":
+			if len(args) != 2 {
+				return nil, fmt.Errorf("line %d: element: expected a name and a count", p.lineNumber)
+			}
+			count, err := strconv.Atoi(args[1])
+			if err != nil || count < 0 {
+				return nil, fmt.Errorf("line %d: element: invalid count %q", p.lineNumber, args[1])
+			}
+			elements = append(elements, &plyElement{name: args[0], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return nil, fmt.Errorf("line %d: property before the first element", p.lineNumber)
+			}
+			var property plyProperty
+			switch {
+			case len(args) == 4 && args[0] == "list":
+				property = plyProperty{name: args[3], typ: args[2], countType: args[1]}
+				if _, ok := plyTypeSizes[property.countType]; !ok || isPLYFloat(property.countType) {
+					return nil, fmt.Errorf("line %d: property: invalid list count type %q", p.lineNumber, property.countType)
+				}
+			case len(args) == 2 && args[0] != "list":
+				property = plyProperty{name: args[1], typ: args[0]}
+			default:
+				return nil, fmt.Errorf("line %d: property: expected a type and a name", p.lineNumber)
+			}
+			if _, ok := plyTypeSizes[property.typ]; !ok {
+				return nil, fmt.Errorf("line %d: property: unknown type %q", p.lineNumber, property.typ)
+			}
+			e := elements[len(elements)-1]
+			e.properties = append(e.properties, property)
+		case "end_header":
+			if !hasFormat {
+				return nil, fmt.Errorf("line %d: end_header before the format", p.lineNumber)
+			}
+			return elements, nil
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", p.lineNumber, keyword)
+		}
+	}
+}
+
+// readValue reads one value of the given type
+func (p *plyReader) readValue(typ string) (float64, error) {
+	if p.ascii {
+		if len(p.fields) == 0 {
+			return 0, errors.New("expected more values")
+		}
+		field := p.fields[0]
+		p.fields = p.fields[1:]
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil || (!isPLYFloat(typ) && v != math.Trunc(v)) {
+			return 0, fmt.Errorf("invalid %s %q", typ, field)
+		}
+		return v, nil
+	}
+	b := p.buf[:plyTypeSizes[typ]]
+	if _, err := io.ReadFull(p.r, b); err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+	switch typ {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(p.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(p.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(p.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(p.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(p.order.Uint32(b))), nil
+	}
+	return math.Float64frombits(p.order.Uint64(b)), nil
+}
+
+// readElement reads the values of one element into row, with one slice of values for each property,
+// where properties that are not lists have one value
+func (p *plyReader) readElement(e *plyElement, row [][]float64) error {
+	if p.ascii {
+		for len(p.fields) == 0 {
+			line, err := p.readLine()
+			if err != nil {
+				return err
+			}
+			p.fields = strings.Fields(line)
+		}
+	}
+	for i, property := range e.properties {
+		row[i] = row[i][:0]
+		count := 1
+		if property.countType != "" {
+			n, err := p.readValue(property.countType)
+			if err != nil {
+				return fmt.Errorf("%s: %w", property.name, err)
+			}
+			if n < 0 {
+				return fmt.Errorf("%s: invalid count %g", property.name, n)
+			}
+			count = int(n)
+		}
+		for j := 0; j < count; j++ {
+			v, err := p.readValue(property.typ)
+			if err != nil {
+				return fmt.Errorf("%s: %w", property.name, err)
+			}
+			row[i] = append(row[i], v)
+		}
+	}
+	if p.ascii && len(p.fields) > 0 {
+		return fmt.Errorf("%d values too many", len(p.fields))
+	}
+	return nil
+}
+
+// plyColor converts a color channel to 8 bits. Floating point values go from 0 to 1, and integers from 0 to 255.
+func plyColor(v float64, typ string) uint8 {
+	if isPLYFloat(typ) {
+		v *= 255
+	}
+	return uint8(math.Max(0, math.Min(255, v)) + 0.5)
+}
+
+// ReadPLY reads a mesh from a PLY file, which can be ASCII or binary. The vertices may have positions (x, y, z),
+// normals (nx, ny, nz), texture coordinates (s, t or u, v) and colors (red, green, blue and optionally alpha),
+// and the faces are lists of vertex indices, which are divided into triangles, assuming that they are convex.
+// Other elements and properties are ignored.
+func ReadPLY(r io.Reader) (*Mesh, error) {
+	p := &plyReader{r: bufio.NewReader(r)}
+	elements, err := p.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	vertexCount := 0
+	for _, e := range elements {
+		if e.name == "vertex" {
+			vertexCount = e.count
+		}
+	}
+	m := NewMesh()
+	for _, e := range elements {
+		// Find the properties that are used for the mesh
+		var x, y, z, nx, ny, nz, u, v, red, green, blue, alpha, indices int
+		switch e.name {
+		case "vertex":
+			x, y, z = e.find("x"), e.find("y"), e.find("z")
+			if x < 0 || y < 0 || z < 0 {
+				return nil, errors.New("the vertices have no x, y and z")
+			}
+			nx, ny, nz = e.find("nx"), e.find("ny"), e.find("nz")
+			u, v = e.find("s", "u", "texture_s", "texture_u"), e.find("t", "v", "texture_t", "texture_v")
+			red, green, blue = e.find("red", "diffuse_red"), e.find("green", "diffuse_green"), e.find("blue", "diffuse_blue")
+			alpha = e.find("alpha")
+			for _, property := range [...]int{x, y, z, nx, ny, nz, u, v, red, green, blue, alpha} {
+				if property >= 0 && e.properties[property].countType != "" {
+					return nil, fmt.Errorf("property %s must not be a list", e.properties[property].name)
+				}
+			}
+		case "face":
+			if indices = e.find("vertex_indices", "vertex_index"); indices < 0 || e.properties[indices].countType == "" {
+				return nil, errors.New("the faces have no list of vertex_indices")
+			}
+		}
+		row := make([][]float64, len(e.properties))
+		for i := 0; i < e.count; i++ {
+			if err := p.readElement(e, row); err != nil {
+				if errors.Is(err, io.ErrUnexpectedEOF) {
+					return nil, fmt.Errorf("%s %d: unexpected end of file", e.name, i)
+				} else if p.ascii {
+					return nil, fmt.Errorf("line %d: %s", p.lineNumber, err)
+				}
+				return nil, fmt.Errorf("%s %d: %s", e.name, i, err)
+			}
+			switch e.name {
+			case "vertex":
+				value := func(property int) float32 {
+					return float32(row[property][0])
+				}
+				m.Positions = append(m.Positions, Vec3{value(x), value(y), value(z)})
+				if nx >= 0 && ny >= 0 && nz >= 0 {
+					m.Normals = append(m.Normals, Vec3{value(nx), value(ny), value(nz)})
+				}
+				if u >= 0 && v >= 0 {
+					m.UVs = append(m.UVs, Vec2{value(u), value(v)})
+				}
+				if red >= 0 && green >= 0 && blue >= 0 {
+					channel := func(property int) uint8 {
+						return plyColor(row[property][0], e.properties[property].typ)
+					}
+					c := color.RGBA{channel(red), channel(green), channel(blue), 0xff}
+					if alpha >= 0 {
+						c.A = channel(alpha)
+					}
+					m.Colors = append(m.Colors, c)
+				}
+			case "face":
+				corners := row[indices]
+				var err error
+				if len(corners) < 3 {
+					err = fmt.Errorf("a face needs at least 3 corners, got %d", len(corners))
+				}
+				for _, index := range corners {
+					if index < 0 || index >= float64(vertexCount) {
+						err = fmt.Errorf("vertex index %g is out of range, there are %d", index, vertexCount)
+					}
+				}
+				if err != nil {
+					if p.ascii {
+						return nil, fmt.Errorf("line %d: %s", p.lineNumber, err)
+					}
+					return nil, fmt.Errorf("face %d: %s", i, err)
+				}
+				for j := 2; j < len(corners); j++ {
+					m.AddTriangle(int(corners[0]), int(corners[j-1]), int(corners[j]))
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// LoadPLY loads a mesh from an ASCII or binary PLY file
+func LoadPLY(filename string) (*Mesh, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadPLY(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return m, nil
+}
+
+// WritePLY writes the mesh in the PLY format, which is binary little-endian unless ascii is true.
+// The positions, normals, texture coordinates, colors and triangles are written, but not the groups.
+func (m *Mesh) WritePLY(w io.Writer, ascii bool) error {
+	if err := m.checkTriangles(); err != nil {
+		return err
+	}
+	n := len(m.Positions)
+	hasNormals, hasUVs, hasColors := len(m.Normals) == n, len(m.UVs) == n, len(m.Colors) == n
+
+	var header strings.Builder
+	header.WriteString("ply\n")
+	if ascii {
+		header.WriteString("format ascii 1.0\n")
+	} else {
+		header.WriteString("format binary_little_endian 1.0\n")
+	}
+	header.WriteString("comment written by pixelpusher\n")
+	fmt.Fprintf(&header, "element vertex %d\n", n)
+	properties := []string{"x", "y", "z"}
+	if hasNormals {
+		properties = append(properties, "nx", "ny", "nz")
+	}
+	if hasUVs {
+		properties = append(properties, "s", "t")
+	}
+	for _, name := range properties {
+		fmt.Fprintf(&header, "property float %s\n", name)
+	}
+	if hasColors {
+		header.WriteString("property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n")
+	}
+	fmt.Fprintf(&header, "element face %d\n", len(m.Triangles))
+	header.WriteString("property list uchar int vertex_indices\nend_header\n")
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+
+	// Each vertex and face is collected in buf, then written
+	var buf []byte
+	separate := func() {
+		if ascii && len(buf) > 0 {
+			buf = append(buf, ' ')
+		}
+	}
+	putFloat := func(v float32) {
+		separate()
+		if ascii {
+			buf = strconv.AppendFloat(buf, float64(v), 'g', -1, 32)
+		} else {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+		}
+	}
+	putInt := func(v int, size int) {
+		separate()
+		switch {
+		case ascii:
+			buf = strconv.AppendInt(buf, int64(v), 10)
+		case size == 1:
+			buf = append(buf, uint8(v))
+		default:
+			buf = binary.LittleEndian.AppendUint32(buf, uint32(v))
+		}
+	}
+	flush := func() error {
+		if ascii {
+			buf = append(buf, '\n')
+		}
+		_, err := w.Write(buf)
+		buf = buf[:0]
+		return err
+	}
+	for i, p := range m.Positions {
+		putFloat(p.X)
+		putFloat(p.Y)
+		putFloat(p.Z)
+		if hasNormals {
+			putFloat(m.Normals[i].X)
+			putFloat(m.Normals[i].Y)
+			putFloat(m.Normals[i].Z)
+		}
+		if hasUVs {
+			putFloat(m.UVs[i].X)
+			putFloat(m.UVs[i].Y)
+		}
+		if hasColors {
+			c := m.Colors[i]
+			putInt(int(c.R), 1)
+			putInt(int(c.G), 1)
+			putInt(int(c.B), 1)
+			putInt(int(c.A), 1)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	for _, t := range m.Triangles {
+		putInt(3, 1)
+		for _, index := range t {
+			putInt(index, 4)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SavePLY saves the mesh to a PLY file, which is binary little-endian unless ascii is true.
+// Set overwrite to true to allow overwriting files.
+func (m *Mesh) SavePLY(filename string, ascii, overwrite bool) error {
+	return saveFile(filename, overwrite, func(w io.Writer) error {
+		return m.WritePLY(w, ascii)
+	})
+}
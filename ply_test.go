@@ -0,0 +1,111 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPLYRoundTrip(t *testing.T) {
+	cube := NewCube()
+	for i := range cube.Positions {
+		cube.Colors = append(cube.Colors, color.RGBA{uint8(i * 10), 0x80, 0xff - uint8(i), uint8(0xff - i*2)})
+	}
+	for _, ascii := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := cube.WritePLY(&buf, ascii); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadPLY(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(m.Positions, cube.Positions) || !slices.Equal(m.Normals, cube.Normals) || !slices.Equal(m.UVs, cube.UVs) ||
+			!slices.Equal(m.Colors, cube.Colors) || !slices.Equal(m.Triangles, cube.Triangles) {
+			t.Errorf("ascii %v: the mesh is different after writing and reading it", ascii)
+		}
+	}
+	// The colors are the colors of the vertices
+	if c := cube.Vertex(3).GetColor(); c != cube.Colors[3] {
+		t.Error(c, "!=", cube.Colors[3])
+	}
+}
+
+func TestReadPLY(t *testing.T) {
+	// A square with float colors, an element that is ignored, and a face that is divided into two triangles
+	m, err := ReadPLY(strings.NewReader(`ply
+format ascii 1.0
+comment a square
+element vertex 4
+property double x
+property double y
+property double z
+property float red
+property float green
+property float blue
+element material 1
+property list uchar uchar name
+element face 1
+property uchar flags
+property list uchar int vertex_index
+end_header
+0 0 0 1 0 0
+1 0 0 0 1 0
+
+1 1 0 0 0 1
+0 1 0 0.5 0.5 0.5
+3 1 2 3
+0 4 0 1 2 3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Positions) != 4 || m.Normals != nil || m.UVs != nil || m.Positions[2] != (Vec3{1, 1, 0}) {
+		t.Errorf("unexpected vertices: %+v", m)
+	}
+	if m.Colors[0] != (color.RGBA{0xff, 0, 0, 0xff}) || m.Colors[3] != (color.RGBA{0x80, 0x80, 0x80, 0xff}) {
+		t.Error("unexpected colors:", m.Colors)
+	}
+	if !slices.Equal(m.Triangles, [][3]int{{0, 1, 2}, {0, 2, 3}}) {
+		t.Error("unexpected triangles:", m.Triangles)
+	}
+	// Without colors, the vertices are white
+	m.Colors = nil
+	if c := m.Vertex(2).GetColor(); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Error("expected white, got", c)
+	}
+}
+
+func TestReadPLYErrors(t *testing.T) {
+	const header = "ply\nformat ascii 1.0\nelement vertex 2\nproperty float x\nproperty float y\nproperty float z\nelement face 1\nproperty list uchar int vertex_indices\nend_header\n"
+	const binaryHeader = "ply\nformat binary_little_endian 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\nend_header\n"
+	for _, test := range []struct {
+		ply, message string
+	}{
+		{"solid", "not a PLY file"},
+		{"ply\nformat ascii 1.0\n", "the header has no end_header"},
+		{"ply\nformat binary_middle_endian 1.0\n", "line 2: format: unsupported format \"binary_middle_endian\""},
+		{"ply\nelement vertex -1\n", "line 2: element: invalid count \"-1\""},
+		{"ply\nproperty float x\n", "line 2: property before the first element"},
+		{"ply\nelement vertex 1\nproperty vector x\n", "line 3: property: unknown type \"vector\""},
+		{"ply\nelement face 1\nproperty list float int vertex_indices\n", "line 3: property: invalid list count type \"float\""},
+		{"ply\nelement vertex 1\nend_header\n", "line 3: end_header before the format"},
+		{"ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nend_header\n", "the vertices have no x, y and z"},
+		{"ply\nformat ascii 1.0\nelement vertex 1\nproperty list uchar float x\nproperty float y\nproperty float z\nend_header\n0 0 0\n", "property x must not be a list"},
+		{"ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\nproperty list uchar uchar red\nproperty uchar green\nproperty uchar blue\nend_header\n0 0 0 0 0 0\n", "property red must not be a list"},
+		{header + "0 0 0\n1 1\n", "line 11: z: expected more values"},
+		{header + "0 0 0\n1 1 1 1\n", "line 11: 1 values too many"},
+		{header + "0 0 0\n1 1 x\n", "line 11: z: invalid float \"x\""},
+		{header + "0 0 0\n1 1 1\n3 0 1 2\n", "line 12: vertex index 2 is out of range, there are 2"},
+		{header + "0 0 0\n1 1 1\n2 0 1.5\n", "line 12: vertex_indices: invalid int \"1.5\""},
+		{header + "0 0 0\n1 1 1\n2 0 1\n", "line 12: a face needs at least 3 corners, got 2"},
+		{header + "0 0 0\n", "vertex 1: unexpected end of file"},
+		{binaryHeader + "\x00\x00\x00\x00\x00\x00", "vertex 0: unexpected end of file"},
+	} {
+		if _, err := ReadPLY(strings.NewReader(test.ply)); err == nil || err.Error() != test.message {
+			t.Errorf("expected %q, got %v", test.message, err)
+		}
+	}
+}
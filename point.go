@@ -0,0 +1,71 @@
+package pixelpusher
+
+import (
+	"math"
+)
+
+// Point is a position with subpixel precision. The center of the pixel at (x, y) is at Point{x, y}.
+type Point struct {
+	X, Y float32
+}
+
+// Pt is shorthand for Point{x, y}
+func Pt(x, y float32) Point {
+	return Point{x, y}
+}
+
+// Add returns the vector p+q
+func (p Point) Add(q Point) Point {
+	return Point{p.X + q.X, p.Y + q.Y}
+}
+
+// Sub returns the vector p-q
+func (p Point) Sub(q Point) Point {
+	return Point{p.X - q.X, p.Y - q.Y}
+}
+
+// Mul returns the vector p*k
+func (p Point) Mul(k float32) Point {
+	return Point{p.X * k, p.Y * k}
+}
+
+// Dot returns the dot product of p and q
+func (p Point) Dot(q Point) float32 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+// Cross returns the z component of the cross product of p and q.
+// Since y points down, it is positive when q is clockwise from p, as seen on the screen.
+func (p Point) Cross(q Point) float32 {
+	return p.X*q.Y - p.Y*q.X
+}
+
+// Len returns the length of the vector p
+func (p Point) Len() float32 {
+	return float32(math.Hypot(float64(p.X), float64(p.Y)))
+}
+
+// Normalize returns the vector p with a length of 1, or the zero vector if p has a length of 0
+func (p Point) Normalize() Point {
+	l := p.Len()
+	if l == 0 {
+		return Point{}
+	}
+	return Point{p.X / l, p.Y / l}
+}
+
+// clampUnit clamps a float32 to the range 0 to 1
+func clampUnit(x float32) float32 {
+	if x < 0 {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
+
+// floor returns the largest integer that is not larger than x
+func floor(x float32) float32 {
+	return float32(math.Floor(float64(x)))
+}
@@ -0,0 +1,145 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// FillRule decides which parts of a polygon are inside, when the polygon intersects itself or has several contours
+type FillRule int
+
+const (
+	// NonZero fills the areas where the contours wind around a non-zero number of times.
+	// Holes must go in the opposite direction of the contour around them.
+	NonZero FillRule = iota
+	// EvenOdd fills the areas that are inside an odd number of contours
+	EvenOdd
+)
+
+// polygonEdge is an edge of a polygon, that is not horizontal. top is the y value of the upper end, where x is x0.
+type polygonEdge struct {
+	top, bottom float64
+	startY      int32 // The first scanline that the edge crosses
+	x0, dxdy    float64
+	winding     int // 1 if the edge goes down, -1 if it goes up
+}
+
+// xAt returns where the edge crosses the given scanline
+func (e *polygonEdge) xAt(y int32) float64 {
+	return e.x0 + (float64(y)-e.top)*e.dxdy
+}
+
+// polygonEdges returns the edges of the given contours, sorted by the first scanline they cross
+func polygonEdges(contours [][]Point) []*polygonEdge {
+	var edges []*polygonEdge
+	for _, contour := range contours {
+		for i, p := range contour {
+			q := contour[(i+1)%len(contour)]
+			if p.Y == q.Y {
+				continue
+			}
+			e := &polygonEdge{winding: 1}
+			if q.Y < p.Y {
+				p, q = q, p
+				e.winding = -1
+			}
+			e.top, e.bottom = float64(p.Y), float64(q.Y)
+			e.x0, e.dxdy = float64(p.X), float64(q.X-p.X)/float64(q.Y-p.Y)
+			e.startY = int32(math.Min(math.Max(math.Ceil(e.top), math.MinInt32), math.MaxInt32))
+			edges = append(edges, e)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].startY < edges[j].startY })
+	return edges
+}
+
+// Polygon fills a polygon, with one or more contours, concurrently.
+// cores is the number of goroutines that will be used. pitch is the width of the pixel buffer.
+func Polygon(cores int, pixels []uint32, contours [][]Point, rule FillRule, c color.RGBA, pitch int32) {
+	NewBufferFromPixels(pixels, pitch).Polygon(cores, contours, rule, c)
+}
+
+// Polygon fills a polygon, with one or more contours, clipped to the buffer, concurrently.
+// The polygon can be concave and intersect itself, and the fill rule decides which parts are inside.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) Polygon(cores int, contours [][]Point, rule FillRule, c color.RGBA) {
+	b.PolygonBlend(cores, contours, rule, c, BlendSrc)
+}
+
+// PolygonBlend fills a polygon, with one or more contours, with the given blend mode, clipped to the buffer,
+// concurrently. The pixels whose centers are inside of the polygon are filled, so polygons that share
+// an edge do not overlap. cores is the number of goroutines that will be used.
+func (b *Buffer) PolygonBlend(cores int, contours [][]Point, rule FillRule, c color.RGBA, mode BlendMode) {
+	edges := polygonEdges(contours)
+	if len(edges) == 0 {
+		return
+	}
+	// The scanlines that are crossed by the edges, within the buffer
+	minY, maxY := int32(math.MaxInt32), int32(0)
+	for _, e := range edges {
+		minY = Min2(minY, e.startY)
+		maxY = Max2(maxY, int32(math.Min(math.Ceil(e.bottom), math.MaxInt32)))
+	}
+	minY, maxY = Max2(minY, 0), Min2(maxY, b.Height)
+
+	colorValue := ColorToColorValue(c)
+	splitRows(cores, minY, maxY, func(minYCore, maxYCore int32) {
+		b.fillPolygonRows(edges, minYCore, maxYCore, rule, colorValue, mode)
+	})
+}
+
+// fillPolygonRows fills the scanlines from minY to maxY (not included) of a polygon,
+// with an active edge table
+func (b *Buffer) fillPolygonRows(edges []*polygonEdge, minY, maxY int32, rule FillRule, colorValue uint32, mode BlendMode) {
+	type crossing struct {
+		x       float64
+		winding int
+	}
+	var (
+		active    []*polygonEdge
+		crossings []crossing
+		next      int // The next edge in the edge table, that is not active yet
+	)
+	for y := minY; y < maxY; y++ {
+		// Add the edges that start at or above this scanline, and remove the edges that end above it
+		for next < len(edges) && edges[next].startY <= y {
+			active = append(active, edges[next])
+			next++
+		}
+		kept := active[:0]
+		for _, e := range active {
+			if float64(y) < e.bottom {
+				kept = append(kept, e)
+			}
+		}
+		active = kept
+
+		// Find where the active edges cross the scanline, from left to right
+		crossings = crossings[:0]
+		for _, e := range active {
+			crossings = append(crossings, crossing{e.xAt(y), e.winding})
+		}
+		sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+
+		// Fill the spans that are inside, according to the fill rule
+		row := b.Row(y)
+		winding := 0
+		for i, cr := range crossings {
+			if rule == EvenOdd {
+				winding ^= 1
+			} else {
+				winding += cr.winding
+			}
+			if winding == 0 || i+1 == len(crossings) {
+				continue
+			}
+			// Fill the pixels whose centers are within the span
+			x1 := math.Max(math.Ceil(cr.x), 0)
+			x2 := math.Min(math.Ceil(crossings[i+1].x), float64(b.Width))
+			if x1 < x2 {
+				b.blendSpan(row[int32(x1):int32(x2)], colorValue, mode)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+// count returns the number of pixels with the given color value
+func count(b *Buffer, colorValue uint32) int {
+	n := 0
+	for y := int32(0); y < b.Height; y++ {
+		for _, cv := range b.Row(y) {
+			if cv == colorValue {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestPolygon(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	b := NewBuffer(32, 32)
+
+	// A rectangle covers the pixels whose centers are inside of it
+	b.Polygon(1, [][]Point{{{1.5, 2.5}, {11.5, 2.5}, {11.5, 7.5}, {1.5, 7.5}}}, NonZero, white)
+	if n := count(b, 0xffffffff); n != 50 {
+		t.Errorf("expected 50 pixels, got %d", n)
+	}
+	if b.GetPixel(2, 3) != 0xffffffff || b.GetPixel(11, 7) != 0xffffffff || b.GetPixel(1, 3) != 0 || b.GetPixel(12, 3) != 0 {
+		t.Error("unexpected rectangle")
+	}
+
+	// Polygons that share an edge do not overlap
+	b.FastClear(0xff000000)
+	gray := color.RGBA{0x40, 0x40, 0x40, 0xff}
+	b.PolygonBlend(1, [][]Point{{{2, 2}, {20, 3}, {9, 25}}}, NonZero, gray, BlendAdditive)
+	b.PolygonBlend(1, [][]Point{{{20, 3}, {28, 28}, {9, 25}}}, NonZero, gray, BlendAdditive)
+	if n := count(b, 0xff808080); n != 0 {
+		t.Errorf("expected no pixels to be drawn twice, got %d", n)
+	}
+
+	// A concave polygon, shaped like an L
+	b.FastClear(0)
+	b.Polygon(4, [][]Point{{{0, 0}, {4, 0}, {4, 8}, {10, 8}, {10, 12}, {0, 12}}}, EvenOdd, white)
+	if b.GetPixel(2, 2) == 0 || b.GetPixel(8, 10) == 0 || b.GetPixel(8, 4) != 0 {
+		t.Error("unexpected concave polygon")
+	}
+}
+
+func TestPolygonFillRules(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	outer := []Point{{2, 2}, {30, 2}, {30, 30}, {2, 30}}
+	hole := []Point{{10, 10}, {22, 10}, {22, 22}, {10, 22}}
+	reversed := []Point{{10, 10}, {10, 22}, {22, 22}, {22, 10}}
+
+	tests := []struct {
+		name     string
+		contours [][]Point
+		rule     FillRule
+		hole     bool
+	}{
+		{"even-odd", [][]Point{outer, hole}, EvenOdd, true},
+		{"non-zero, same direction", [][]Point{outer, hole}, NonZero, false},
+		{"non-zero, opposite direction", [][]Point{outer, reversed}, NonZero, true},
+	}
+	for _, tc := range tests {
+		b := NewBuffer(32, 32)
+		b.Polygon(3, tc.contours, tc.rule, white)
+		if b.GetPixel(5, 5) == 0 || b.GetPixel(25, 16) == 0 {
+			t.Errorf("%s: expected the polygon to be filled", tc.name)
+		}
+		if hole := b.GetPixel(16, 16) == 0; hole != tc.hole {
+			t.Errorf("%s: expected a hole: %v", tc.name, tc.hole)
+		}
+	}
+
+	// A pentagram intersects itself. The center is only filled with the non-zero rule.
+	var star []Point
+	for i := 0; i < 5; i++ {
+		a := float64(i)*4*math.Pi/5 - math.Pi/2
+		star = append(star, Point{float32(16 + 14*math.Cos(a)), float32(16 + 14*math.Sin(a))})
+	}
+	b := NewBuffer(32, 32)
+	b.Polygon(1, [][]Point{star}, EvenOdd, white)
+	if b.GetPixel(16, 16) != 0 || b.GetPixel(16, 4) == 0 {
+		t.Error("expected the center of the star to be empty with the even-odd rule")
+	}
+	b.Polygon(1, [][]Point{star}, NonZero, white)
+	if b.GetPixel(16, 16) == 0 {
+		t.Error("expected the center of the star to be filled with the non-zero rule")
+	}
+}
+
+func TestPolygonCores(t *testing.T) {
+	// The result is the same, regardless of the number of cores, and the polygon is clipped to the buffer
+	contours := [][]Point{{{-20, 5}, {40, -10}, {50, 60}, {10, 20}, {-5, 45}}, {{5, 5}, {15, 30}, {25, 8}}}
+	c := color.RGBA{0x20, 0x40, 0x60, 0xff}
+	single := make([]uint32, 32*40)
+	Polygon(1, single, contours, EvenOdd, c, 32)
+	for _, cores := range []int{2, 3, 8, 100} {
+		b := NewBuffer(32, 40)
+		b.Polygon(cores, contours, EvenOdd, c)
+		for i := range single {
+			if single[i] != b.Pixels[i] {
+				t.Fatalf("the result with %d cores differs at %d", cores, i)
+			}
+		}
+	}
+	NewBuffer(4, 4).Polygon(2, [][]Point{{{0, 0}, {1e20, 1}, {0, 1e20}}, {{1, 1}}, nil}, NonZero, c)
+}
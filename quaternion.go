@@ -0,0 +1,121 @@
+package pixelpusher
+
+import (
+	"math"
+)
+
+// Quaternion is a rotation in 3D, as X*i + Y*j + Z*k + W. Rotations should have a length of 1.
+type Quaternion struct {
+	X, Y, Z, W float32
+}
+
+// IdentityQuaternion returns the quaternion for no rotation
+func IdentityQuaternion() Quaternion {
+	return Quaternion{0, 0, 0, 1}
+}
+
+// QuaternionFromAxisAngle returns the quaternion for a rotation by the given angle, in radians, around the given axis,
+// in the same direction as Rotation. If the axis has a length of 0, the identity quaternion is returned.
+func QuaternionFromAxisAngle(axis Vec3, angle float32) Quaternion {
+	axis = axis.Normalize()
+	if axis == (Vec3{}) {
+		return IdentityQuaternion()
+	}
+	s, c := math.Sincos(float64(angle) / 2)
+	v := axis.Mul(float32(s))
+	return Quaternion{v.X, v.Y, v.Z, float32(c)}
+}
+
+// Mul returns the product q*r, which is the rotation r followed by q
+func (q Quaternion) Mul(r Quaternion) Quaternion {
+	return Quaternion{
+		q.W*r.X + q.X*r.W + q.Y*r.Z - q.Z*r.Y,
+		q.W*r.Y - q.X*r.Z + q.Y*r.W + q.Z*r.X,
+		q.W*r.Z + q.X*r.Y - q.Y*r.X + q.Z*r.W,
+		q.W*r.W - q.X*r.X - q.Y*r.Y - q.Z*r.Z,
+	}
+}
+
+// Conjugate returns q with the vector part negated, which is the opposite rotation, if q has a length of 1
+func (q Quaternion) Conjugate() Quaternion {
+	return Quaternion{-q.X, -q.Y, -q.Z, q.W}
+}
+
+// Dot returns the dot product of q and r
+func (q Quaternion) Dot(r Quaternion) float32 {
+	return q.X*r.X + q.Y*r.Y + q.Z*r.Z + q.W*r.W
+}
+
+// Len returns the length of q
+func (q Quaternion) Len() float32 {
+	return float32(math.Sqrt(float64(q.Dot(q))))
+}
+
+// Normalize returns q with a length of 1, or the identity quaternion if q has a length of 0
+func (q Quaternion) Normalize() Quaternion {
+	l := q.Len()
+	if l == 0 {
+		return IdentityQuaternion()
+	}
+	return Quaternion{q.X / l, q.Y / l, q.Z / l, q.W / l}
+}
+
+// Inverse returns the inverse of q, so that q.Mul(q.Inverse()) is the identity quaternion.
+// ok is false if q has a length of 0.
+func (q Quaternion) Inverse() (inv Quaternion, ok bool) {
+	d := q.Dot(q)
+	if d == 0 {
+		return Quaternion{}, false
+	}
+	c := q.Conjugate()
+	return Quaternion{c.X / d, c.Y / d, c.Z / d, c.W / d}, true
+}
+
+// Rotate returns the vector v, rotated by q, which must have a length of 1
+func (q Quaternion) Rotate(v Vec3) Vec3 {
+	// v + 2w(u x v) + 2u x (u x v), where u is the vector part of q
+	u := Vec3{q.X, q.Y, q.Z}
+	t := u.Cross(v).Mul(2)
+	return v.Add(t.Mul(q.W)).Add(u.Cross(t))
+}
+
+// Mat3 returns the rotation matrix for q, which must have a length of 1
+func (q Quaternion) Mat3() Mat3 {
+	x, y, z, w := q.X, q.Y, q.Z, q.W
+	return Mat3{
+		1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w),
+		2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w),
+		2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y),
+	}
+}
+
+// Mat4 returns the rotation matrix for q, which must have a length of 1
+func (q Quaternion) Mat4() Mat4 {
+	return q.Mat3().Mat4()
+}
+
+// Slerp returns the rotation that is t of the way from q to r, with spherical linear interpolation,
+// which rotates at a constant speed along the shortest way. q and r must have a length of 1.
+func (q Quaternion) Slerp(r Quaternion, t float32) Quaternion {
+	cos := float64(q.Dot(r))
+	if cos < 0 {
+		// Both r and -r are the same rotation, but one of them is closer to q
+		r = Quaternion{-r.X, -r.Y, -r.Z, -r.W}
+		cos = -cos
+	}
+	var a, b float64
+	if cos > 0.9995 {
+		// The rotations are so close that linear interpolation is more precise
+		a, b = 1-float64(t), float64(t)
+	} else {
+		angle := math.Acos(cos)
+		sin := math.Sin(angle)
+		a, b = math.Sin((1-float64(t))*angle)/sin, math.Sin(float64(t)*angle)/sin
+	}
+	return Quaternion{
+		float32(a*float64(q.X) + b*float64(r.X)),
+		float32(a*float64(q.Y) + b*float64(r.Y)),
+		float32(a*float64(q.Z) + b*float64(r.Z)),
+		float32(a*float64(q.W) + b*float64(r.W)),
+	}.Normalize()
+}
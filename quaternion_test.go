@@ -0,0 +1,74 @@
+package pixelpusher
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuaternion(t *testing.T) {
+	axis := Vec3{1, 2, 3}
+	q := QuaternionFromAxisAngle(axis, 0.8)
+	if l := q.Len(); !closeTo(l, 1) {
+		t.Error(l, "!=", 1)
+	}
+
+	// Quaternions rotate the same way as rotation matrices
+	v := Vec3{3, -1, 2}
+	if a, b := q.Rotate(v), Rotation(axis, 0.8).MulPoint(v); !closeToVec3(a, b) {
+		t.Error(a, "!=", b)
+	}
+	if a, b := q.Mat4(), Rotation(axis, 0.8); !closeToMat4(a, b) {
+		t.Error(a, "!=", b)
+	}
+
+	// Multiplying combines the rotations
+	r := QuaternionFromAxisAngle(Vec3{0, 1, 0}, 1.3)
+	if a, b := q.Mul(r).Rotate(v), q.Rotate(r.Rotate(v)); !closeToVec3(a, b) {
+		t.Error(a, "!=", b)
+	}
+	inv, ok := q.Inverse()
+	if !ok {
+		t.Fatal("expected the quaternion to be invertible")
+	}
+	if p := q.Mul(inv); !closeTo(p.W, 1) || !closeTo(p.X, 0) || !closeTo(p.Y, 0) || !closeTo(p.Z, 0) {
+		t.Error(p, "!=", IdentityQuaternion())
+	}
+	if c := q.Conjugate(); !closeToVec3(c.Rotate(q.Rotate(v)), v) {
+		t.Error("expected the conjugate to rotate back")
+	}
+	if _, ok := (Quaternion{}).Inverse(); ok {
+		t.Error("expected the zero quaternion to not be invertible")
+	}
+	if n := (Quaternion{0, 0, 0, 2}).Normalize(); n != IdentityQuaternion() {
+		t.Error(n, "!=", IdentityQuaternion())
+	}
+}
+
+func TestSlerp(t *testing.T) {
+	z := Vec3{0, 0, 1}
+	a := IdentityQuaternion()
+	b := QuaternionFromAxisAngle(z, math.Pi/2)
+
+	// Halfway between no rotation and a quarter turn is an eighth of a turn
+	if s, e := a.Slerp(b, 0.5), QuaternionFromAxisAngle(z, math.Pi/4); !closeTo(s.Dot(e), 1) {
+		t.Error(s, "!=", e)
+	}
+	if s := a.Slerp(b, 0); !closeTo(s.Dot(a), 1) {
+		t.Error(s, "!=", a)
+	}
+	if s := a.Slerp(b, 1); !closeTo(s.Dot(b), 1) {
+		t.Error(s, "!=", b)
+	}
+
+	// The shortest way is taken, even when the quaternions point in opposite directions
+	neg := Quaternion{-b.X, -b.Y, -b.Z, -b.W}
+	if s := a.Slerp(neg, 0.5); !closeToVec3(s.Rotate(Vec3{1, 0, 0}), Vec3{float32(math.Sqrt2) / 2, float32(math.Sqrt2) / 2, 0}) {
+		t.Error(s, "does not rotate an eighth of a turn")
+	}
+
+	// Rotations that are very close are interpolated linearly
+	c := QuaternionFromAxisAngle(z, 1e-4)
+	if s := a.Slerp(c, 0.5); !closeTo(s.Len(), 1) {
+		t.Error(s.Len(), "!=", 1)
+	}
+}
@@ -0,0 +1,121 @@
+package pixelpusher
+
+import (
+	"fmt"
+	"io"
+)
+
+// Y4MEncoder writes frames as a YUV4MPEG2 stream, which can be piped directly into encoders like ffmpeg or x264.
+// The pixels are converted from RGB to Y'CbCr with BT.601 limited range, with 4:2:0 chroma subsampling.
+// Alpha is ignored, like when the pixels are displayed.
+type Y4MEncoder struct {
+	w         io.Writer
+	width     int
+	height    int
+	frameRate int
+	frame     []byte // "FRAME\n", followed by the Y, Cb and Cr planes
+	started   bool
+}
+
+// NewY4MEncoder creates a new YUV4MPEG2 encoder for frames of the given size.
+// frameRate is written to the stream header. 60 is used if it is 0.
+func NewY4MEncoder(w io.Writer, width, height, frameRate int) *Y4MEncoder {
+	if frameRate <= 0 {
+		frameRate = 60
+	}
+	chromaWidth, chromaHeight := (width+1)/2, (height+1)/2
+	return &Y4MEncoder{
+		w:         w,
+		width:     width,
+		height:    height,
+		frameRate: frameRate,
+		frame:     make([]byte, 6+width*height+2*chromaWidth*chromaHeight),
+	}
+}
+
+// RGBToYCbCr converts an RGB color to Y'CbCr, with BT.601 limited range (16 to 235 for Y', 16 to 240 for Cb and Cr)
+func RGBToYCbCr(r, g, b uint8) (uint8, uint8, uint8) {
+	ri, gi, bi := int32(r), int32(g), int32(b)
+	y := ((66*ri + 129*gi + 25*bi + 128) >> 8) + 16
+	cb := ((-38*ri - 74*gi + 112*bi + 128) >> 8) + 128
+	cr := ((112*ri - 94*gi - 18*bi + 128) >> 8) + 128
+	return uint8(y), uint8(cb), uint8(cr)
+}
+
+// Encode converts and writes a frame. The stream header is written before the first frame.
+func (e *Y4MEncoder) Encode(pixels []uint32, pitch int32) error {
+	if !e.started {
+		if _, err := fmt.Fprintf(e.w, "YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C420jpeg XCOLORRANGE=LIMITED\n", e.width, e.height, e.frameRate); err != nil {
+			return err
+		}
+		e.started = true
+	}
+	copy(e.frame, "FRAME\n")
+	lumaPlane := e.frame[6 : 6+e.width*e.height]
+	chromaWidth, chromaHeight := (e.width+1)/2, (e.height+1)/2
+	cbPlane := e.frame[6+e.width*e.height : 6+e.width*e.height+chromaWidth*chromaHeight]
+	crPlane := e.frame[6+e.width*e.height+chromaWidth*chromaHeight:]
+
+	for cy := 0; cy < chromaHeight; cy++ {
+		for cx := 0; cx < chromaWidth; cx++ {
+			// Each chroma sample is the average of up to 2x2 pixels
+			var cbSum, crSum, count int32
+			for y := cy * 2; y < cy*2+2 && y < e.height; y++ {
+				for x := cx * 2; x < cx*2+2 && x < e.width; x++ {
+					cv := pixels[int32(y)*pitch+int32(x)]
+					luma, cb, cr := RGBToYCbCr(Red(cv), Green(cv), Blue(cv))
+					lumaPlane[y*e.width+x] = luma
+					cbSum += int32(cb)
+					crSum += int32(cr)
+					count++
+				}
+			}
+			cbPlane[cy*chromaWidth+cx] = uint8((cbSum + count/2) / count)
+			crPlane[cy*chromaWidth+cx] = uint8((crSum + count/2) / count)
+		}
+	}
+	_, err := e.w.Write(e.frame)
+	return err
+}
+
+// Close does nothing, since every frame is written as it is encoded
+func (e *Y4MEncoder) Close() error {
+	return nil
+}
+
+// PAMEncoder writes frames as a stream of PAM images with RGBA pixels, one after the other.
+// The stream can be read by ffmpeg with "-f image2pipe -c:v pam".
+type PAMEncoder struct {
+	w      io.Writer
+	width  int
+	height int
+	frame  []byte // PAM header, followed by the RGBA pixels
+	header int    // The length of the PAM header
+}
+
+// NewPAMEncoder creates a new PAM stream encoder for frames of the given size
+func NewPAMEncoder(w io.Writer, width, height int) *PAMEncoder {
+	header := fmt.Sprintf("P7\nWIDTH %d\nHEIGHT %d\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n", width, height)
+	frame := make([]byte, len(header)+width*height*4)
+	copy(frame, header)
+	return &PAMEncoder{w: w, width: width, height: height, frame: frame, header: len(header)}
+}
+
+// Encode writes a frame as a PAM image
+func (e *PAMEncoder) Encode(pixels []uint32, pitch int32) error {
+	data := e.frame[e.header:]
+	for y := 0; y < e.height; y++ {
+		row := pixels[int32(y)*pitch:]
+		for x := 0; x < e.width; x++ {
+			i := (y*e.width + x) * 4
+			data[i], data[i+1], data[i+2], data[i+3] = ColorValueToRGBA(row[x])
+		}
+	}
+	_, err := e.w.Write(e.frame)
+	return err
+}
+
+// Close does nothing, since every frame is written as it is encoded
+func (e *PAMEncoder) Close() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+package pixelpusher
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRGBToYCbCr(t *testing.T) {
+	tests := []struct {
+		r, g, b   uint8
+		y, cb, cr uint8
+	}{
+		{0, 0, 0, 16, 128, 128},
+		{255, 255, 255, 235, 128, 128},
+		{255, 0, 0, 82, 90, 240},
+		{0, 0, 255, 41, 240, 110},
+	}
+	for _, test := range tests {
+		y, cb, cr := RGBToYCbCr(test.r, test.g, test.b)
+		if y != test.y || cb != test.cb || cr != test.cr {
+			t.Errorf("RGBToYCbCr(%d, %d, %d): expected (%d, %d, %d), got (%d, %d, %d)", test.r, test.g, test.b, test.y, test.cb, test.cr, y, cb, cr)
+		}
+	}
+}
+
+func TestY4MEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewY4MEncoder(&buf, 3, 3, 30)
+	pixels := make([]uint32, 4*3) // pitch 4, with an unused column
+	FastClear(pixels, 0xffffffff)
+	pixels[0] = 0xff000000
+	for i := 0; i < 2; i++ {
+		if err := e.Encode(pixels, 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := bufio.NewReader(&buf)
+	header, _ := r.ReadString('\n')
+	if !strings.HasPrefix(header, "YUV4MPEG2 W3 H3 F30:1 ") {
+		t.Errorf("unexpected header: %q", header)
+	}
+	for i := 0; i < 2; i++ {
+		if line, _ := r.ReadString('\n'); line != "FRAME\n" {
+			t.Fatalf("expected a frame header, got %q", line)
+		}
+		// 3x3 luma samples and 2x2 samples for each of the two chroma planes
+		data := make([]byte, 9+4+4)
+		if _, err := io.ReadFull(r, data); err != nil {
+			t.Fatal(err)
+		}
+		if data[0] != 16 || data[1] != 235 || data[8] != 235 {
+			t.Errorf("unexpected luma plane: %v", data[:9])
+		}
+		if data[9] != 128 || data[13] != 128 {
+			t.Errorf("unexpected chroma planes: %v", data[9:])
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Error("expected the end of the stream")
+	}
+}
+
+func TestPAMEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewPAMEncoder(&buf, 2, 1)
+	e.Encode([]uint32{0x80102030, 0xff405060}, 2)
+	e.Encode([]uint32{0, 0}, 2)
+	header := "P7\nWIDTH 2\nHEIGHT 1\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n"
+	expected := header + "\x10\x20\x30\x80\x40\x50\x60\xff" + header + "\x00\x00\x00\x00\x00\x00\x00\x00"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestRecordWriter(t *testing.T) {
+	var buf bytes.Buffer
+	c := New("")
+	c.Backend = NewHeadlessBackend(5)
+	c.RecordWriter = &buf
+	c.RecordFormat = RecordY4M
+	c.FrameRate = 25
+	if err := c.Run(func(c *Canvas) error { return nil }, nil, nil, nil); err != ErrQuit {
+		t.Fatal("expected ErrQuit, got", err)
+	}
+	header := fmt.Sprintf("YUV4MPEG2 W%d H%d F25:1 ", c.Width, c.Height)
+	if !strings.HasPrefix(buf.String(), header) {
+		t.Errorf("expected the stream to start with %q", header)
+	}
+	if frames := strings.Count(buf.String(), "FRAME\n"); frames != 5 {
+		t.Errorf("expected 5 frames, got %d", frames)
+	}
+}
@@ -0,0 +1,188 @@
+package pixelpusher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// FrameEncoder encodes a sequence of frames. Close must be called after the last frame.
+type FrameEncoder interface {
+	// Encode adds a frame. pitch is the width of the pixel buffer.
+	Encode(pixels []uint32, pitch int32) error
+	// Close finishes encoding, but does not close the underlying writer
+	Close() error
+}
+
+// RecordFormat is a file format for recordings
+type RecordFormat int
+
+// File formats for recordings
+const (
+	RecordGIF       RecordFormat = iota // Animated GIF, with a quantized palette for each frame
+	RecordAPNG                          // Animated PNG
+	RecordPNGFrames                     // One PNG file per frame, named frame00000.png, frame00001.png etc.
+	RecordY4M                           // Raw YUV4MPEG2 video, with 4:2:0 chroma subsampling
+	RecordPAM                           // A stream of PAM images with RGBA pixels
+)
+
+// Extension returns the filename extension for the record format, including the dot
+func (rf RecordFormat) Extension() string {
+	switch rf {
+	case RecordAPNG, RecordPNGFrames:
+		return ".png"
+	case RecordY4M:
+		return ".y4m"
+	case RecordPAM:
+		return ".pam"
+	}
+	return ".gif"
+}
+
+// recorderQueueSize is the number of frames that can be waiting to be encoded before Add blocks
+const recorderQueueSize = 256
+
+// Recorder encodes frames on a background goroutine, so that drawing does not have to wait for the encoding
+type Recorder struct {
+	Filename string // The file that is being written to, or "" if writing to an io.Writer
+	frames   chan []uint32
+	done     chan error
+	width    int
+	height   int
+	stopped  bool
+}
+
+// NewRecorder starts a background goroutine that encodes frames to the given file, in the given format.
+// width and height is the size of the frames and frameRate is used for the frame delays.
+func NewRecorder(filename string, format RecordFormat, width, height, frameRate int) (*Recorder, error) {
+	if format == RecordPNGFrames {
+		return newRecorder(filename, &pngFramesEncoder{}, width, height, nil), nil
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	bw := bufio.NewWriter(f)
+	var encoder FrameEncoder
+	if format == RecordAPNG {
+		// Write the frames directly to the file, so that the number of frames can be filled in afterwards
+		encoder, err = newAPNGFileEncoder(f, width, height, frameRate)
+	} else {
+		encoder, err = newFrameEncoder(bw, format, width, height, frameRate)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return newRecorder(filename, encoder, width, height, func() error {
+		if err := bw.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}), nil
+}
+
+// NewRecorderWriter starts a background goroutine that encodes frames to the given writer, in the given format.
+// This can be used for piping a recording to another program, for instance by using os.Stdout.
+// The writer is not closed when the recorder is stopped.
+func NewRecorderWriter(w io.Writer, format RecordFormat, width, height, frameRate int) (*Recorder, error) {
+	encoder, err := newFrameEncoder(w, format, width, height, frameRate)
+	if err != nil {
+		return nil, err
+	}
+	return newRecorder("", encoder, width, height, nil), nil
+}
+
+// newRecorder starts encoding frames with the given encoder.
+// finish is called after the encoder has been closed, if it is not nil.
+func newRecorder(filename string, encoder FrameEncoder, width, height int, finish func() error) *Recorder {
+	r := &Recorder{
+		Filename: filename,
+		frames:   make(chan []uint32, recorderQueueSize),
+		done:     make(chan error, 1),
+		width:    width,
+		height:   height,
+	}
+	go func() {
+		var err error
+		for pixels := range r.frames {
+			if err == nil {
+				err = encoder.Encode(pixels, int32(width))
+			}
+		}
+		if closeErr := encoder.Close(); err == nil {
+			err = closeErr
+		}
+		if finish != nil {
+			if finishErr := finish(); err == nil {
+				err = finishErr
+			}
+		}
+		r.done <- err
+	}()
+	return r
+}
+
+// newFrameEncoder returns an encoder for the given format
+func newFrameEncoder(w io.Writer, format RecordFormat, width, height, frameRate int) (FrameEncoder, error) {
+	switch format {
+	case RecordGIF:
+		return NewGIFEncoder(w, width, height, frameRate), nil
+	case RecordAPNG:
+		return NewAPNGEncoder(w, width, height, frameRate), nil
+	case RecordY4M:
+		return NewY4MEncoder(w, width, height, frameRate), nil
+	case RecordPAM:
+		return NewPAMEncoder(w, width, height), nil
+	}
+	return nil, fmt.Errorf("unsupported record format: %d", format)
+}
+
+// TimestampedFilename returns a filename like "recording-20060102-150405.gif",
+// with the current time and the extension for the given format
+func TimestampedFilename(prefix string, format RecordFormat) string {
+	return prefix + "-" + time.Now().Format("20060102-150405") + format.Extension()
+}
+
+// Add copies the given pixels and queues them for encoding.
+// pitch is the width of the pixel buffer. Add only blocks if the queue of frames is full.
+func (r *Recorder) Add(pixels []uint32, pitch int32) {
+	if r.stopped {
+		return
+	}
+	frame := make([]uint32, r.width*r.height)
+	for y := 0; y < r.height; y++ {
+		copy(frame[y*r.width:(y+1)*r.width], pixels[int32(y)*pitch:])
+	}
+	r.frames <- frame
+}
+
+// Stop waits for all queued frames to be encoded, then finishes writing the file
+func (r *Recorder) Stop() error {
+	if r.stopped {
+		return nil
+	}
+	r.stopped = true
+	close(r.frames)
+	return <-r.done
+}
+
+// pngFramesEncoder saves every frame as a separate PNG file in the current directory
+type pngFramesEncoder struct {
+	counter uint64
+}
+
+// Encode saves the frame as frameNNNNN.png
+func (e *pngFramesEncoder) Encode(pixels []uint32, pitch int32) error {
+	filename := fmt.Sprintf("frame%05d.png", e.counter)
+	e.counter++
+	return SavePixelsToPNG(pixels, pitch, filename, true)
+}
+
+// Close does nothing, since every frame is saved as it is encoded
+func (e *pngFramesEncoder) Close() error {
+	return nil
+}
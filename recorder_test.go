@@ -0,0 +1,246 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testFrames returns frames where a red pixel moves one pixel to the right for each frame
+func testFrames(count int) [][]uint32 {
+	frames := make([][]uint32, count)
+	for i := range frames {
+		frames[i] = make([]uint32, 16*8)
+		FastClear(frames[i], 0xff000080)
+		frames[i][3*16+i] = 0xffff0000
+	}
+	return frames
+}
+
+func TestGIFEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewGIFEncoder(&buf, 16, 8, 25)
+	frames := testFrames(4)
+	for _, pixels := range frames {
+		e.Encode(pixels, 16)
+	}
+	// The same frame again, which should only make the last frame last longer
+	e.Encode(frames[3], 16)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(g.Image))
+	}
+	expectedDelays := []int{4, 4, 4, 8}
+	for i, delay := range g.Delay {
+		if delay != expectedDelays[i] {
+			t.Errorf("expected delays %v, got %v", expectedDelays, g.Delay)
+			break
+		}
+	}
+	r, _, _, _ := g.Image[2].At(2, 3).RGBA()
+	if r>>8 != 0xff {
+		t.Error("expected a red pixel at (2, 3) in the third frame")
+	}
+}
+
+func TestGIFEncoderHighFrameRate(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewGIFEncoder(&buf, 16, 8, 100)
+	for _, pixels := range testFrames(8) {
+		e.Encode(pixels, 16)
+	}
+	e.Close()
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, delay := range g.Delay {
+		if delay < 2 {
+			t.Error("expected no delays below 2/100 seconds, got", g.Delay)
+		}
+		total += delay
+	}
+	if len(g.Image) != 4 || total != 8 {
+		t.Errorf("expected 4 frames lasting 8/100 seconds, got %d frames lasting %d/100 seconds", len(g.Image), total)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	pixels := make([]uint32, 64*64)
+	for i := range pixels {
+		pixels[i] = RGBAToColorValue(uint8(i%64*4), uint8(i/64*4), uint8(i%7*30), 255)
+	}
+	img := Quantize(pixels, 64, 256)
+	if len(img.Palette) != 256 {
+		t.Fatalf("expected 256 colors, got %d", len(img.Palette))
+	}
+	// The quantized colors should be close to the original colors, on average
+	var diff int32
+	for i, cv := range pixels {
+		r, g, b, _ := img.At(i%64, i/64).RGBA()
+		diff += Abs(int32(r>>8)-int32(Red(cv))) + Abs(int32(g>>8)-int32(Green(cv))) + Abs(int32(b>>8)-int32(Blue(cv)))
+	}
+	if average := diff / int32(len(pixels)*3); average > 12 {
+		t.Errorf("the quantized colors are too far from the original colors, with an average difference of %d", average)
+	}
+	// Few colors are kept as they are
+	img = Quantize([]uint32{0xff123456, 0xff000000, 0xff123456, 0xffffffff}, 2, 256)
+	if len(img.Palette) != 3 {
+		t.Fatalf("expected 3 colors, got %d", len(img.Palette))
+	}
+	if r, g, b, _ := img.At(0, 1).RGBA(); r>>8 != 0x12 || g>>8 != 0x34 || b>>8 != 0x56 {
+		t.Error("expected the exact color to be kept")
+	}
+}
+
+// apngChunks counts the chunks of an APNG, checks their checksums, and returns the number of frames
+// in the animation control chunk and the delay of the last frame
+func apngChunks(t *testing.T, data []byte) (chunks map[string]int, numFrames uint32, lastDelay uint16) {
+	chunks = make(map[string]int)
+	for i := 8; i < len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		chunks[chunkType]++
+		switch chunkType {
+		case "acTL":
+			numFrames = binary.BigEndian.Uint32(data[i+8:])
+		case "fcTL":
+			lastDelay = binary.BigEndian.Uint16(data[i+8+20:])
+		}
+		if crc32.ChecksumIEEE(data[i+4:i+8+length]) != binary.BigEndian.Uint32(data[i+8+length:]) {
+			t.Errorf("wrong checksum for the %s chunk", chunkType)
+		}
+		i += 12 + length
+	}
+	return chunks, numFrames, lastDelay
+}
+
+func TestAPNGEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewAPNGEncoder(&buf, 16, 8, 30)
+	frames := testFrames(3)
+	for _, pixels := range frames {
+		e.Encode(pixels, 16)
+	}
+	e.Encode(frames[2], 16)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	chunks, numFrames, lastDelay := apngChunks(t, data)
+	if numFrames != 3 || chunks["fcTL"] != 3 || chunks["IDAT"] != 1 || chunks["fdAT"] != 2 {
+		t.Errorf("unexpected chunks: %v, number of frames: %d", chunks, numFrames)
+	}
+	if lastDelay != 2 {
+		t.Errorf("expected the last frame to last 2/30 seconds, got %d/30", lastDelay)
+	}
+
+	// The first frame can be read as a regular PNG
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, g, b, _ := img.At(0, 3).RGBA(); r>>8 != 0xff || g != 0 || b != 0 {
+		t.Error("expected a red pixel at (0, 3)")
+	}
+	if _, _, b, _ := img.At(5, 5).RGBA(); b>>8 != 0x80 {
+		t.Error("expected a blue pixel at (5, 5)")
+	}
+}
+
+func TestGIFEncoderWritesFrames(t *testing.T) {
+	// Frames are written as soon as the next different frame is encoded, instead of being kept until Close
+	var buf bytes.Buffer
+	e := NewGIFEncoder(&buf, 16, 8, 25)
+	frames := testFrames(3)
+	e.Encode(frames[0], 16)
+	if buf.Len() != 0 {
+		t.Error("expected the first frame to wait for its delay")
+	}
+	e.Encode(frames[1], 16)
+	written := buf.Len()
+	if written == 0 {
+		t.Error("expected the first frame to be written")
+	}
+	e.Encode(frames[1], 16)
+	if buf.Len() != written {
+		t.Error("expected an identical frame to be merged into the previous frame")
+	}
+}
+
+func TestRecorderAPNG(t *testing.T) {
+	// APNG files are written while recording, and the number of frames is filled in when stopping
+	filename := filepath.Join(t.TempDir(), "recording.png")
+	r, err := NewRecorder(filename, RecordAPNG, 16, 8, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := testFrames(4)
+	for _, pixels := range append(frames, frames[3]) {
+		r.Add(pixels, 16)
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, numFrames, lastDelay := apngChunks(t, data)
+	if numFrames != 4 || chunks["fcTL"] != 4 || chunks["IDAT"] != 1 || chunks["fdAT"] != 3 || chunks["IEND"] != 1 {
+		t.Errorf("unexpected chunks: %v, number of frames: %d", chunks, numFrames)
+	}
+	if lastDelay != 2 {
+		t.Errorf("expected the last frame to last 2/30 seconds, got %d/30", lastDelay)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecorderError(t *testing.T) {
+	if _, err := NewRecorder(filepath.Join(t.TempDir(), "missing", "recording.gif"), RecordGIF, 16, 8, 10); err == nil {
+		t.Error("expected an error when the directory does not exist")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "recording.gif")
+	r, err := NewRecorder(filename, RecordGIF, 16, 8, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Record from a larger pixel buffer, with a pitch of 20
+	for i := 0; i < 5; i++ {
+		pixels := make([]uint32, 20*8)
+		pixels[i] = 0xffffffff
+		r.Add(pixels, 20)
+	}
+	if err := r.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 5 || g.Config.Width != 16 {
+		t.Errorf("expected 5 frames with a width of 16, got %d frames with a width of %d", len(g.Image), g.Config.Width)
+	}
+}
@@ -0,0 +1,215 @@
+package pixelpusher
+
+import (
+	"image"
+	"image/color"
+)
+
+// CornerRadii are the radii of the four corners of a rounded rectangle, in pixels
+type CornerRadii struct {
+	TopLeft, TopRight, BottomRight, BottomLeft int32
+}
+
+// Radii returns corner radii where all four corners have the same radius
+func Radii(r int32) CornerRadii {
+	return CornerRadii{r, r, r, r}
+}
+
+// fit returns the corner radii, scaled down so that the corners do not overlap in a rectangle of the given size
+func (cr CornerRadii) fit(width, height int32) CornerRadii {
+	radii := [4]*int32{&cr.TopLeft, &cr.TopRight, &cr.BottomRight, &cr.BottomLeft}
+	for _, r := range radii {
+		*r = Max2(*r, 0)
+	}
+	// The largest sum of two radii along each side, compared to the room that there is for them
+	num, den := int64(1), int64(1)
+	for _, side := range [4][3]int32{
+		{cr.TopLeft, cr.TopRight, width},
+		{cr.BottomLeft, cr.BottomRight, width},
+		{cr.TopLeft, cr.BottomLeft, height},
+		{cr.TopRight, cr.BottomRight, height},
+	} {
+		sum, room := int64(side[0])+int64(side[1]), int64(Max2(side[2]-1, 0))
+		if sum > room && room*den < num*sum {
+			num, den = room, sum
+		}
+	}
+	if num < den {
+		for _, r := range radii {
+			*r = int32(int64(*r) * num / den)
+		}
+	}
+	return cr
+}
+
+// roundedRect finds the first and last pixel of each row of a rounded rectangle
+type roundedRect struct {
+	r          image.Rectangle
+	radii      CornerRadii
+	halfWidths [4][]int32 // The half widths of the circle for each corner, for each row from the center of the circle
+}
+
+// newRoundedRect prepares a rounded rectangle, where the radii are scaled down to fit if needed
+func newRoundedRect(r image.Rectangle, radii CornerRadii) *roundedRect {
+	r = r.Canon()
+	rr := &roundedRect{r: r, radii: radii.fit(int32(r.Dx()), int32(r.Dy()))}
+	for i, radius := range [4]int32{rr.radii.TopLeft, rr.radii.TopRight, rr.radii.BottomRight, rr.radii.BottomLeft} {
+		if radius > 0 {
+			rr.halfWidths[i] = make([]int32, radius+1)
+			for _, p := range circleQuadrant(radius) {
+				rr.halfWidths[i][p.y] = Max2(rr.halfWidths[i][p.y], p.x)
+			}
+		}
+	}
+	return rr
+}
+
+// span returns the first and last x of the given row. ok is false if the row is outside of the rectangle.
+func (rr *roundedRect) span(y int32) (x1, x2 int32, ok bool) {
+	minY, maxY := int32(rr.r.Min.Y), int32(rr.r.Max.Y)-1
+	if y < minY || y > maxY {
+		return 0, 0, false
+	}
+	x1, x2 = int32(rr.r.Min.X), int32(rr.r.Max.X)-1
+	// inset returns how far the corner with the given radius is from the side, j rows from the top or bottom
+	inset := func(i int, radius, j int32) int32 {
+		if j >= radius {
+			return 0
+		}
+		return radius - rr.halfWidths[i][radius-j]
+	}
+	top, bottom := y-minY, maxY-y
+	x1 += Max2(inset(0, rr.radii.TopLeft, top), inset(3, rr.radii.BottomLeft, bottom))
+	x2 -= Max2(inset(1, rr.radii.TopRight, top), inset(2, rr.radii.BottomRight, bottom))
+	return x1, x2, true
+}
+
+// fillRoundedRect draws the spans of the rounded rectangle, clipped to the buffer
+func (b *Buffer) fillRoundedRect(rr *roundedRect, c color.RGBA, mode BlendMode) {
+	for y := Max2(int32(rr.r.Min.Y), 0); y < Min2(int32(rr.r.Max.Y), b.Height); y++ {
+		if x1, x2, ok := rr.span(y); ok {
+			b.HorizontalLineBlend(y, x1, x2, c, mode)
+		}
+	}
+}
+
+// wireRoundedRect draws the outline of the rounded rectangle, clipped to the buffer.
+// The outline is the pixels of the filled shape that are next to a pixel that is outside of it.
+// Each pixel is only drawn once.
+func (b *Buffer) wireRoundedRect(rr *roundedRect, c color.RGBA, mode BlendMode) {
+	for y := Max2(int32(rr.r.Min.Y), 0); y < Min2(int32(rr.r.Max.Y), b.Height); y++ {
+		x1, x2, _ := rr.span(y)
+		ax1, ax2, aok := rr.span(y - 1)
+		bx1, bx2, bok := rr.span(y + 1)
+		// The pixels between lo and hi are inside of the shape, both above and below
+		lo, hi := Max2(ax1, bx1), Min2(ax2, bx2)
+		if !aok || !bok || lo > hi || Max2(x1, lo-1)+1 >= Min2(x2, hi+1) {
+			b.HorizontalLineBlend(y, x1, x2, c, mode)
+			continue
+		}
+		b.HorizontalLineBlend(y, x1, Max2(x1, lo-1), c, mode)
+		b.HorizontalLineBlend(y, Min2(x2, hi+1), x2, c, mode)
+	}
+}
+
+// Rectangle fills the given rectangle. pitch is the width of the pixel buffer.
+func Rectangle(pixels []uint32, r image.Rectangle, c color.RGBA, pitch int32) {
+	NewBufferFromPixels(pixels, pitch).Rectangle(r, c)
+}
+
+// WireRectangle draws the outline of the given rectangle. pitch is the width of the pixel buffer.
+func WireRectangle(pixels []uint32, r image.Rectangle, c color.RGBA, pitch int32) {
+	NewBufferFromPixels(pixels, pitch).WireRectangle(r, c)
+}
+
+// Rectangle fills the given rectangle, clipped to the buffer. r.Max is not included.
+func (b *Buffer) Rectangle(r image.Rectangle, c color.RGBA) {
+	b.RectangleBlend(r, c, BlendSrc)
+}
+
+// RectangleBlend fills the given rectangle with the given blend mode, clipped to the buffer. r.Max is not included.
+func (b *Buffer) RectangleBlend(r image.Rectangle, c color.RGBA, mode BlendMode) {
+	r = r.Canon().Intersect(b.Rect())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		b.HorizontalLineBlend(int32(y), int32(r.Min.X), int32(r.Max.X-1), c, mode)
+	}
+}
+
+// WireRectangle draws the outline of the given rectangle, clipped to the buffer. r.Max is not included,
+// so the outline is within the rectangle.
+func (b *Buffer) WireRectangle(r image.Rectangle, c color.RGBA) {
+	b.WireRectangleBlend(r, c, BlendSrc)
+}
+
+// WireRectangleBlend draws the outline of the given rectangle with the given blend mode, clipped to the buffer.
+// r.Max is not included, so the outline is within the rectangle. Each pixel is only drawn once.
+func (b *Buffer) WireRectangleBlend(r image.Rectangle, c color.RGBA, mode BlendMode) {
+	b.wireRoundedRect(newRoundedRect(r, CornerRadii{}), c, mode)
+}
+
+// RoundedRectangle fills a rectangle with rounded corners, clipped to the buffer.
+// The radii are scaled down if the corners would overlap.
+func (b *Buffer) RoundedRectangle(r image.Rectangle, radii CornerRadii, c color.RGBA) {
+	b.RoundedRectangleBlend(r, radii, c, BlendSrc)
+}
+
+// RoundedRectangleBlend fills a rectangle with rounded corners with the given blend mode, clipped to the buffer.
+// The radii are scaled down if the corners would overlap.
+func (b *Buffer) RoundedRectangleBlend(r image.Rectangle, radii CornerRadii, c color.RGBA, mode BlendMode) {
+	b.fillRoundedRect(newRoundedRect(r, radii), c, mode)
+}
+
+// WireRoundedRectangle draws the outline of a rectangle with rounded corners, clipped to the buffer
+func (b *Buffer) WireRoundedRectangle(r image.Rectangle, radii CornerRadii, c color.RGBA) {
+	b.WireRoundedRectangleBlend(r, radii, c, BlendSrc)
+}
+
+// WireRoundedRectangleBlend draws the outline of a rectangle with rounded corners with the given blend mode,
+// clipped to the buffer. Each pixel is only drawn once.
+func (b *Buffer) WireRoundedRectangleBlend(r image.Rectangle, radii CornerRadii, c color.RGBA, mode BlendMode) {
+	b.wireRoundedRect(newRoundedRect(r, radii), c, mode)
+}
+
+// FrameStyle decides how a frame is shaded
+type FrameStyle int
+
+const (
+	FrameRaised FrameStyle = iota // Light at the top and left, dark at the bottom and right
+	FrameSunken                   // Dark at the top and left, light at the bottom and right
+	FrameRidge                    // Raised on the outside, sunken on the inside
+	FrameGroove                   // Sunken on the outside, raised on the inside
+)
+
+// Frame draws a beveled frame with the given thickness just inside of the given rectangle, clipped to the buffer
+func (b *Buffer) Frame(r image.Rectangle, thickness int32, style FrameStyle, light, dark color.RGBA) {
+	b.FrameBlend(r, thickness, style, light, dark, BlendSrc)
+}
+
+// FrameBlend draws a beveled frame with the given thickness just inside of the given rectangle,
+// with the given blend mode, clipped to the buffer. The light and dark colors meet diagonally in the
+// upper right and lower left corners, and each pixel is only drawn once.
+func (b *Buffer) FrameBlend(r image.Rectangle, thickness int32, style FrameStyle, light, dark color.RGBA, mode BlendMode) {
+	r = r.Canon()
+	for i := int32(0); i < thickness && !r.Empty(); i++ {
+		// The upper left color, for this ring of the frame
+		raised := style == FrameRaised || style == FrameRidge
+		if (style == FrameRidge || style == FrameGroove) && i >= (thickness+1)/2 {
+			raised = !raised
+		}
+		upperLeft, lowerRight := light, dark
+		if !raised {
+			upperLeft, lowerRight = dark, light
+		}
+		x1, y1, x2, y2 := int32(r.Min.X), int32(r.Min.Y), int32(r.Max.X-1), int32(r.Max.Y-1)
+		if x1 == x2 || y1 == y2 {
+			// A single row or column
+			b.RectangleBlend(r, upperLeft, mode)
+			break
+		}
+		b.HorizontalLineBlend(y1, x1, x2-1, upperLeft, mode)
+		b.VerticalLineBlend(x1, y1+1, y2-1, upperLeft, mode)
+		b.HorizontalLineBlend(y2, x1, x2, lowerRight, mode)
+		b.VerticalLineBlend(x2, y1, y2-1, lowerRight, mode)
+		r = r.Inset(1)
+	}
+}
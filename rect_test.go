@@ -0,0 +1,113 @@
+package pixelpusher
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRectangle(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	b := NewBuffer(16, 16)
+	b.Rectangle(image.Rect(2, 3, 12, 8), white)
+	if n := count(b, 0xffffffff); n != 50 {
+		t.Errorf("expected 50 pixels, got %d", n)
+	}
+	if b.GetPixel(2, 3) == 0 || b.GetPixel(11, 7) == 0 || b.GetPixel(12, 7) != 0 || b.GetPixel(11, 8) != 0 {
+		t.Error("expected r.Max to be excluded")
+	}
+
+	// The outline is within the rectangle, and each pixel is only drawn once
+	b.FastClear(0xff000000)
+	gray := color.RGBA{0x40, 0x40, 0x40, 0xff}
+	b.WireRectangleBlend(image.Rect(12, 8, 2, 3), gray, BlendAdditive)
+	if n := count(b, 0xff404040); n != 26 {
+		t.Errorf("expected 26 pixels in the outline, got %d", n)
+	}
+	if b.GetPixel(5, 5) != 0xff000000 || b.GetPixel(11, 7) != 0xff404040 {
+		t.Error("unexpected outline")
+	}
+
+	// The free functions and clipping
+	pixels := make([]uint32, 16*16)
+	Rectangle(pixels, image.Rect(-10, -10, 4, 4), white, 16)
+	WireRectangle(pixels, image.Rect(10, 10, 100, 100), white, 16)
+	if pixels[0] == 0 || pixels[3*16+3] == 0 || pixels[4*16+4] != 0 || pixels[15*16+15] != 0 || pixels[10*16+15] == 0 {
+		t.Error("unexpected clipped rectangles")
+	}
+}
+
+func TestRoundedRectangle(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	r := image.Rect(2, 2, 30, 22)
+	b := NewBuffer(32, 24)
+	b.RoundedRectangle(r, CornerRadii{TopLeft: 6, BottomRight: 3}, white)
+	// The upper left corner is rounded, the upper right corner is not
+	if b.GetPixel(2, 2) != 0 || b.GetPixel(29, 2) == 0 || b.GetPixel(29, 21) != 0 || b.GetPixel(2, 21) == 0 {
+		t.Error("unexpected corners")
+	}
+	if b.GetPixel(8, 2) == 0 || b.GetPixel(2, 8) == 0 || b.GetPixel(3, 3) != 0 || b.GetPixel(4, 4) == 0 {
+		t.Error("expected the corner to have a radius of 6")
+	}
+
+	// The outline covers the edge of the filled shape, and each pixel is only drawn once
+	for _, radii := range []CornerRadii{Radii(0), Radii(4), Radii(100), {1, 2, 3, 4}} {
+		filled, wire := NewBuffer(32, 24), NewBuffer(32, 24)
+		filled.RoundedRectangle(r, radii, white)
+		wire.FastClear(0xff000000)
+		wire.WireRoundedRectangleBlend(r, radii, color.RGBA{0x40, 0x40, 0x40, 0xff}, BlendAdditive)
+		for y := int32(0); y < 24; y++ {
+			for x := int32(0); x < 32; x++ {
+				edge := filled.GetPixel(x, y) != 0 && (filled.GetPixel(x-1, y) == 0 || filled.GetPixel(x+1, y) == 0 ||
+					filled.GetPixel(x, y-1) == 0 || filled.GetPixel(x, y+1) == 0)
+				expected := uint32(0xff000000)
+				if edge {
+					expected = 0xff404040
+				}
+				if wire.GetPixel(x, y) != expected {
+					t.Fatalf("radii %v: expected %08x at (%d, %d), got %08x", radii, expected, x, y, wire.GetPixel(x, y))
+				}
+			}
+		}
+	}
+
+	// Radii that are too large are scaled down, so that the shape becomes a circle
+	b.FastClear(0)
+	b.RoundedRectangle(image.Rect(0, 0, 11, 11), Radii(50), white)
+	circle := NewBuffer(32, 24)
+	circle.FilledCircle(5, 5, 5, white)
+	for i := range b.Pixels {
+		if b.Pixels[i] != circle.Pixels[i] {
+			t.Fatalf("expected a circle, differs at %d", i)
+		}
+	}
+}
+
+func TestFrame(t *testing.T) {
+	light := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	dark := color.RGBA{0x40, 0x40, 0x40, 0xff}
+	b := NewBuffer(16, 16)
+	b.Frame(image.Rect(0, 0, 16, 16), 2, FrameRaised, light, dark)
+	if b.GetPixel(0, 0) != 0xffffffff || b.GetPixel(1, 8) != 0xffffffff || b.GetPixel(15, 15) != 0xff404040 || b.GetPixel(14, 8) != 0xff404040 {
+		t.Error("expected a raised frame")
+	}
+	// The colors meet diagonally in the upper right and lower left corners
+	if b.GetPixel(15, 0) != 0xff404040 || b.GetPixel(14, 0) != 0xffffffff || b.GetPixel(0, 15) != 0xff404040 || b.GetPixel(0, 14) != 0xffffffff {
+		t.Error("unexpected corners")
+	}
+	if b.GetPixel(2, 2) != 0 {
+		t.Error("expected the inside to be left alone")
+	}
+
+	b.Frame(image.Rect(0, 0, 16, 16), 2, FrameGroove, light, dark)
+	if b.GetPixel(0, 8) != 0xff404040 || b.GetPixel(1, 8) != 0xffffffff || b.GetPixel(15, 8) != 0xffffffff || b.GetPixel(14, 8) != 0xff404040 {
+		t.Error("expected a grooved frame")
+	}
+
+	// A frame that is thicker than the rectangle fills it
+	b.FastClear(0)
+	b.Frame(image.Rect(4, 4, 9, 7), 10, FrameSunken, light, dark)
+	if n := count(b, 0); n != 16*16-15 {
+		t.Errorf("expected the rectangle to be filled, got %d empty pixels", n)
+	}
+}
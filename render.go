@@ -0,0 +1,166 @@
+package pixelpusher
+
+import (
+	"image/color"
+)
+
+// CullMode decides which triangles of a mesh are skipped, depending on which way they face
+type CullMode int
+
+const (
+	CullBack  CullMode = iota // Skip the triangles that face away from the viewer
+	CullNone                  // Draw all triangles
+	CullFront                 // Skip the triangles that face the viewer
+)
+
+// MeshOptions decides how a mesh is transformed, lit and drawn, by Buffer.DrawMesh.
+// NewMeshOptions returns options that can be used as a starting point.
+type MeshOptions struct {
+	Model      Mat4       // Transforms the mesh into the world
+	View       Mat4       // Transforms the world into the view of the camera, for instance with LookAt
+	Projection Mat4       // Projects the view, for instance with Perspective or Orthographic
+	Light      Vec3       // The direction towards the light, in the world. If it is the zero vector, the mesh is not lit.
+	Ambient    float32    // How much light there is where the light does not reach, from 0 to 1
+	Color      color.RGBA // The color of the mesh, used if the mesh has no colors
+	Cull       CullMode   // Which triangles are skipped
+	Mode       BlendMode  // How the triangles are combined with the pixels below
+}
+
+// NewMeshOptions returns mesh options with identity matrices, a white mesh and a light from the front,
+// where the triangles that face away from the viewer are skipped
+func NewMeshOptions() *MeshOptions {
+	return &MeshOptions{
+		Model:      IdentityMat4(),
+		View:       IdentityMat4(),
+		Projection: IdentityMat4(),
+		Light:      Vec3{0, 0, 1},
+		Ambient:    0.2,
+		Color:      color.RGBA{0xff, 0xff, 0xff, 0xff},
+	}
+}
+
+// clipVertex is a corner of a triangle, in clip space, before it is divided by w
+type clipVertex struct {
+	pos   Vec4
+	color linearColor
+}
+
+// lerpClipVertex returns the vertex that is t of the way from a to b
+func lerpClipVertex(a, b clipVertex, t float32) clipVertex {
+	return clipVertex{a.pos.Lerp(b.pos, t), linearColor{
+		lerp(a.color.r, b.color.r, t),
+		lerp(a.color.g, b.color.g, t),
+		lerp(a.color.b, b.color.b, t),
+		lerp(a.color.a, b.color.a, t),
+	}}
+}
+
+// clipNear clips a triangle in clip space against the near plane, where z is -w, and returns the polygon that is
+// in front of it, which has from 0 to 4 corners. The triangle may only be partly visible, but the parts that are
+// behind the camera must be removed before dividing by w.
+func clipNear(corners [3]clipVertex) []clipVertex {
+	polygon := make([]clipVertex, 0, 4)
+	for i, a := range corners {
+		b := corners[(i+1)%3]
+		da, db := a.pos.Z+a.pos.W, b.pos.Z+b.pos.W
+		if da >= 0 {
+			polygon = append(polygon, a)
+		}
+		if (da >= 0) != (db >= 0) {
+			polygon = append(polygon, lerpClipVertex(a, b, da/(da-db)))
+		}
+	}
+	return polygon
+}
+
+// viewport divides the vertex by w and places it on the buffer, where x and y from -1 to 1 cover the buffer
+// and y points up, and z from -1 to 1 becomes a depth from 0 to 1
+func (b *Buffer) viewport(v clipVertex) shadedVertex {
+	ndc := v.pos.PerspectiveDivide()
+	return shadedVertex{
+		x:     (ndc.X+1)*float32(b.Width)/2 - 0.5,
+		y:     (1-ndc.Y)*float32(b.Height)/2 - 0.5,
+		z:     (ndc.Z + 1) / 2,
+		invW:  1 / v.pos.W,
+		color: v.color,
+	}
+}
+
+// light returns the premultiplied color, lit by a light from the given direction, for a surface with the given normal
+func (opts *MeshOptions) light(c color.RGBA, normal, light Vec3, linear bool) linearColor {
+	lc := toPremultiplied(ColorToColorValue(c), linear)
+	if light == (Vec3{}) {
+		return lc
+	}
+	intensity := opts.Ambient + (1-opts.Ambient)*max(normal.Dot(light), 0)
+	return linearColor{lc.r * intensity, lc.g * intensity, lc.b * intensity, lc.a}
+}
+
+// DrawMesh transforms, lights and draws a mesh with Gouraud-shaded triangles, clipped to the buffer, concurrently.
+// The vertices are transformed with opts.Projection * opts.View * opts.Model, and triangles are clipped against
+// the near plane, then placed on the buffer so that the projected x and y from -1 to 1 cover the whole buffer.
+// The triangles are depth tested against b.Depth, which should be set and cleared before each frame.
+// If the mesh has no normals, each triangle is lit with its own normal. opts can be nil.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) DrawMesh(cores int, m *Mesh, opts *MeshOptions) {
+	if opts == nil {
+		opts = NewMeshOptions()
+	}
+	mvp := opts.Projection.Mul(opts.View).Mul(opts.Model)
+	normals := normalMatrix(opts.Model)
+	light := opts.Light.Normalize()
+	clipPositions := make([]Vec4, len(m.Positions))
+	for i, p := range m.Positions {
+		clipPositions[i] = mvp.MulVec4(p.Vec4(1))
+	}
+	hasNormals, hasColors := len(m.Normals) == len(m.Positions), len(m.Colors) == len(m.Positions)
+
+	// Transform, light, clip and cull the triangles
+	var triangles []*shadedTriangle
+	for _, indices := range m.Triangles {
+		if min(indices[0], indices[1], indices[2]) < 0 || max(indices[0], indices[1], indices[2]) >= len(m.Positions) {
+			continue
+		}
+		var corners [3]clipVertex
+		for i, index := range indices {
+			var n Vec3
+			if hasNormals {
+				n = m.Normals[index]
+			} else {
+				n = faceNormal(m.Positions[indices[0]], m.Positions[indices[1]], m.Positions[indices[2]])
+			}
+			c := opts.Color
+			if hasColors {
+				c = m.Colors[index]
+			}
+			corners[i] = clipVertex{clipPositions[index], opts.light(c, normals.MulVec3(n).Normalize(), light, b.Linear)}
+		}
+		polygon := clipNear(corners)
+		for i := 2; i < len(polygon); i++ {
+			v := [3]shadedVertex{b.viewport(polygon[0]), b.viewport(polygon[i-1]), b.viewport(polygon[i])}
+			// The area is negative for triangles that are counterclockwise on the screen, which face the viewer
+			area := edgeFunction(v[0].x, v[0].y, v[1].x, v[1].y, v[2].x, v[2].y)
+			if (opts.Cull == CullBack && area > 0) || (opts.Cull == CullFront && area < 0) {
+				continue
+			}
+			if t, ok := prepareShadedTriangle(v); ok {
+				triangles = append(triangles, t)
+			}
+		}
+	}
+
+	// Let each goroutine draw all of the triangles, within a band of rows
+	maxX, maxY := b.Width, b.Height
+	if b.Depth != nil {
+		maxX, maxY = Min2(maxX, b.Depth.Width), Min2(maxY, b.Depth.Height)
+	}
+	splitRows(cores, 0, maxY, func(minYCore, maxYCore int32) {
+		for _, t := range triangles {
+			x1, x2, y1, y2 := t.bounds(b)
+			x2, y1, y2 = Min2(x2, maxX), Max2(y1, minYCore), Min2(y2, maxYCore)
+			if x1 < x2 && y1 < y2 {
+				b.drawPartialShadedTriangle(t, b.Depth, x1, x2, y1, y2, opts.Mode)
+			}
+		}
+	})
+}
@@ -1,9 +1,11 @@
 package pixelpusher
 
 import (
+	"bufio"
 	"errors"
 	"image"
 	"image/png"
+	"io"
 	"os"
 )
 
@@ -13,9 +15,9 @@ func exists(filename string) bool {
 	return err == nil
 }
 
-// SaveImageToPNG saves an image.RGBA image to a PNG file.
+// saveFile creates a file and writes to it with the given function, through a buffer.
 // Set overwrite to true to allow overwriting files.
-func SaveImageToPNG(img *image.RGBA, filename string, overwrite bool) error {
+func saveFile(filename string, overwrite bool, write func(w io.Writer) error) error {
 	if !overwrite && exists(filename) {
 		return errors.New(filename + " already exists")
 	}
@@ -23,16 +25,29 @@ func SaveImageToPNG(img *image.RGBA, filename string, overwrite bool) error {
 	if err != nil {
 		return err
 	}
-	if err := png.Encode(f, img); err != nil {
+	w := bufio.NewWriter(f)
+	if err := write(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
 		f.Close()
 		return err
 	}
 	return f.Close()
 }
 
+// SaveImageToPNG saves an image to a PNG file.
+// Set overwrite to true to allow overwriting files.
+func SaveImageToPNG(img image.Image, filename string, overwrite bool) error {
+	return saveFile(filename, overwrite, func(w io.Writer) error {
+		return png.Encode(w, img)
+	})
+}
+
 // Save pixels in uint32 ARGB format to PNG with alpha.
 // pitch is the width of the pixel buffer.
 // Set overwrite to true to allow overwriting files.
 func SavePixelsToPNG(pixels []uint32, pitch int32, filename string, overwrite bool) error {
-	return SaveImageToPNG(PixelsToImage(pixels, pitch), filename, overwrite)
+	return SaveImageToPNG(NewBufferFromPixels(pixels, pitch), filename, overwrite)
 }
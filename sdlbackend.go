@@ -0,0 +1,192 @@
+package pixelpusher
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// SDLBackend displays the canvas in an SDL2 window
+type SDLBackend struct {
+	window     *sdl.Window
+	renderer   *sdl.Renderer
+	texture    *sdl.Texture
+	joystick   *sdl.Joystick
+	controller *sdl.GameController
+	start      time.Time
+}
+
+// NewSDLBackend creates a new SDL2 backend. The window is created when Open is called.
+func NewSDLBackend() Backend {
+	return &SDLBackend{}
+}
+
+// Open initializes SDL and creates a window, renderer and texture for the given canvas
+func (s *SDLBackend) Open(c *Canvas) error {
+	var err error
+
+	// Initialize SDL (video + joystick + game controller)
+	sdl.Init(uint32(sdl.INIT_VIDEO) | uint32(sdl.INIT_JOYSTICK) | uint32(sdl.INIT_GAMECONTROLLER))
+
+	// Create a window
+	s.window, err = sdl.CreateWindow(c.Title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, int32(c.Width*c.PixelScale), int32(c.Height*c.PixelScale), sdl.WINDOW_SHOWN)
+	if err != nil {
+		s.Close()
+		return fmt.Errorf("failed to create window: %s", err)
+	}
+
+	// Create a renderer
+	flags := uint32(sdl.RENDERER_ACCELERATED)
+	if c.VSync {
+		flags |= sdl.RENDERER_PRESENTVSYNC
+	}
+	s.renderer, err = sdl.CreateRenderer(s.window, -1, flags)
+	if err != nil {
+		s.Close()
+		return fmt.Errorf("failed to create renderer: %s", err)
+	}
+
+	// Fill the render buffer with black
+	s.renderer.SetDrawColor(0, 0, 0, c.Opaque)
+	s.renderer.Clear()
+
+	// Create a texture to draw to
+	s.texture, err = s.renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_STREAMING, int32(c.Width), int32(c.Height))
+	if err != nil {
+		s.Close()
+		return fmt.Errorf("failed to create texture: %s", err)
+	}
+
+	// Initialize joystick, and also open it as a game controller, if it is one
+	if sdl.NumJoysticks() > 0 {
+		s.joystick = sdl.JoystickOpen(0)
+		if sdl.IsGameController(0) {
+			s.controller = sdl.GameControllerOpen(0)
+		}
+	}
+
+	s.start = time.Now()
+	return nil
+}
+
+// Present copies the pixels of the canvas to the texture and shows it in the window, letterboxed if needed
+func (s *SDLBackend) Present(c *Canvas) error {
+	if err := s.texture.UpdateRGBA(nil, c.Pixels, int(c.Pitch)); err != nil {
+		return err
+	}
+	outputWidth, outputHeight, err := s.renderer.GetOutputSize()
+	if err != nil {
+		return err
+	}
+	x, y, w, h := letterbox(outputWidth, outputHeight, int32(c.Width), int32(c.Height))
+	s.renderer.Clear()
+	if err := s.renderer.Copy(s.texture, nil, &sdl.Rect{X: x, Y: y, W: w, H: h}); err != nil {
+		return err
+	}
+	s.renderer.Present()
+	return nil
+}
+
+// ScreenSize returns the size of the window, in the same coordinates as mouse events
+func (s *SDLBackend) ScreenSize() (int32, int32) {
+	return s.window.GetSize()
+}
+
+// CursorVisible checks if the mouse cursor is shown
+func (s *SDLBackend) CursorVisible() bool {
+	return IsCursorVisible()
+}
+
+// PollEvent returns the next SDL event that has a corresponding Event type,
+// or nil if there are no more events.
+func (s *SDLBackend) PollEvent() Event {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch e := event.(type) {
+		case *sdl.QuitEvent:
+			return &QuitEvent{}
+		case *sdl.KeyboardEvent:
+			return &KeyEvent{Key: Key(e.Keysym.Sym), Mod: Mod(e.Keysym.Mod), Down: e.State == sdl.PRESSED, Repeat: e.Repeat != 0}
+		case *sdl.TextInputEvent:
+			return &TextInputEvent{Text: e.GetText()}
+		case *sdl.MouseMotionEvent:
+			return &MouseMotionEvent{X: e.X, Y: e.Y, XRel: e.XRel, YRel: e.YRel, Buttons: e.State}
+		case *sdl.MouseButtonEvent:
+			return &MouseButtonEvent{X: e.X, Y: e.Y, Button: MouseButton(e.Button), Down: e.State == sdl.PRESSED, Clicks: e.Clicks}
+		case *sdl.MouseWheelEvent:
+			x, y := e.X, e.Y
+			if e.Direction == sdl.MOUSEWHEEL_FLIPPED {
+				x, y = -x, -y
+			}
+			return &MouseWheelEvent{X: x, Y: y}
+		case *sdl.JoyAxisEvent:
+			return &JoyAxisEvent{Joystick: int32(e.Which), Axis: e.Axis, Value: e.Value}
+		case *sdl.JoyButtonEvent:
+			return &JoyButtonEvent{Joystick: int32(e.Which), Button: e.Button, Down: e.State == sdl.PRESSED}
+		case *sdl.ControllerAxisEvent:
+			return &ControllerAxisEvent{Joystick: int32(e.Which), Axis: ControllerAxis(e.Axis), Value: e.Value}
+		case *sdl.ControllerButtonEvent:
+			return &ControllerButtonEvent{Joystick: int32(e.Which), Button: ControllerButton(e.Button), Down: e.State == sdl.PRESSED}
+		case *sdl.WindowEvent:
+			switch e.Event {
+			case sdl.WINDOWEVENT_SIZE_CHANGED:
+				return &ResizeEvent{Width: e.Data1, Height: e.Data2}
+			case sdl.WINDOWEVENT_FOCUS_GAINED:
+				return &FocusEvent{Focused: true}
+			case sdl.WINDOWEVENT_FOCUS_LOST:
+				return &FocusEvent{Focused: false}
+			}
+		}
+	}
+	return nil
+}
+
+// Delay waits for the given duration
+func (s *SDLBackend) Delay(d time.Duration) {
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// Now returns the time since Open was called
+func (s *SDLBackend) Now() time.Duration {
+	return time.Since(s.start)
+}
+
+// ToggleFullscreen switches the window to fullscreen, or back.
+// Returns true if the mode has been switched to fullscreen.
+func (s *SDLBackend) ToggleFullscreen() bool {
+	return ToggleFullscreen(s.window)
+}
+
+// Screenshot saves the contents of the renderer to a PNG file.
+// Set overwrite to true for overwriting any existing files.
+func (s *SDLBackend) Screenshot(filename string, overwrite bool) error {
+	return Screenshot(s.renderer, filename, overwrite)
+}
+
+// Close destroys the texture, renderer and window, then quits SDL
+func (s *SDLBackend) Close() error {
+	if s.controller != nil {
+		s.controller.Close()
+		s.controller = nil
+	}
+	if s.joystick != nil {
+		s.joystick.Close()
+		s.joystick = nil
+	}
+	if s.texture != nil {
+		s.texture.Destroy()
+		s.texture = nil
+	}
+	if s.renderer != nil {
+		s.renderer.Destroy()
+		s.renderer = nil
+	}
+	if s.window != nil {
+		s.window.Destroy()
+		s.window = nil
+	}
+	sdl.Quit()
+	return nil
+}
@@ -0,0 +1,241 @@
+package pixelpusher
+
+import (
+	"math"
+)
+
+// toPremultiplied converts an ARGB uint32 color value to channels from 0 to 1, where the colors are premultiplied
+// by alpha, so that they can be interpolated. The colors are converted to linear light if linear is true.
+func toPremultiplied(cv uint32, linear bool) linearColor {
+	var c linearColor
+	if linear {
+		c = toLinear(cv)
+	} else {
+		c = linearColor{float32((cv>>16)&0xff) / 255, float32((cv>>8)&0xff) / 255, float32(cv&0xff) / 255, float32(cv>>24) / 255}
+	}
+	return linearColor{c.r * c.a, c.g * c.a, c.b * c.a, c.a}
+}
+
+// fromPremultiplied converts premultiplied channels from 0 to 1 back to an ARGB uint32 color value with straight alpha.
+// The colors are converted back from linear light if linear is true.
+func fromPremultiplied(c linearColor, linear bool) uint32 {
+	if c.a <= 0 {
+		return 0
+	}
+	c.r, c.g, c.b = c.r/c.a, c.g/c.a, c.b/c.a
+	if linear {
+		return c.colorValue()
+	}
+	channel := func(v float32) uint32 {
+		return uint32(clampUnit(v)*255 + 0.5)
+	}
+	return channel(c.a)<<24 | channel(c.r)<<16 | channel(c.g)<<8 | channel(c.b)
+}
+
+// edgeFunction returns twice the signed area of the triangle (a, b, p). It is positive if p is to the right
+// of the line from a to b, as seen on the screen, where y points down.
+func edgeFunction(ax, ay, bx, by, px, py float32) float32 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+// isTopLeft checks if the edge from a to b is a top or left edge of a triangle where the edge function is positive
+// inside. Pixels that are exactly on a top or left edge are drawn, so that triangles that share an edge do not overlap.
+func isTopLeft(ax, ay, bx, by float32) bool {
+	return (by == ay && bx > ax) || by < ay
+}
+
+// shadedVertex is a corner of a shadedTriangle
+type shadedVertex struct {
+	x, y, z float32
+	invW    float32 // 1 divided by w, for perspective correct colors
+	color   linearColor
+}
+
+// shadedTriangle is a triangle that is prepared for drawing, with the vertices in clockwise order
+type shadedTriangle struct {
+	v           [3]shadedVertex
+	area        float32
+	topLeft     [3]bool // If edge i, opposite of vertex i, is a top or left edge
+	perspective bool    // If the vertices have different w values, so that the colors must be perspective corrected
+}
+
+// newShadedTriangle prepares a triangle for drawing. ok is false if the triangle has no area.
+func newShadedTriangle(v1, v2, v3 *Vertex, linear bool) (t *shadedTriangle, ok bool) {
+	var v [3]shadedVertex
+	for i, vertex := range [3]*Vertex{v1, v2, v3} {
+		v[i] = shadedVertex{vertex.pos.X, vertex.pos.Y, vertex.pos.Z, 1 / vertex.W(), toPremultiplied(vertex.colorValue, linear)}
+	}
+	return prepareShadedTriangle(v)
+}
+
+// prepareShadedTriangle prepares a triangle with the given vertices for drawing. ok is false if the triangle
+// has no area.
+func prepareShadedTriangle(v [3]shadedVertex) (t *shadedTriangle, ok bool) {
+	t = &shadedTriangle{v: v}
+	t.perspective = t.v[0].invW != t.v[1].invW || t.v[0].invW != t.v[2].invW
+	t.area = edgeFunction(t.v[0].x, t.v[0].y, t.v[1].x, t.v[1].y, t.v[2].x, t.v[2].y)
+	if t.area == 0 || math.IsNaN(float64(t.area)) {
+		return nil, false
+	}
+	if t.area < 0 {
+		// Make the order of the vertices clockwise, as seen on the screen
+		t.v[1], t.v[2] = t.v[2], t.v[1]
+		t.area = -t.area
+	}
+	// Start with the top left vertex, so that the same triangle is drawn the same way, regardless of the order
+	first := 0
+	for i := 1; i < 3; i++ {
+		if t.v[i].y < t.v[first].y || (t.v[i].y == t.v[first].y && t.v[i].x < t.v[first].x) {
+			first = i
+		}
+	}
+	t.v = [3]shadedVertex{t.v[first], t.v[(first+1)%3], t.v[(first+2)%3]}
+	// Edge i is opposite of vertex i
+	for i := 0; i < 3; i++ {
+		a, b := t.v[(i+1)%3], t.v[(i+2)%3]
+		t.topLeft[i] = isTopLeft(a.x, a.y, b.x, b.y)
+	}
+	return t, true
+}
+
+// weights returns the edge functions for the pixel at (x, y), which are the barycentric coordinates times the area.
+// ok is false if the pixel is outside of the triangle.
+func (t *shadedTriangle) weights(x, y float32) (w [3]float32, ok bool) {
+	for i := 0; i < 3; i++ {
+		a, b := t.v[(i+1)%3], t.v[(i+2)%3]
+		w[i] = edgeFunction(a.x, a.y, b.x, b.y, x, y)
+		if w[i] < 0 || (w[i] == 0 && !t.topLeft[i]) {
+			return w, false
+		}
+	}
+	return w, true
+}
+
+// bounds returns the pixels that may be within the triangle, clipped to the buffer. The maximum values are not included.
+func (t *shadedTriangle) bounds(b *Buffer) (minX, maxX, minY, maxY int32) {
+	clip := func(v float32, hi int32) int32 {
+		if v < 0 {
+			return 0
+		}
+		if v > float32(hi) {
+			return hi
+		}
+		return int32(v)
+	}
+	v := t.v
+	minX = clip(float32(math.Ceil(float64(min(v[0].x, v[1].x, v[2].x)))), b.Width)
+	maxX = clip(float32(math.Floor(float64(max(v[0].x, v[1].x, v[2].x))))+1, b.Width)
+	minY = clip(float32(math.Ceil(float64(min(v[0].y, v[1].y, v[2].y)))), b.Height)
+	maxY = clip(float32(math.Floor(float64(max(v[0].y, v[1].y, v[2].y))))+1, b.Height)
+	return
+}
+
+// drawPartialShadedTriangle draws the rows from minY to maxY (not included) of a shaded triangle.
+// If depth is not nil, the pixels are depth tested.
+func (b *Buffer) drawPartialShadedTriangle(t *shadedTriangle, depth *DepthBuffer, minX, maxX, minY, maxY int32, mode BlendMode) {
+	invArea := 1 / t.area
+	v0, v1, v2 := &t.v[0], &t.v[1], &t.v[2]
+	for y := minY; y < maxY; y++ {
+		offset := y * b.Stride
+		for x := minX; x < maxX; x++ {
+			w, ok := t.weights(float32(x), float32(y))
+			if !ok {
+				continue
+			}
+			// The barycentric coordinates on the screen
+			l0, l1, l2 := w[0]*invArea, w[1]*invArea, w[2]*invArea
+			if depth != nil {
+				// The depth is interpolated linearly on the screen, since it has been divided by w
+				z := l0*v0.z + l1*v1.z + l2*v2.z
+				i := y*depth.Stride + x
+				if !depth.Test.passes(z, depth.Depth[i]) {
+					continue
+				}
+				if !depth.ReadOnly {
+					depth.Depth[i] = z
+				}
+			}
+			if t.perspective {
+				// Interpolate the colors divided by w, then divide by the interpolated 1/w
+				l0, l1, l2 = l0*v0.invW, l1*v1.invW, l2*v2.invW
+				if sum := l0 + l1 + l2; sum != 0 {
+					l0, l1, l2 = l0/sum, l1/sum, l2/sum
+				}
+			}
+			// Interpolate the premultiplied colors with the barycentric coordinates
+			c0, c1, c2 := v0.color, v1.color, v2.color
+			c := linearColor{
+				l0*c0.r + l1*c1.r + l2*c2.r,
+				l0*c0.g + l1*c1.g + l2*c2.g,
+				l0*c0.b + l1*c1.b + l2*c2.b,
+				l0*c0.a + l1*c1.a + l2*c2.a,
+			}
+			b.Pixels[offset+x] = b.combine(mode, b.Pixels[offset+x], fromPremultiplied(c, b.Linear))
+		}
+	}
+}
+
+// ShadedTriangle draws a triangle where the colors of the three vertices are blended across the triangle,
+// concurrently. cores is the number of goroutines that will be used. pitch is the width of the pixel buffer.
+func ShadedTriangle(cores int, pixels []uint32, v1, v2, v3 *Vertex, pitch int32) {
+	NewBufferFromPixels(pixels, pitch).ShadedTriangle(cores, v1, v2, v3)
+}
+
+// ShadedTriangle draws a Gouraud-shaded triangle, where the colors of the three vertices are blended
+// across the triangle, clipped to the buffer, concurrently. The z positions of the vertices are not used.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) ShadedTriangle(cores int, v1, v2, v3 *Vertex) {
+	b.ShadedTriangleBlend(cores, v1, v2, v3, BlendSrc)
+}
+
+// ShadedTriangleBlend draws a Gouraud-shaded triangle with the given blend mode, clipped to the buffer, concurrently.
+// The colors, including alpha, are interpolated with premultiplied alpha, and in linear light if b.Linear is set.
+// If the vertices have w values, the colors are interpolated with the perspective taken into account.
+// Pixels whose centers are on an edge that is shared by two triangles are only drawn by one of them.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) ShadedTriangleBlend(cores int, v1, v2, v3 *Vertex, mode BlendMode) {
+	t, ok := newShadedTriangle(v1, v2, v3, b.Linear)
+	if !ok {
+		return
+	}
+	minX, maxX, minY, maxY := t.bounds(b)
+	if minX >= maxX {
+		return
+	}
+	// Let each goroutine draw a band of rows
+	splitRows(cores, minY, maxY, func(minYCore, maxYCore int32) {
+		b.drawPartialShadedTriangle(t, nil, minX, maxX, minYCore, maxYCore, mode)
+	})
+}
+
+// DepthTriangle draws a Gouraud-shaded triangle, where each pixel is depth tested against the depth buffer
+// of the buffer, b.Depth, clipped to the buffer, concurrently. See DepthTriangleBlend.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) DepthTriangle(cores int, v1, v2, v3 *Vertex) {
+	b.DepthTriangleBlend(cores, v1, v2, v3, BlendSrc)
+}
+
+// DepthTriangleBlend draws a Gouraud-shaded triangle with the given blend mode, where each pixel is depth tested
+// against the depth buffer of the buffer, b.Depth, clipped to the buffer, concurrently.
+// The x and y positions of the vertices are on the screen, and the z positions are the depth, which is
+// interpolated linearly on the screen, so it should already be divided by w. The depth of each pixel is compared
+// with the depth buffer, with b.Depth.Test, and the pixels that pass are drawn, and stored in the depth buffer
+// unless b.Depth.ReadOnly is set. If b.Depth is nil, the triangle is drawn without depth testing.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) DepthTriangleBlend(cores int, v1, v2, v3 *Vertex, mode BlendMode) {
+	t, ok := newShadedTriangle(v1, v2, v3, b.Linear)
+	if !ok {
+		return
+	}
+	minX, maxX, minY, maxY := t.bounds(b)
+	if b.Depth != nil {
+		// Only draw where there are depth values
+		maxX, maxY = Min2(maxX, b.Depth.Width), Min2(maxY, b.Depth.Height)
+	}
+	if minX >= maxX {
+		return
+	}
+	splitRows(cores, minY, maxY, func(minYCore, maxYCore int32) {
+		b.drawPartialShadedTriangle(t, b.Depth, minX, maxX, minYCore, maxYCore, mode)
+	})
+}
@@ -0,0 +1,72 @@
+package pixelpusher
+
+import (
+	"testing"
+)
+
+func TestShadedTriangle(t *testing.T) {
+	b := NewBuffer(32, 32)
+
+	// The vertices have their own colors, and the colors in between are blended
+	red := NewVertex(0, 0, 0, 0xff, 0, 0, 0xff)
+	green := NewVertex(30, 0, 0, 0, 0xff, 0, 0xff)
+	blue := NewVertex(0, 30, 0, 0, 0, 0xff, 0xff)
+	b.ShadedTriangle(4, red, green, blue)
+	if cv := b.GetPixel(0, 0); cv != 0xffff0000 {
+		t.Errorf("expected red, got %08x", cv)
+	}
+	if cv := b.GetPixel(15, 0); cv != 0xff808000 {
+		t.Errorf("expected a blend of red and green, got %08x", cv)
+	}
+	if cv := b.GetPixel(10, 10); cv != 0xff555555 {
+		t.Errorf("expected a blend of all three colors, got %08x", cv)
+	}
+	if b.GetPixel(16, 16) != 0 {
+		t.Error("expected a pixel outside of the triangle to be unchanged")
+	}
+
+	// The order of the vertices does not matter
+	other := NewBuffer(32, 32)
+	other.ShadedTriangle(1, blue, green, red)
+	for i := range b.Pixels {
+		if b.Pixels[i] != other.Pixels[i] {
+			t.Fatalf("expected the same pixels in both directions, pixel %d differs", i)
+		}
+	}
+
+	// Triangles that share an edge do not overlap, and leave no gaps
+	b.FastClear(0xff000000)
+	a := NewVertex(2, 2, 0, 0x40, 0x40, 0x40, 0xff)
+	c := NewVertex(20, 3, 0, 0x40, 0x40, 0x40, 0xff)
+	d := NewVertex(9, 25, 0, 0x40, 0x40, 0x40, 0xff)
+	e := NewVertex(28, 28, 0, 0x40, 0x40, 0x40, 0xff)
+	b.ShadedTriangleBlend(2, a, c, d, BlendAdditive)
+	b.ShadedTriangleBlend(2, c, e, d, BlendAdditive)
+	if n := count(b, 0xff808080); n != 0 {
+		t.Errorf("expected no overlapping pixels, got %d", n)
+	}
+	if n := count(b, 0xff404040); n == 0 {
+		t.Error("expected the triangles to be drawn")
+	}
+
+	// Alpha is interpolated with premultiplied colors, so a transparent vertex does not darken the others
+	b.FastClear(0)
+	b.ShadedTriangle(1, NewVertex(0, 0, 0, 0xff, 0xff, 0xff, 0xff), NewVertex(30, 0, 0, 0, 0, 0, 0), NewVertex(0, 30, 0, 0xff, 0xff, 0xff, 0xff))
+	if cv := b.GetPixel(15, 0); cv != 0x80ffffff {
+		t.Errorf("expected half transparent white, got %08x", cv)
+	}
+
+	// Triangles are clipped to the buffer
+	b.FastClear(0)
+	b.ShadedTriangle(1, NewVertex(-100, -100, 0, 0xff, 0, 0, 0xff), NewVertex(140, -100, 0, 0xff, 0, 0, 0xff), NewVertex(-100, 140, 0, 0xff, 0, 0, 0xff))
+	if b.GetPixel(0, 0) != 0xffff0000 || b.GetPixel(31, 8) != 0xffff0000 || b.GetPixel(31, 31) != 0 {
+		t.Error("unexpected clipped triangle")
+	}
+
+	// A triangle without an area is not drawn
+	b.FastClear(0)
+	b.ShadedTriangle(1, red, green, NewVertex(15, 0, 0, 0xff, 0xff, 0xff, 0xff))
+	if n := count(b, 0); n != 32*32 {
+		t.Errorf("expected nothing to be drawn, got %d pixels", 32*32-n)
+	}
+}
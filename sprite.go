@@ -0,0 +1,102 @@
+package pixelpusher
+
+import (
+	"image"
+)
+
+// AlphaMode decides how the alpha values of a sprite are used when blitting
+type AlphaMode int
+
+const (
+	// StraightAlpha blends the sprite on top, where the colors of the sprite are not premultiplied by alpha.
+	// This is the format that is used by pixel buffers.
+	StraightAlpha AlphaMode = iota
+	// PremultipliedAlpha blends the sprite on top, where the colors of the sprite have already been multiplied by alpha.
+	// This is only useful for Buffer sprites, since the colors of other images are converted to straight alpha first.
+	PremultipliedAlpha
+	// NoAlpha copies the pixels of the sprite, including alpha, replacing the pixels below
+	NoAlpha
+)
+
+// BlitOptions are options for drawing sprites with Buffer.Blit. The zero value blends with straight alpha.
+type BlitOptions struct {
+	Alpha       AlphaMode
+	Mode        BlendMode // How the sprite is combined with the pixels below, unless Alpha is NoAlpha
+	UseColorKey bool      // Skip pixels where the red, green and blue values are the same as in ColorKey
+	ColorKey    uint32    // ARGB uint32 color value for transparent pixels. Alpha is disregarded.
+	FlipX       bool      // Mirror the sprite horizontally
+	FlipY       bool      // Mirror the sprite vertically
+}
+
+// Blit draws the given sprite with its upper left corner at (x, y).
+// The sprite can be any image.Image, like a Buffer or an image loaded from a PNG file.
+// The position can be partially or completely outside of the buffer. opts can be nil.
+func (b *Buffer) Blit(sprite image.Image, x, y int32, opts *BlitOptions) {
+	b.BlitRect(sprite, sprite.Bounds(), x, y, opts)
+}
+
+// BlitRect draws the given rectangle of the sprite with its upper left corner at (x, y).
+// srcRect is in the coordinates of the sprite, and is clipped to the bounds of the sprite.
+// The position can be partially or completely outside of the buffer. opts can be nil.
+func (b *Buffer) BlitRect(sprite image.Image, srcRect image.Rectangle, x, y int32, opts *BlitOptions) {
+	if opts == nil {
+		opts = &BlitOptions{}
+	}
+	// Clip the source rectangle to the sprite. When flipping, the clipped part is on the other side.
+	clipped := srcRect.Intersect(sprite.Bounds())
+	if opts.FlipX {
+		x += int32(srcRect.Max.X - clipped.Max.X)
+	} else {
+		x += int32(clipped.Min.X - srcRect.Min.X)
+	}
+	if opts.FlipY {
+		y += int32(srcRect.Max.Y - clipped.Max.Y)
+	} else {
+		y += int32(clipped.Min.Y - srcRect.Min.Y)
+	}
+	srcRect = clipped
+
+	// Clip the destination rectangle to the buffer
+	w, h := int32(srcRect.Dx()), int32(srcRect.Dy())
+	minX, minY := Max2(x, 0), Max2(y, 0)
+	maxX, maxY := Min2(x+w, b.Width), Min2(y+h, b.Height)
+	if minX >= maxX || minY >= maxY {
+		return
+	}
+
+	colorValueAt := colorValueFunc(sprite)
+	key := opts.ColorKey & 0xffffff
+	for dy := minY; dy < maxY; dy++ {
+		sy := int32(srcRect.Min.Y) + dy - y
+		if opts.FlipY {
+			sy = int32(srcRect.Max.Y) - 1 - (dy - y)
+		}
+		row := b.Row(dy)
+		for dx := minX; dx < maxX; dx++ {
+			sx := int32(srcRect.Min.X) + dx - x
+			if opts.FlipX {
+				sx = int32(srcRect.Max.X) - 1 - (dx - x)
+			}
+			cv := colorValueAt(int(sx), int(sy))
+			if opts.UseColorKey && cv&0xffffff == key {
+				continue
+			}
+			switch {
+			case opts.Alpha == NoAlpha:
+				row[dx] = cv
+			case opts.Mode == BlendOver && !b.Linear:
+				row[dx] = sourceOver(row[dx], cv, opts.Alpha == PremultipliedAlpha)
+			case opts.Alpha == PremultipliedAlpha:
+				row[dx] = b.combine(opts.Mode, row[dx], unpremultiply(cv))
+			default:
+				row[dx] = b.combine(opts.Mode, row[dx], cv)
+			}
+		}
+	}
+}
+
+// BlitSprite draws the given sprite on the pixel buffer, with its upper left corner at (x, y).
+// pitch is the width of the pixel buffer. opts can be nil.
+func BlitSprite(pixels []uint32, pitch int32, sprite image.Image, x, y int32, opts *BlitOptions) {
+	NewBufferFromPixels(pixels, pitch).Blit(sprite, x, y, opts)
+}
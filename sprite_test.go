@@ -0,0 +1,85 @@
+package pixelpusher
+
+import (
+	"image"
+	"testing"
+)
+
+// testSprite returns a 3x2 sprite where every pixel has a different color, and the last pixel is magenta
+func testSprite() *Buffer {
+	sprite := NewBuffer(3, 2)
+	copy(sprite.Pixels, []uint32{0xff000001, 0xff000002, 0xff000003, 0xff000004, 0xff000005, 0xffff00ff})
+	return sprite
+}
+
+func TestBlitClipping(t *testing.T) {
+	b := NewBuffer(4, 4)
+	// Partially outside, to the upper left
+	b.Blit(testSprite(), -1, -1, nil)
+	if b.GetPixel(0, 0) != 0xff000005 || b.GetPixel(1, 0) != 0xffff00ff || b.GetPixel(2, 0) != 0 || b.GetPixel(0, 1) != 0 {
+		t.Errorf("unexpected pixels after blitting at (-1, -1): %x", b.Pixels[:8])
+	}
+	// Partially outside, to the lower right
+	b.Blit(testSprite(), 3, 3, nil)
+	if b.GetPixel(3, 3) != 0xff000001 {
+		t.Errorf("expected the upper left pixel of the sprite at (3, 3), got %x", b.GetPixel(3, 3))
+	}
+	// Completely outside
+	b.Blit(testSprite(), 100, -100, nil)
+	b.Blit(testSprite(), -3, 0, nil)
+}
+
+func TestBlitFlipAndColorKey(t *testing.T) {
+	b := NewBuffer(3, 2)
+	b.FastClear(0xff808080)
+	b.Blit(testSprite(), 0, 0, &BlitOptions{FlipX: true, FlipY: true, UseColorKey: true, ColorKey: 0x00ff00ff})
+	expected := []uint32{0xff808080, 0xff000005, 0xff000004, 0xff000003, 0xff000002, 0xff000001}
+	for i, cv := range expected {
+		if b.Pixels[i] != cv {
+			t.Fatalf("expected %x, got %x", expected, b.Pixels)
+		}
+	}
+	// Flipping a source rectangle that is clipped by the sprite bounds
+	b.FastClear(0)
+	b.BlitRect(testSprite(), image.Rect(1, 0, 4, 1), 0, 0, &BlitOptions{FlipX: true})
+	if b.Pixels[0] != 0 || b.Pixels[1] != 0xff000003 || b.Pixels[2] != 0xff000002 {
+		t.Errorf("unexpected pixels after flipping a clipped rectangle: %x", b.Pixels[:3])
+	}
+}
+
+func TestBlitAlpha(t *testing.T) {
+	b := NewBuffer(1, 1)
+	sprite := NewBuffer(1, 1)
+
+	// Half transparent white over opaque black gives gray
+	b.Pixels[0] = 0xff000000
+	sprite.Pixels[0] = 0x80ffffff
+	b.Blit(sprite, 0, 0, nil)
+	if b.Pixels[0] != 0xff808080 {
+		t.Errorf("straight alpha: expected ff808080, got %x", b.Pixels[0])
+	}
+
+	// The same color, premultiplied
+	b.Pixels[0] = 0xff000000
+	sprite.Pixels[0] = 0x80808080
+	b.Blit(sprite, 0, 0, &BlitOptions{Alpha: PremultipliedAlpha})
+	if b.Pixels[0] != 0xff808080 {
+		t.Errorf("premultiplied alpha: expected ff808080, got %x", b.Pixels[0])
+	}
+
+	// Drawing on a transparent pixel keeps the color of the sprite
+	b.Pixels[0] = 0
+	sprite.Pixels[0] = 0x80ff0000
+	b.Blit(sprite, 0, 0, nil)
+	if b.Pixels[0] != 0x80ff0000 {
+		t.Errorf("expected 80ff0000, got %x", b.Pixels[0])
+	}
+
+	// Copying without blending
+	b.Pixels[0] = 0xffffffff
+	sprite.Pixels[0] = 0x10203040
+	b.Blit(sprite, 0, 0, &BlitOptions{Alpha: NoAlpha})
+	if b.Pixels[0] != 0x10203040 {
+		t.Errorf("expected 10203040, got %x", b.Pixels[0])
+	}
+}
@@ -0,0 +1,110 @@
+package pixelpusher
+
+import (
+	"fmt"
+	"time"
+)
+
+// statsSamples is the number of frame times used for the moving average
+const statsSamples = 60
+
+// FrameStats contains timing information for the main loop in Canvas.Run.
+// The durations are for the previous iteration of the main loop.
+type FrameStats struct {
+	Loops          uint64        // Number of iterations of the main loop
+	Frames         uint64        // Number of frames that have been drawn
+	Updates        uint64        // Number of times the tick function has been called
+	DroppedFrames  uint64        // Number of frames that were skipped because the main loop fell behind
+	DroppedUpdates uint64        // Number of fixed timestep updates that were skipped because of the catch-up limit
+	FrameTime      time.Duration // Time for a complete iteration of the main loop, including waiting
+	DrawTime       time.Duration // Time spent drawing and presenting the frame
+	UpdateTime     time.Duration // Time spent in the tick function
+	Elapsed        time.Duration // Time since the main loop was started
+	FPS            float64       // Frames per second, as a moving average
+	Alpha          float64       // How far it is from the previous to the next fixed timestep update, from 0 to 1
+}
+
+// frameTimer keeps track of frame times, for calculating the moving average
+type frameTimer struct {
+	samples [statsSamples]time.Duration
+	sum     time.Duration
+	count   int
+	index   int
+}
+
+// add adds a frame time and returns the average frames per second
+func (ft *frameTimer) add(d time.Duration) float64 {
+	ft.sum += d - ft.samples[ft.index]
+	ft.samples[ft.index] = d
+	ft.index = (ft.index + 1) % statsSamples
+	if ft.count < statsSamples {
+		ft.count++
+	}
+	if ft.sum <= 0 {
+		return 0
+	}
+	return float64(ft.count) * float64(time.Second) / float64(ft.sum)
+}
+
+// Stats returns the timing information for the main loop.
+// It can be called from the draw function, for instance for interpolating with Alpha.
+func (c *Canvas) Stats() FrameStats {
+	return c.stats
+}
+
+// String returns the frames per second and frame time, like "60.0 FPS 16.7 MS"
+func (fs FrameStats) String() string {
+	return fmt.Sprintf("%.1f FPS %.1f MS", fs.FPS, float64(fs.FrameTime)/float64(time.Millisecond))
+}
+
+// statsFont is a tiny 3x5 pixel font for the characters used by the stats overlay.
+// Each row is 3 bits, where the highest bit is the leftmost pixel.
+var statsFont = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 3, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 2, 2},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'.': {0, 0, 0, 0, 2},
+	'F': {7, 4, 6, 4, 4},
+	'P': {7, 5, 7, 4, 4},
+	'S': {7, 4, 7, 1, 7},
+	'M': {5, 7, 7, 5, 5},
+	' ': {0, 0, 0, 0, 0},
+}
+
+// DrawStats draws the given text in the upper left corner of the pixel buffer,
+// white on black, using a tiny font that only has the characters needed for FrameStats.String.
+// Returns an error if the text does not fit.
+func DrawStats(pixels []uint32, pitch int32, text string) error {
+	const charWidth, charHeight, margin = 4, 6, 1
+	width := int32(len(text))*charWidth + margin
+	height := int32(charHeight + margin)
+	if width > pitch || height*pitch > int32(len(pixels)) {
+		return fmt.Errorf("the stats overlay (%d, %d) does not fit in the pixel buffer", width, height)
+	}
+	// Black background
+	for y := int32(0); y < height; y++ {
+		for x := int32(0); x < width; x++ {
+			pixels[y*pitch+x] = 0xff000000
+		}
+	}
+	// White text
+	for i, r := range text {
+		glyph := statsFont[r]
+		ox := int32(i)*charWidth + margin
+		for y := int32(0); y < 5; y++ {
+			for x := int32(0); x < 3; x++ {
+				if glyph[y]&(4>>uint(x)) != 0 {
+					pixels[(y+margin)*pitch+ox+x] = 0xffffffff
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package pixelpusher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedTimestep(t *testing.T) {
+	c := New("Fixed timestep")
+	c.FrameRate = 50  // 20 ms per frame
+	c.UpdateRate = 25 // 40 ms per update
+	headless := NewHeadlessBackend(10)
+	c.Backend = headless
+	var alphas []float64
+	onDraw := func(c *Canvas) error {
+		alphas = append(alphas, c.Stats().Alpha)
+		return nil
+	}
+	if err := c.Run(onDraw, nil, nil, nil); err != ErrQuit {
+		t.Fatal("expected ErrQuit, got:", err)
+	}
+	stats := c.Stats()
+	if stats.Updates != 4 {
+		t.Errorf("expected 4 updates, got %d", stats.Updates)
+	}
+	if stats.Frames != 10 || stats.Loops != 9 {
+		t.Errorf("expected 10 frames and 9 completed loops, got %d and %d", stats.Frames, stats.Loops)
+	}
+	if stats.FrameTime != 20*time.Millisecond || stats.Elapsed != 180*time.Millisecond {
+		t.Errorf("unexpected frame time and elapsed time: %v, %v", stats.FrameTime, stats.Elapsed)
+	}
+	if stats.FPS < 49.9 || stats.FPS > 50.1 {
+		t.Errorf("expected 50 FPS, got %f", stats.FPS)
+	}
+	expected := []float64{0, 0, 0.5, 0, 0.5, 0, 0.5, 0, 0.5, 0}
+	for i := range expected {
+		if alphas[i] != expected[i] {
+			t.Fatalf("expected alpha values %v, got %v", expected, alphas)
+		}
+	}
+}
+
+func TestCatchUpLimit(t *testing.T) {
+	c := New("Catch up")
+	c.FrameRate = 10   // 100 ms per frame
+	c.UpdateRate = 100 // 10 ms per update
+	c.MaxUpdates = 5
+	c.Backend = NewHeadlessBackend(4)
+	ticks := 0
+	onTick := func() error {
+		ticks++
+		return nil
+	}
+	c.Run(nil, nil, nil, onTick)
+	stats := c.Stats()
+	if ticks != 10 || stats.DroppedUpdates != 10 {
+		t.Errorf("expected 10 updates and 10 dropped updates, got %d and %d", ticks, stats.DroppedUpdates)
+	}
+}
+
+func TestStatsOverlay(t *testing.T) {
+	c := New("Stats")
+	c.ShowStats = true
+	headless := NewHeadlessBackend(2)
+	c.Backend = headless
+	c.Run(nil, nil, nil, nil)
+	if headless.Output[1][0] != 0xff000000 || headless.Output[1][320*2+1] != 0xffffffff {
+		t.Error("expected the stats overlay to be presented")
+	}
+	if c.Pixels[0] != 0 {
+		t.Error("expected the stats overlay to not be drawn to the canvas pixels")
+	}
+	if err := DrawStats(make([]uint32, 10*10), 10, "60.0 FPS"); err == nil {
+		t.Error("expected an error when the stats do not fit")
+	}
+}
@@ -0,0 +1,200 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// stlHeaderSize is the size of the header of a binary STL file, followed by the number of triangles
+const stlHeaderSize = 80
+
+// stlTriangleSize is the size of each triangle in a binary STL file: a normal, three corners and an attribute
+const stlTriangleSize = 50
+
+// addFacet adds a triangle with its own three vertices to the mesh, with the given normal,
+// or with the normal of the triangle if the given normal is not usable
+func (m *Mesh) addFacet(normal Vec3, corners [3]Vec3) {
+	if l := normal.Len(); l == 0 || math.IsNaN(float64(l)) || math.IsInf(float64(l), 0) {
+		normal = faceNormal(corners[0], corners[1], corners[2])
+	} else {
+		normal = normal.Mul(1 / l)
+	}
+	first := len(m.Positions)
+	for _, p := range corners {
+		m.Positions = append(m.Positions, p)
+		m.Normals = append(m.Normals, normal)
+	}
+	m.AddTriangle(first, first+1, first+2)
+}
+
+// ReadSTL reads a mesh from an STL file, which can be ASCII or binary. Each triangle gets its own three vertices,
+// with the normal from the file, or the normal of the triangle if the file has no usable normal.
+func ReadSTL(r io.Reader) (*Mesh, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// Binary STL files may also start with "solid", so check if the size matches the number of triangles first
+	if len(data) >= stlHeaderSize+4 {
+		count := binary.LittleEndian.Uint32(data[stlHeaderSize:])
+		if uint64(len(data)) == stlHeaderSize+4+stlTriangleSize*uint64(count) {
+			return readBinarySTL(data, int(count)), nil
+		}
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("solid")) {
+		return readASCIISTL(data)
+	}
+	if len(data) < stlHeaderSize+4 {
+		return nil, errors.New("too short for a binary STL file, and not an ASCII STL file")
+	}
+	count := binary.LittleEndian.Uint32(data[stlHeaderSize:])
+	return nil, fmt.Errorf("a binary STL file with %d triangles should be %d bytes, but it is %d bytes", count, stlHeaderSize+4+stlTriangleSize*uint64(count), len(data))
+}
+
+// readBinarySTL reads the triangles of a binary STL file, where the size has been checked
+func readBinarySTL(data []byte, count int) *Mesh {
+	m := NewMesh()
+	vector := func(b []byte) Vec3 {
+		return Vec3{
+			math.Float32frombits(binary.LittleEndian.Uint32(b)),
+			math.Float32frombits(binary.LittleEndian.Uint32(b[4:])),
+			math.Float32frombits(binary.LittleEndian.Uint32(b[8:])),
+		}
+	}
+	for i := 0; i < count; i++ {
+		t := data[stlHeaderSize+4+i*stlTriangleSize:]
+		m.addFacet(vector(t), [3]Vec3{vector(t[12:]), vector(t[24:]), vector(t[36:])})
+	}
+	return m
+}
+
+// readASCIISTL reads the facets of an ASCII STL file
+func readASCIISTL(data []byte) (*Mesh, error) {
+	m := NewMesh()
+	var (
+		normal  Vec3
+		corners []Vec3
+	)
+	scanner := newLineScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "solid", "endsolid", "outer", "endloop":
+		case "facet", "vertex":
+			args := fields[1:]
+			if fields[0] == "facet" {
+				if len(args) == 0 || args[0] != "normal" {
+					return nil, fmt.Errorf("line %d: expected \"facet normal\"", lineNumber)
+				}
+				args = args[1:]
+			}
+			if len(args) != 3 {
+				return nil, fmt.Errorf("line %d: %s: expected 3 numbers, got %d", lineNumber, fields[0], len(args))
+			}
+			values, err := parseFloats(args)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %s", lineNumber, fields[0], err)
+			}
+			v := Vec3{values[0], values[1], values[2]}
+			if fields[0] == "facet" {
+				normal, corners = v, corners[:0]
+			} else {
+				corners = append(corners, v)
+			}
+		case "endfacet":
+			if len(corners) != 3 {
+				return nil, fmt.Errorf("line %d: a facet needs 3 vertices, got %d", lineNumber, len(corners))
+			}
+			m.addFacet(normal, [3]Vec3{corners[0], corners[1], corners[2]})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected %q", lineNumber, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %s", lineNumber+1, err)
+	}
+	return m, nil
+}
+
+// LoadSTL loads a mesh from an ASCII or binary STL file
+func LoadSTL(filename string) (*Mesh, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadSTL(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return m, nil
+}
+
+// WriteSTL writes the triangles of the mesh in the STL format, which is binary unless ascii is true.
+// STL files only have the positions of the corners, and the normal of each triangle.
+func (m *Mesh) WriteSTL(w io.Writer, ascii bool) error {
+	if err := m.checkTriangles(); err != nil {
+		return err
+	}
+	if ascii {
+		return m.writeASCIISTL(w)
+	}
+	if uint64(len(m.Triangles)) > math.MaxUint32 {
+		return errors.New("too many triangles for a binary STL file")
+	}
+	var header [stlHeaderSize + 4]byte
+	copy(header[:], "binary STL written by pixelpusher")
+	binary.LittleEndian.PutUint32(header[stlHeaderSize:], uint32(len(m.Triangles)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	var buf [stlTriangleSize]byte
+	for _, t := range m.Triangles {
+		a, b, c := m.Positions[t[0]], m.Positions[t[1]], m.Positions[t[2]]
+		for i, v := range [4]Vec3{faceNormal(a, b, c), a, b, c} {
+			binary.LittleEndian.PutUint32(buf[i*12:], math.Float32bits(v.X))
+			binary.LittleEndian.PutUint32(buf[i*12+4:], math.Float32bits(v.Y))
+			binary.LittleEndian.PutUint32(buf[i*12+8:], math.Float32bits(v.Z))
+		}
+		if _, err := w.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeASCIISTL writes the triangles of the mesh in the ASCII STL format
+func (m *Mesh) writeASCIISTL(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "solid mesh"); err != nil {
+		return err
+	}
+	for _, t := range m.Triangles {
+		a, b, c := m.Positions[t[0]], m.Positions[t[1]], m.Positions[t[2]]
+		n := faceNormal(a, b, c)
+		if _, err := fmt.Fprintf(w, "facet normal %g %g %g\n outer loop\n  vertex %g %g %g\n  vertex %g %g %g\n  vertex %g %g %g\n endloop\nendfacet\n",
+			n.X, n.Y, n.Z, a.X, a.Y, a.Z, b.X, b.Y, b.Z, c.X, c.Y, c.Z); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "endsolid mesh")
+	return err
+}
+
+// SaveSTL saves the triangles of the mesh to an STL file, which is binary unless ascii is true.
+// Set overwrite to true to allow overwriting files.
+func (m *Mesh) SaveSTL(filename string, ascii, overwrite bool) error {
+	return saveFile(filename, overwrite, func(w io.Writer) error {
+		return m.WriteSTL(w, ascii)
+	})
+}
@@ -0,0 +1,74 @@
+package pixelpusher
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSTLRoundTrip(t *testing.T) {
+	cube := NewCube()
+	for _, ascii := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := cube.WriteSTL(&buf, ascii); err != nil {
+			t.Fatal(err)
+		}
+		if !ascii && buf.Len() != 84+50*12 {
+			t.Errorf("expected %d bytes, got %d", 84+50*12, buf.Len())
+		}
+		m, err := ReadSTL(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Each triangle gets its own vertices
+		if len(m.Positions) != 36 || len(m.Normals) != 36 || len(m.Triangles) != 12 {
+			t.Fatalf("expected 36 vertices and 12 triangles, got %d and %d", len(m.Positions), len(m.Triangles))
+		}
+		for i, tri := range cube.Triangles {
+			for j, index := range tri {
+				if m.Positions[m.Triangles[i][j]] != cube.Positions[index] {
+					t.Errorf("triangle %d, corner %d: %v != %v", i, j, m.Positions[m.Triangles[i][j]], cube.Positions[index])
+				}
+				if !closeToVec3(m.Normals[m.Triangles[i][j]], cube.Normals[index]) {
+					t.Errorf("triangle %d, corner %d: normal %v != %v", i, j, m.Normals[m.Triangles[i][j]], cube.Normals[index])
+				}
+			}
+		}
+	}
+}
+
+func TestReadSTL(t *testing.T) {
+	// Missing normals are computed from the corners
+	m, err := ReadSTL(strings.NewReader(`solid triangle
+  facet normal 0 0 0
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 0 1 0
+    endloop
+  endfacet
+endsolid triangle
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Triangles) != 1 || m.Normals[0] != (Vec3{0, 0, 1}) || m.Positions[1] != (Vec3{1, 0, 0}) {
+		t.Errorf("unexpected mesh: %+v", m)
+	}
+
+	for _, test := range []struct {
+		stl, message string
+	}{
+		{"solid\nfacet normal 0 0 1\nouter loop\nvertex 0 0 0\nvertex 1 0 0\nendloop\nendfacet", "line 7: a facet needs 3 vertices, got 2"},
+		{"solid\nfacet normal 0 0 1\nouter loop\nvertex 0 0 x\n", "line 4: vertex: invalid number \"x\""},
+		{"solid\nfacet 0 0 1\n", "line 2: expected \"facet normal\""},
+		{"solid\nfacet normal 0 1\n", "line 2: facet: expected 3 numbers, got 2"},
+		{"solid\nface normal 0 0 1\n", "line 2: unexpected \"face\""},
+		{"not an STL file", "too short for a binary STL file, and not an ASCII STL file"},
+		{strings.Repeat("\x00", 80) + "\x02\x00\x00\x00" + strings.Repeat("\x00", 50), "a binary STL file with 2 triangles should be 184 bytes, but it is 134 bytes"},
+	} {
+		if _, err := ReadSTL(strings.NewReader(test.stl)); err == nil || err.Error() != test.message {
+			t.Errorf("expected %q, got %v", test.message, err)
+		}
+	}
+}
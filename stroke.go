@@ -0,0 +1,397 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"math"
+)
+
+// LineCap decides how the ends of thick lines are drawn
+type LineCap int
+
+const (
+	ButtCap   LineCap = iota // The line ends exactly at the end points
+	RoundCap                 // The line ends with a half circle around each end point
+	SquareCap                // The line is extended by half the width at each end
+)
+
+// LineJoin decides how the corners of thick polylines are drawn
+type LineJoin int
+
+const (
+	MiterJoin LineJoin = iota // The outer edges are extended until they meet, unless the corner is too sharp
+	RoundJoin                 // The corner is rounded, with a circle around the corner point
+	BevelJoin                 // The corner is cut off
+)
+
+// DefaultMiterLimit is the miter limit that is used when StrokeStyle.MiterLimit is 0
+const DefaultMiterLimit = 4
+
+// StrokeStyle decides how thick lines are drawn. The zero value draws anti-aliased lines
+// that are 0 pixels wide, which are not visible, so Width should always be set.
+type StrokeStyle struct {
+	Width      float32   // The width of the line, in pixels
+	Cap        LineCap   // How the ends of the line are drawn
+	Join       LineJoin  // How the corners of polylines are drawn
+	MiterLimit float32   // The longest miter, relative to half the width, before a bevel is used instead
+	Mode       BlendMode // How the line is combined with the pixels below
+	Dashes     []float32 // Alternating lengths of dashes and gaps, in pixels. The line is solid if there are none.
+	DashOffset float32   // How far into the dash pattern the line starts
+}
+
+// coverageMask is a rectangle of coverage values from 0 to 1, used for drawing anti-aliased shapes.
+// Shapes that are added to the mask are combined by keeping the largest coverage for each pixel,
+// so that overlapping parts are only drawn once.
+type coverageMask struct {
+	minX, minY    int32
+	width, height int32
+	coverage      []float32
+}
+
+// clampFloor rounds v down and clamps it to the range from lo to hi
+func clampFloor(v float32, lo, hi int32) int32 {
+	if v < float32(lo) {
+		return lo
+	}
+	if v > float32(hi) {
+		return hi
+	}
+	return int32(floor(v))
+}
+
+// newCoverageMask creates a coverage mask for the rectangle from (minX, minY) to (maxX, maxY),
+// clipped to the buffer. Both minX, minY and maxX, maxY are included.
+func (b *Buffer) newCoverageMask(minX, minY, maxX, maxY float32) *coverageMask {
+	x1, y1 := clampFloor(minX, 0, b.Width), clampFloor(minY, 0, b.Height)
+	x2, y2 := clampFloor(maxX+1, x1, b.Width), clampFloor(maxY+1, y1, b.Height)
+	return &coverageMask{x1, y1, x2 - x1, y2 - y1, make([]float32, (x2-x1)*(y2-y1))}
+}
+
+// bounds returns the part of the mask that is covered by the rectangle from (minX, minY) to (maxX, maxY),
+// with a margin of one pixel for the anti-aliasing. The returned maximum values are not included.
+func (m *coverageMask) bounds(minX, minY, maxX, maxY float32) (x1, y1, x2, y2 int32) {
+	x1 = clampFloor(minX-1, m.minX, m.minX+m.width)
+	y1 = clampFloor(minY-1, m.minY, m.minY+m.height)
+	x2 = clampFloor(maxX+2, x1, m.minX+m.width)
+	y2 = clampFloor(maxY+2, y1, m.minY+m.height)
+	return
+}
+
+// set keeps the largest of the current and the given coverage, for the pixel at (x, y)
+func (m *coverageMask) set(x, y int32, coverage float32) {
+	i := (y-m.minY)*m.width + (x - m.minX)
+	if coverage > m.coverage[i] {
+		m.coverage[i] = coverage
+	}
+}
+
+// addConvex adds a convex polygon to the mask. The points can be in any order around the polygon.
+func (m *coverageMask) addConvex(points ...Point) {
+	if len(points) < 3 {
+		return
+	}
+	// Find the outward normals of the edges, and the bounding box
+	var area float32
+	for i, p := range points {
+		area += p.Cross(points[(i+1)%len(points)])
+	}
+	if area == 0 {
+		return
+	}
+	minX, minY, maxX, maxY := points[0].X, points[0].Y, points[0].X, points[0].Y
+	normals := make([]Point, len(points))
+	offsets := make([]float32, len(points))
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		n := Point{q.Y - p.Y, p.X - q.X}.Normalize()
+		if area < 0 {
+			n = n.Mul(-1)
+		}
+		normals[i], offsets[i] = n, n.Dot(p)
+		minX, minY = float32(math.Min(float64(minX), float64(p.X))), float32(math.Min(float64(minY), float64(p.Y)))
+		maxX, maxY = float32(math.Max(float64(maxX), float64(p.X))), float32(math.Max(float64(maxY), float64(p.Y)))
+	}
+	x1, y1, x2, y2 := m.bounds(minX, minY, maxX, maxY)
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			// The signed distance to the polygon is the largest signed distance to the edges
+			p := Point{float32(x), float32(y)}
+			d := float32(math.Inf(-1))
+			for i, n := range normals {
+				if e := n.Dot(p) - offsets[i]; e > d {
+					d = e
+				}
+			}
+			if d < 0.5 {
+				m.set(x, y, clampUnit(0.5-d))
+			}
+		}
+	}
+}
+
+// addDisc adds a filled circle to the mask
+func (m *coverageMask) addDisc(center Point, radius float32) {
+	x1, y1, x2, y2 := m.bounds(center.X-radius, center.Y-radius, center.X+radius, center.Y+radius)
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			d := Point{float32(x), float32(y)}.Sub(center).Len()
+			if d < radius+0.5 {
+				m.set(x, y, clampUnit(radius-d+0.5))
+			}
+		}
+	}
+}
+
+// drawCoverage blends the color value into the buffer, with the alpha multiplied by the coverage of each pixel
+func (b *Buffer) drawCoverage(m *coverageMask, colorValue uint32, mode BlendMode) {
+	for y := int32(0); y < m.height; y++ {
+		row := m.coverage[y*m.width : (y+1)*m.width]
+		offset := (m.minY+y)*b.Stride + m.minX
+		for x, coverage := range row {
+			if coverage > 0 {
+				i := offset + int32(x)
+				b.Pixels[i] = b.combine(mode, b.Pixels[i], withCoverage(colorValue, coverage))
+			}
+		}
+	}
+}
+
+// ThickLine draws an anti-aliased line from (x1, y1) to (x2, y2) with the given width and line caps,
+// blended on top with BlendOver, clipped to the buffer
+func (b *Buffer) ThickLine(x1, y1, x2, y2, width float32, lineCap LineCap, c color.RGBA) {
+	b.Polyline([]Point{{x1, y1}, {x2, y2}}, &StrokeStyle{Width: width, Cap: lineCap}, c)
+}
+
+// Polyline draws anti-aliased lines between the given points, with the given stroke style, clipped to the buffer.
+// Pixels where the lines overlap are only drawn once.
+func (b *Buffer) Polyline(points []Point, style *StrokeStyle, c color.RGBA) {
+	b.strokePolyline(points, false, style, c)
+}
+
+// ClosedPolyline is like Polyline, but also draws a line from the last point back to the first point
+func (b *Buffer) ClosedPolyline(points []Point, style *StrokeStyle, c color.RGBA) {
+	b.strokePolyline(points, true, style, c)
+}
+
+// strokePolyline draws a polyline with the given stroke style, and also joins the last point with the first one
+// if closed is true
+func (b *Buffer) strokePolyline(points []Point, closed bool, style *StrokeStyle, c color.RGBA) {
+	b.strokeContours([][]Point{points}, []bool{closed}, style, c)
+}
+
+// strokeContours draws several polylines with the given stroke style, where closed[i] decides if contours[i]
+// is closed. Pixels where the polylines overlap are only drawn once.
+func (b *Buffer) strokeContours(contours [][]Point, closed []bool, style *StrokeStyle, c color.RGBA) {
+	if style == nil {
+		style = &StrokeStyle{Width: 1}
+	}
+	hw := style.Width / 2
+	if hw <= 0 {
+		return
+	}
+	limit := style.MiterLimit
+	if limit <= 0 {
+		limit = DefaultMiterLimit
+	}
+
+	// A mask that is large enough for the points, the caps and the miters
+	margin := hw*float32(math.Max(float64(limit), 1.5)) + 1
+	minX, minY := float32(math.Inf(1)), float32(math.Inf(1))
+	maxX, maxY := float32(math.Inf(-1)), float32(math.Inf(-1))
+	for _, points := range contours {
+		for _, p := range points {
+			minX, minY = float32(math.Min(float64(minX), float64(p.X))), float32(math.Min(float64(minY), float64(p.Y)))
+			maxX, maxY = float32(math.Max(float64(maxX), float64(p.X))), float32(math.Max(float64(maxY), float64(p.Y)))
+		}
+	}
+	if minX > maxX {
+		return
+	}
+	m := b.newCoverageMask(minX-margin, minY-margin, maxX+margin, maxY+margin)
+	if len(m.coverage) == 0 {
+		return
+	}
+
+	for i, points := range contours {
+		if !style.dashed() {
+			m.addStroke(points, closed[i], hw, limit, style)
+			continue
+		}
+		for _, dash := range dashPolyline(points, closed[i], style.Dashes, style.DashOffset) {
+			m.addStroke(dash, false, hw, limit, style)
+		}
+	}
+	b.drawCoverage(m, ColorToColorValue(c), style.Mode)
+}
+
+// dashed checks if the stroke style has a dash pattern
+func (style *StrokeStyle) dashed() bool {
+	var total float32
+	for _, d := range style.Dashes {
+		if d < 0 {
+			return false
+		}
+		total += d
+	}
+	return total > 0
+}
+
+// dashPolyline splits a polyline into dashes, with the given dash pattern and offset into the pattern.
+// The dash pattern must have a positive length.
+func dashPolyline(points []Point, closed bool, dashes []float32, offset float32) [][]Point {
+	if len(points) == 0 {
+		return nil
+	}
+	if len(dashes)%2 == 1 {
+		// An odd number of lengths is repeated, so that the lengths alternate between dashes and gaps
+		dashes = append(dashes[:len(dashes):len(dashes)], dashes...)
+	}
+	if closed {
+		points = append(points[:len(points):len(points)], points[0])
+	}
+	var total float32
+	for _, d := range dashes {
+		total += d
+	}
+
+	// Find where in the dash pattern the polyline starts
+	offset = float32(math.Mod(float64(offset), float64(total)))
+	if offset < 0 {
+		offset += total
+	}
+	i, remaining := 0, dashes[0]
+	for offset > 0 {
+		if offset < remaining {
+			remaining -= offset
+			break
+		}
+		offset -= remaining
+		i = (i + 1) % len(dashes)
+		remaining = dashes[i]
+	}
+
+	var result [][]Point
+	var current []Point
+	on := i%2 == 0
+	if on {
+		current = []Point{points[0]}
+	}
+	for j := 1; j < len(points); j++ {
+		p, q := points[j-1], points[j]
+		length, pos := q.Sub(p).Len(), float32(0)
+		for length-pos > remaining {
+			pos += remaining
+			pt := p.Add(q.Sub(p).Mul(pos / length))
+			if on {
+				result = append(result, append(current, pt))
+				current = nil
+			} else {
+				current = []Point{pt}
+			}
+			on = !on
+			i = (i + 1) % len(dashes)
+			remaining = dashes[i]
+		}
+		remaining -= length - pos
+		if on {
+			current = append(current, q)
+		}
+	}
+	if on && len(current) > 0 {
+		result = append(result, current)
+	}
+	return result
+}
+
+// addStroke adds a polyline to the mask, where hw is half the width and limit is the miter limit
+func (m *coverageMask) addStroke(points []Point, closed bool, hw, limit float32, style *StrokeStyle) {
+	// Skip points that are at the same position as the previous one
+	ps := make([]Point, 0, len(points))
+	for _, p := range points {
+		if len(ps) == 0 || p != ps[len(ps)-1] {
+			ps = append(ps, p)
+		}
+	}
+	if closed && len(ps) > 1 && ps[0] == ps[len(ps)-1] {
+		ps = ps[:len(ps)-1]
+	}
+	if len(ps) == 0 {
+		return
+	}
+
+	if len(ps) == 1 {
+		// A single point is drawn as a dot, if the line caps extend beyond the end points
+		switch style.Cap {
+		case RoundCap:
+			m.addDisc(ps[0], hw)
+		case SquareCap:
+			p := ps[0]
+			m.addConvex(Point{p.X - hw, p.Y - hw}, Point{p.X + hw, p.Y - hw}, Point{p.X + hw, p.Y + hw}, Point{p.X - hw, p.Y + hw})
+		}
+		return
+	}
+
+	segments := len(ps) - 1
+	if closed && len(ps) > 2 {
+		segments = len(ps)
+	} else {
+		closed = false
+	}
+	for i := 0; i < segments; i++ {
+		p, q := ps[i], ps[(i+1)%len(ps)]
+		d := q.Sub(p).Normalize()
+		n := Point{-d.Y, d.X}.Mul(hw)
+		// Extend the ends of open polylines for square caps. Extend the ends that are joined with other segments
+		// by half a pixel, so that the anti-aliased edges at the ends do not leave a seam.
+		startExt, endExt := float32(math.Min(float64(hw), 0.5)), float32(math.Min(float64(hw), 0.5))
+		if !closed && i == 0 {
+			startExt = 0
+			if style.Cap == SquareCap {
+				startExt = hw
+			}
+		}
+		if !closed && i == segments-1 {
+			endExt = 0
+			if style.Cap == SquareCap {
+				endExt = hw
+			}
+		}
+		p, q = p.Sub(d.Mul(startExt)), q.Add(d.Mul(endExt))
+		m.addConvex(p.Add(n), q.Add(n), q.Sub(n), p.Sub(n))
+	}
+	if !closed && style.Cap == RoundCap {
+		m.addDisc(ps[0], hw)
+		m.addDisc(ps[len(ps)-1], hw)
+	}
+
+	// The joins, at every point between two segments
+	for i := 0; i < len(ps); i++ {
+		if !closed && (i == 0 || i == len(ps)-1) {
+			continue
+		}
+		v := ps[i]
+		prev, next := ps[(i+len(ps)-1)%len(ps)], ps[(i+1)%len(ps)]
+		d0, d1 := v.Sub(prev).Normalize(), next.Sub(v).Normalize()
+		turn := d0.Cross(d1)
+		if turn == 0 && d0.Dot(d1) > 0 {
+			// A straight line, with no corner
+			continue
+		}
+		if style.Join == RoundJoin {
+			m.addDisc(v, hw)
+			continue
+		}
+		// The outer side of the corner is on the opposite side of the turn
+		s := float32(-1)
+		if turn < 0 {
+			s = 1
+		}
+		n0, n1 := Point{-d0.Y, d0.X}, Point{-d1.Y, d1.X}
+		o0, o1 := v.Add(n0.Mul(s*hw)), v.Add(n1.Mul(s*hw))
+		if cos := 1 + n0.Dot(n1); style.Join == MiterJoin && cos > 0 && 2/float32(math.Sqrt(float64(2*cos))) <= limit {
+			miter := v.Add(n0.Add(n1).Mul(s * hw / cos))
+			m.addConvex(v, o0, miter, o1)
+			continue
+		}
+		m.addConvex(v, o0, o1)
+	}
+}
@@ -0,0 +1,92 @@
+package pixelpusher
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestThickLine(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	caps := []struct {
+		lineCap    LineCap
+		outer, end uint32 // The pixels at (1, 5) and (2, 5)
+	}{
+		{ButtCap, 0xff000000, 0xff000000},
+		{SquareCap, 0xff808080, 0xffffffff},
+		{RoundCap, 0xff808080, 0xffffffff},
+	}
+	for _, tc := range caps {
+		b := NewBuffer(16, 16)
+		b.FastClear(0xff000000)
+		b.ThickLine(2.5, 5, 11.5, 5, 3, tc.lineCap, white)
+		for x := int32(3); x <= 11; x++ {
+			for y := int32(4); y <= 6; y++ {
+				if b.GetPixel(x, y) != 0xffffffff {
+					t.Errorf("cap %d: expected (%d, %d) to be covered, got %08x", tc.lineCap, x, y, b.GetPixel(x, y))
+				}
+			}
+			if b.GetPixel(x, 3) != 0xff000000 || b.GetPixel(x, 7) != 0xff000000 {
+				t.Errorf("cap %d: expected the line to be 3 pixels wide at x %d", tc.lineCap, x)
+			}
+		}
+		if b.GetPixel(1, 5) != tc.outer || b.GetPixel(2, 5) != tc.end || b.GetPixel(13, 5) != tc.outer || b.GetPixel(12, 5) != tc.end {
+			t.Errorf("cap %d: unexpected pixels at the ends, %08x and %08x", tc.lineCap, b.GetPixel(1, 5), b.GetPixel(2, 5))
+		}
+	}
+}
+
+func TestPolylineJoins(t *testing.T) {
+	white := color.RGBA{0xff, 0xff, 0xff, 0xff}
+	points := []Point{{2, 2}, {10, 2}, {10, 10}}
+	joins := []struct {
+		join         LineJoin
+		corner, near uint32 // The pixels at (12, 0) and (11, 1)
+	}{
+		{MiterJoin, 0xff808080, 0xffffffff},
+		{BevelJoin, 0xff000000, 0xff808080},
+		{RoundJoin, 0xff000000, 0xffffffff},
+	}
+	for _, tc := range joins {
+		b := NewBuffer(16, 16)
+		b.FastClear(0xff000000)
+		b.Polyline(points, &StrokeStyle{Width: 4, Join: tc.join}, white)
+		if b.GetPixel(12, 0) != tc.corner || b.GetPixel(11, 1) != tc.near {
+			t.Errorf("join %d: unexpected pixels at the corner, %08x and %08x", tc.join, b.GetPixel(12, 0), b.GetPixel(11, 1))
+		}
+		if b.GetPixel(10, 2) != 0xffffffff || b.GetPixel(6, 2) != 0xffffffff || b.GetPixel(10, 7) != 0xffffffff {
+			t.Errorf("join %d: expected the lines to be drawn", tc.join)
+		}
+	}
+
+	// A sharp corner is beveled when the miter would be longer than the miter limit
+	b := NewBuffer(32, 16)
+	b.Polyline([]Point{{2, 2}, {28, 4}, {2, 6}}, &StrokeStyle{Width: 2}, white)
+	if b.GetPixel(31, 4) != 0 {
+		t.Error("expected the sharp corner to be beveled")
+	}
+}
+
+func TestPolylineOverlap(t *testing.T) {
+	// Pixels where the lines overlap are only blended once
+	b := NewBuffer(16, 16)
+	b.FastClear(0xff000000)
+	gray := color.RGBA{0xff, 0xff, 0xff, 0x80}
+	b.ClosedPolyline([]Point{{2, 2}, {12, 2}, {12, 12}, {2, 12}}, &StrokeStyle{Width: 3, Join: RoundJoin}, gray)
+	for _, p := range [][2]int32{{2, 2}, {12, 2}, {7, 2}, {12, 12}, {2, 7}} {
+		if cv := b.GetPixel(p[0], p[1]); cv != 0xff808080 {
+			t.Errorf("expected ff808080 at (%d, %d), got %08x", p[0], p[1], cv)
+		}
+	}
+	if b.GetPixel(7, 7) != 0xff000000 {
+		t.Error("expected the inside to be left alone")
+	}
+
+	// A single point with round caps is a dot, and lines outside of the buffer are clipped
+	b.Polyline([]Point{{7, 7}}, &StrokeStyle{Width: 2, Cap: RoundCap}, gray)
+	if b.GetPixel(7, 7) == 0xff000000 {
+		t.Error("expected a dot")
+	}
+	b.Polyline([]Point{{-100, -100}, {200, 300}, {-50, 1e6}}, &StrokeStyle{Width: 5}, gray)
+	b.ThickLine(100, 100, 200, 100, 10, RoundCap, gray)
+	b.Polyline(nil, nil, gray)
+}
@@ -1,7 +1,6 @@
 package pixelpusher
 
 import (
-	"encoding/binary"
 	"image/color"
 	"sync"
 )
@@ -21,13 +20,12 @@ func area(p0, p1, p2 *Pos) float32 {
 
 // drawPartialTriangle draws a part of a triangle
 // areaMod is 1.0 divided on (the area of the triangle, times 2)
-func drawPartialTriangle(wg *sync.WaitGroup, pixels []uint32, p1, p2, p3 *Pos, minX, maxX, minY, maxY int32, areaMod float32, colorValue uint32, pitch int32) {
-	defer wg.Done()
+func (b *Buffer) drawPartialTriangle(p1, p2, p3 *Pos, minX, maxX, minY, maxY int32, areaMod float32, colorValue uint32, mode BlendMode) {
 	for y := minY; y < maxY; y++ {
-		offset := y * pitch
+		offset := y * b.Stride
 		for x := minX; x < maxX; x++ {
 			if pointInTriangle(x, y, p1, p2, p3, areaMod) {
-				pixels[offset+x] = colorValue
+				b.Pixels[offset+x] = b.combine(mode, b.Pixels[offset+x], colorValue)
 			}
 		}
 	}
@@ -37,8 +35,18 @@ func drawPartialTriangle(wg *sync.WaitGroup, pixels []uint32, p1, p2, p3 *Pos, m
 // Core is the number of goroutines that will be used.
 // pitch is the "width" of the pixel buffer.
 func Triangle(cores int, pixels []uint32, x1, y1, x2, y2, x3, y3 int32, c color.RGBA, pitch int32) {
-	var wg sync.WaitGroup
+	NewBufferFromPixels(pixels, pitch).Triangle(cores, x1, y1, x2, y2, x3, y3, c)
+}
+
+// Triangle draws a triangle, clipped to the buffer, concurrently.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) Triangle(cores int, x1, y1, x2, y2, x3, y3 int32, c color.RGBA) {
+	b.TriangleBlend(cores, x1, y1, x2, y2, x3, y3, c, BlendSrc)
+}
 
+// TriangleBlend draws a triangle with the given blend mode, clipped to the buffer, concurrently.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) TriangleBlend(cores int, x1, y1, x2, y2, x3, y3 int32, c color.RGBA, mode BlendMode) {
 	p1 := &Pos{x1, y1}
 	p2 := &Pos{x2, y2}
 	p3 := &Pos{x3, y3}
@@ -46,64 +54,66 @@ func Triangle(cores int, pixels []uint32, x1, y1, x2, y2, x3, y3 int32, c color.
 	minY, maxY := MinMax3(y1, y2, y3)
 	minX, maxX := MinMax3(x1, x2, x3)
 
+	if minY == maxY {
+		// This is not a triangle, but a horizontal line, since the height is 0
+		b.HorizontalLineBlend(minY, minX, maxX, c, mode)
+		return
+	}
+
 	// Triangle area, with modifications, for performance
 	areaMod := 1.0 / (area(p1, p2, p3) * 2.0)
 
-	colorValue := binary.BigEndian.Uint32([]uint8{c.A, c.R, c.G, c.B})
+	colorValue := ColorToColorValue(c)
 
-	ylength := (maxY - minY)
-
-	if ylength == 0 {
-		// This is not a triangle, but a horizontal line, since ylength == 0
-		HorizontalLineFast(pixels, minY, minX, maxX, c, pitch)
+	// Only loop over the part of the triangle that is within the buffer
+	minX, maxX = Max2(minX, 0), Min2(maxX, b.Width)
+	minY, maxY = Max2(minY, 0), Min2(maxY, b.Height)
+	if minX >= maxX {
 		return
 	}
-	// cores-1 because of the final part after the for loop
-	ystep := ylength / int32(cores-1)
-	if ystep == 0 {
-		return
-	}
-	var minYCore int32
-	var maxYCore int32
-	for y := minY; y < (maxY - ystep); y += ystep {
-		minYCore = y
-		maxYCore = y + ystep
-		wg.Add(1)
-		// TODO: Create a slice of pixels by looking at the min and max values, then pass only that slice on!
-		go drawPartialTriangle(&wg, pixels, p1, p2, p3, minX, maxX, minYCore, maxYCore, areaMod, colorValue, pitch)
-	}
-	// Draw the final part, if there are a few pixels missing at the end
-	if maxYCore < maxY {
-		wg.Add(1)
-		go drawPartialTriangle(&wg, pixels, p1, p2, p3, minX, maxX, maxYCore, maxY, areaMod, colorValue, pitch)
-	}
 
-	wg.Wait()
+	// Let each goroutine draw a band of rows
+	splitRows(cores, minY, maxY, func(minYCore, maxYCore int32) {
+		b.drawPartialTriangle(p1, p2, p3, minX, maxX, minYCore, maxYCore, areaMod, colorValue, mode)
+	})
 }
 
 // WireTriangle draws a wireframe triangle, concurrently.
 // Core is the number of goroutines that will be used.
 // pitch is the "width" of the pixel buffer.
 func WireTriangle(cores int, pixels []uint32, x1, y1, x2, y2, x3, y3 int32, c color.RGBA, pitch int32) {
-	if cores >= 3 {
+	NewBufferFromPixels(pixels, pitch).WireTriangle(cores, x1, y1, x2, y2, x3, y3, c)
+}
+
+// WireTriangle draws a wireframe triangle, clipped to the buffer, concurrently.
+// cores is the number of goroutines that will be used.
+func (b *Buffer) WireTriangle(cores int, x1, y1, x2, y2, x3, y3 int32, c color.RGBA) {
+	b.WireTriangleBlend(cores, x1, y1, x2, y2, x3, y3, c, BlendSrc)
+}
+
+// WireTriangleBlend draws a wireframe triangle with the given blend mode, clipped to the buffer.
+// cores is the number of goroutines that will be used. Since the lines meet at the corners,
+// the lines are drawn one after the other for blend modes other than BlendSrc.
+func (b *Buffer) WireTriangleBlend(cores int, x1, y1, x2, y2, x3, y3 int32, c color.RGBA, mode BlendMode) {
+	if cores >= 3 && mode == BlendSrc {
 		var wg sync.WaitGroup
 		wg.Add(3)
 		go func(wg *sync.WaitGroup) {
 			defer wg.Done()
-			Line(pixels, x1, y1, x2, y2, c, pitch)
+			b.LineBlend(x1, y1, x2, y2, c, mode)
 		}(&wg)
 		go func(wg *sync.WaitGroup) {
 			defer wg.Done()
-			Line(pixels, x1, y1, x3, y3, c, pitch)
+			b.LineBlend(x1, y1, x3, y3, c, mode)
 		}(&wg)
 		go func(wg *sync.WaitGroup) {
 			defer wg.Done()
-			Line(pixels, x2, y2, x3, y3, c, pitch)
+			b.LineBlend(x2, y2, x3, y3, c, mode)
 		}(&wg)
 		wg.Wait()
 	} else {
-		Line(pixels, x1, y1, x2, y2, c, pitch)
-		Line(pixels, x1, y1, x3, y3, c, pitch)
-		Line(pixels, x2, y2, x3, y3, c, pitch)
+		b.LineBlend(x1, y1, x2, y2, c, mode)
+		b.LineBlend(x1, y1, x3, y3, c, mode)
+		b.LineBlend(x2, y2, x3, y3, c, mode)
 	}
 }
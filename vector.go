@@ -0,0 +1,132 @@
+package pixelpusher
+
+import (
+	"math"
+)
+
+// Vec2 is a vector with two components. It is the same type as Point.
+type Vec2 = Point
+
+// Vec3 is a vector with three components, for positions and directions in 3D
+type Vec3 struct {
+	X, Y, Z float32
+}
+
+// Vec4 is a vector with four components, for homogeneous coordinates and colors
+type Vec4 struct {
+	X, Y, Z, W float32
+}
+
+// lerp returns the value that is t of the way from a to b
+func lerp(a, b, t float32) float32 {
+	return a + (b-a)*t
+}
+
+// Lerp returns the vector that is t of the way from p to q, where t is usually from 0 to 1
+func (p Point) Lerp(q Point, t float32) Point {
+	return Point{lerp(p.X, q.X, t), lerp(p.Y, q.Y, t)}
+}
+
+// Add returns the vector v+u
+func (v Vec3) Add(u Vec3) Vec3 {
+	return Vec3{v.X + u.X, v.Y + u.Y, v.Z + u.Z}
+}
+
+// Sub returns the vector v-u
+func (v Vec3) Sub(u Vec3) Vec3 {
+	return Vec3{v.X - u.X, v.Y - u.Y, v.Z - u.Z}
+}
+
+// Mul returns the vector v*k
+func (v Vec3) Mul(k float32) Vec3 {
+	return Vec3{v.X * k, v.Y * k, v.Z * k}
+}
+
+// Neg returns the vector -v
+func (v Vec3) Neg() Vec3 {
+	return Vec3{-v.X, -v.Y, -v.Z}
+}
+
+// Dot returns the dot product of v and u
+func (v Vec3) Dot(u Vec3) float32 {
+	return v.X*u.X + v.Y*u.Y + v.Z*u.Z
+}
+
+// Cross returns the cross product of v and u, which is perpendicular to both
+func (v Vec3) Cross(u Vec3) Vec3 {
+	return Vec3{v.Y*u.Z - v.Z*u.Y, v.Z*u.X - v.X*u.Z, v.X*u.Y - v.Y*u.X}
+}
+
+// Len returns the length of the vector v
+func (v Vec3) Len() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// Normalize returns the vector v with a length of 1, or the zero vector if v has a length of 0
+func (v Vec3) Normalize() Vec3 {
+	l := v.Len()
+	if l == 0 {
+		return Vec3{}
+	}
+	return Vec3{v.X / l, v.Y / l, v.Z / l}
+}
+
+// Lerp returns the vector that is t of the way from v to u, where t is usually from 0 to 1
+func (v Vec3) Lerp(u Vec3, t float32) Vec3 {
+	return Vec3{lerp(v.X, u.X, t), lerp(v.Y, u.Y, t), lerp(v.Z, u.Z, t)}
+}
+
+// Vec4 returns the vector with w added as the fourth component
+func (v Vec3) Vec4(w float32) Vec4 {
+	return Vec4{v.X, v.Y, v.Z, w}
+}
+
+// Add returns the vector v+u
+func (v Vec4) Add(u Vec4) Vec4 {
+	return Vec4{v.X + u.X, v.Y + u.Y, v.Z + u.Z, v.W + u.W}
+}
+
+// Sub returns the vector v-u
+func (v Vec4) Sub(u Vec4) Vec4 {
+	return Vec4{v.X - u.X, v.Y - u.Y, v.Z - u.Z, v.W - u.W}
+}
+
+// Mul returns the vector v*k
+func (v Vec4) Mul(k float32) Vec4 {
+	return Vec4{v.X * k, v.Y * k, v.Z * k, v.W * k}
+}
+
+// Dot returns the dot product of v and u
+func (v Vec4) Dot(u Vec4) float32 {
+	return v.X*u.X + v.Y*u.Y + v.Z*u.Z + v.W*u.W
+}
+
+// Len returns the length of the vector v
+func (v Vec4) Len() float32 {
+	return float32(math.Sqrt(float64(v.Dot(v))))
+}
+
+// Normalize returns the vector v with a length of 1, or the zero vector if v has a length of 0
+func (v Vec4) Normalize() Vec4 {
+	l := v.Len()
+	if l == 0 {
+		return Vec4{}
+	}
+	return Vec4{v.X / l, v.Y / l, v.Z / l, v.W / l}
+}
+
+// Lerp returns the vector that is t of the way from v to u, where t is usually from 0 to 1
+func (v Vec4) Lerp(u Vec4, t float32) Vec4 {
+	return Vec4{lerp(v.X, u.X, t), lerp(v.Y, u.Y, t), lerp(v.Z, u.Z, t), lerp(v.W, u.W, t)}
+}
+
+// Vec3 returns the first three components of v, without dividing by w
+func (v Vec4) Vec3() Vec3 {
+	return Vec3{v.X, v.Y, v.Z}
+}
+
+// PerspectiveDivide returns the first three components of v divided by w.
+// This turns homogeneous coordinates into ordinary coordinates.
+func (v Vec4) PerspectiveDivide() Vec3 {
+	return Vec3{v.X / v.W, v.Y / v.W, v.Z / v.W}
+}
@@ -0,0 +1,93 @@
+package pixelpusher
+
+import (
+	"math"
+	"testing"
+)
+
+// closeTo checks if a and b differ by less than 1e-5
+func closeTo(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-5
+}
+
+// closeToVec3 checks if each component of a and b differ by less than 1e-5
+func closeToVec3(a, b Vec3) bool {
+	return closeTo(a.X, b.X) && closeTo(a.Y, b.Y) && closeTo(a.Z, b.Z)
+}
+
+func TestVec2(t *testing.T) {
+	p := Vec2{1, 2}
+	if l := p.Lerp(Vec2{3, -2}, 0.5); l != (Vec2{2, 0}) {
+		t.Error(l, "!=", Vec2{2, 0})
+	}
+}
+
+func TestVec3(t *testing.T) {
+	v := Vec3{1, 2, 3}
+	u := Vec3{4, 5, 6}
+	if s := v.Add(u); s != (Vec3{5, 7, 9}) {
+		t.Error(s, "!=", Vec3{5, 7, 9})
+	}
+	if d := u.Sub(v); d != (Vec3{3, 3, 3}) {
+		t.Error(d, "!=", Vec3{3, 3, 3})
+	}
+	if m := v.Mul(2); m != (Vec3{2, 4, 6}) {
+		t.Error(m, "!=", Vec3{2, 4, 6})
+	}
+	if n := v.Neg(); n != (Vec3{-1, -2, -3}) {
+		t.Error(n, "!=", Vec3{-1, -2, -3})
+	}
+	if d := v.Dot(u); d != 32 {
+		t.Error(d, "!=", 32)
+	}
+	if c := (Vec3{1, 0, 0}).Cross(Vec3{0, 1, 0}); c != (Vec3{0, 0, 1}) {
+		t.Error(c, "!=", Vec3{0, 0, 1})
+	}
+	if c := v.Cross(u); c.Dot(v) != 0 || c.Dot(u) != 0 {
+		t.Error(c, "is not perpendicular to", v, "and", u)
+	}
+	if l := (Vec3{2, 3, 6}).Len(); l != 7 {
+		t.Error(l, "!=", 7)
+	}
+	if n := (Vec3{2, 3, 6}).Normalize(); !closeToVec3(n, Vec3{2.0 / 7, 3.0 / 7, 6.0 / 7}) {
+		t.Error(n, "is not normalized")
+	}
+	if n := (Vec3{}).Normalize(); n != (Vec3{}) {
+		t.Error(n, "!=", Vec3{})
+	}
+	if l := v.Lerp(u, 0.25); !closeToVec3(l, Vec3{1.75, 2.75, 3.75}) {
+		t.Error(l, "!=", Vec3{1.75, 2.75, 3.75})
+	}
+}
+
+func TestVec4(t *testing.T) {
+	v := Vec4{1, 2, 3, 4}
+	u := Vec4{4, 3, 2, 1}
+	if s := v.Add(u); s != (Vec4{5, 5, 5, 5}) {
+		t.Error(s, "!=", Vec4{5, 5, 5, 5})
+	}
+	if d := v.Sub(u); d != (Vec4{-3, -1, 1, 3}) {
+		t.Error(d, "!=", Vec4{-3, -1, 1, 3})
+	}
+	if m := v.Mul(0.5); m != (Vec4{0.5, 1, 1.5, 2}) {
+		t.Error(m, "!=", Vec4{0.5, 1, 1.5, 2})
+	}
+	if d := v.Dot(u); d != 20 {
+		t.Error(d, "!=", 20)
+	}
+	if l := (Vec4{1, 1, 1, 1}).Len(); l != 2 {
+		t.Error(l, "!=", 2)
+	}
+	if n := (Vec4{0, 0, 0, 3}).Normalize(); n != (Vec4{0, 0, 0, 1}) {
+		t.Error(n, "!=", Vec4{0, 0, 0, 1})
+	}
+	if l := v.Lerp(u, 1); l != u {
+		t.Error(l, "!=", u)
+	}
+	if p := (Vec4{2, 4, 6, 2}).PerspectiveDivide(); p != (Vec3{1, 2, 3}) {
+		t.Error(p, "!=", Vec3{1, 2, 3})
+	}
+	if p := v.Vec3().Vec4(4); p != v {
+		t.Error(p, "!=", v)
+	}
+}
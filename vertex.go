@@ -7,53 +7,63 @@ import (
 	"math"
 )
 
-type Vec3 struct {
-	x float32
-	y float32
-	z float32
-}
-
 // Vertex has a position and a color value
 type Vertex struct {
 	pos        *Vec3
 	colorValue uint32
+	w          float32 // The w value from before the perspective divide, for perspective correct colors
 }
 
 func NewVertex(x, y, z float32, r, g, b, a uint8) *Vertex {
-	return &Vertex{&Vec3{x, y, z}, RGBAToColorValue(r, g, b, a)}
+	return &Vertex{&Vec3{x, y, z}, RGBAToColorValue(r, g, b, a), 1}
 }
 
 func (v *Vertex) X() float32 {
-	return v.pos.x
+	return v.pos.X
 }
 
 func (v *Vertex) Y() float32 {
-	return v.pos.y
+	return v.pos.Y
 }
 
 func (v *Vertex) Z() float32 {
-	return v.pos.z
+	return v.pos.Z
+}
+
+// W returns the w value that the position had before it was divided by w, for the perspective projection.
+// The default is 1.
+func (v *Vertex) W() float32 {
+	if v.w == 0 {
+		return 1
+	}
+	return v.w
+}
+
+// SetW sets the w value that the position had before it was divided by w, for the perspective projection.
+// When drawing triangles, the colors are interpolated with the perspective taken into account.
+func (v *Vertex) SetW(w float32) {
+	v.w = w
 }
 
 func (v *Vertex) Normalize() error {
-	l := float32(math.Sqrt(float64(v.pos.x*v.pos.x + v.pos.y*v.pos.y + v.pos.z*v.pos.z)))
+	l := float32(math.Sqrt(float64(v.pos.X*v.pos.X + v.pos.Y*v.pos.Y + v.pos.Z*v.pos.Z)))
 	if l == 0 {
 		return errors.New("normalizing a Vertex with length 0")
 	}
-	v.pos.x /= l
-	v.pos.y /= l
-	v.pos.z /= l
+	v.pos.X /= l
+	v.pos.Y /= l
+	v.pos.Z /= l
 	return nil
 }
 
 func (v *Vertex) Set(x, y, z float32) {
-	v.pos.x = x
-	v.pos.y = y
-	v.pos.z = z
+	v.pos.X = x
+	v.pos.Y = y
+	v.pos.Z = z
 }
 
 func (v *Vertex) Get() (float32, float32, float32) {
-	return v.pos.x, v.pos.y, v.pos.z
+	return v.pos.X, v.pos.Y, v.pos.Z
 }
 
 func (v *Vertex) GetVec3() *Vec3 {
@@ -99,5 +109,5 @@ func (v *Vertex) A() uint8 {
 
 func (v *Vertex) String() string {
 	r, g, b, a := v.GetRGBA()
-	return fmt.Sprintf("v(%v, %v, %v) color(%v, %v, %v, %v)", v.pos.x, v.pos.y, v.pos.z, r, g, b, a)
+	return fmt.Sprintf("v(%v, %v, %v) color(%v, %v, %v, %v)", v.pos.X, v.pos.Y, v.pos.Z, r, g, b, a)
 }